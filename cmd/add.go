@@ -1 +1,61 @@
-package commands
\ No newline at end of file
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"ollama_go/internal/ingest"
+	"ollama_go/internal/store"
+)
+
+// Add indexes local Markdown, text, reStructuredText, HTML and PDF files.
+//
+//	go run . add [-exclude pattern]... <file|dir|glob>...
+func Add(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	var excludes stringList
+	fs.Var(&excludes, "exclude", "gitignore-style pattern to skip (repeatable)")
+	workers := fs.Int("workers", 3, "number of parallel embedding workers")
+	metric := fs.String("metric", "", "distance metric of a new index: cosine, dot or l2")
+	collection := fs.String("collection", store.DefaultCollection, "collection to add the documents to")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: add [-exclude pattern]... <file|dir|glob>...")
+	}
+
+	fmt.Println("📂 Loading local files...")
+	pages, err := ingest.LoadFiles(fs.Args(), excludes)
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		fmt.Println("\n⚠️  No supported files found!")
+		return nil
+	}
+	for _, page := range pages {
+		fmt.Printf("📄 %s (%s)\n", page.Title, page.Metadata["path"])
+	}
+
+	docStore, err := openCollection(*collection, *metric)
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+
+	embedder, err := collectionEmbedder(docStore)
+	if err != nil {
+		return err
+	}
+	defer closeEmbedder(embedder)
+
+	fmt.Println("\n🔄 Generating embeddings for local files...")
+	saved := ingest.NewIndexer(embedder, docStore, *workers).Index(context.Background(), pages)
+
+	fmt.Println("\n" + strings.Repeat("=", 80))
+	fmt.Printf("✅ Generated and saved %d embeddings\n", saved)
+	fmt.Println(strings.Repeat("=", 80))
+	return nil
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+
+	"ollama_go/internal/embedding"
+)
+
+// Cache inspects the embedding cache or purges the entries of a retired model.
+//
+//	go run . cache stats
+//	go run . cache purge -model ollama/llama3:latest
+func Cache(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: cache <stats|purge> [flags]")
+	}
+
+	cfg := embedding.ConfigFromEnv(defaultModel)
+	fs := flag.NewFlagSet("cache "+args[0], flag.ExitOnError)
+	path := fs.String("path", cfg.CachePath, "embedding cache file")
+	model := fs.String("model", "", "model ID whose entries to purge, e.g. ollama/llama3:latest")
+	fs.Parse(args[1:])
+
+	if *path == "" {
+		return fmt.Errorf("the embedding cache is disabled (RAG_EMBED_CACHE=off)")
+	}
+	cache, err := embedding.LoadCache(*path, cfg.CacheSize)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "stats":
+		models, counts := cache.Models()
+		fmt.Printf("🗄️  %s: %d entries\n", *path, cache.Stats().Entries)
+		for _, m := range models {
+			fmt.Printf("   %-40s %d\n", m, counts[m])
+		}
+		return nil
+
+	case "purge":
+		if *model == "" {
+			return fmt.Errorf("usage: cache purge -model <model ID>")
+		}
+		removed := cache.Purge(*model)
+		if err := cache.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("🧹 Removed %d cached embeddings for %s\n", removed, *model)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown cache command: %s", args[0])
+	}
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+
+	"ollama_go/internal/store"
+	"ollama_go/internal/vector"
+)
+
+// Collection creates, drops and lists named collections. Each collection
+// keeps its own metric, dimension and embedding model.
+//
+//	go run . collection create [-metric cosine] [-quantize none] [-model provider/model] [-backend json] <name>
+//	go run . collection quantize <name> <none|int8|pq>
+//	go run . collection drop <name>
+//	go run . collection list
+func Collection(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: collection <create|quantize|drop|list> [flags]")
+	}
+
+	collections := store.NewCollections(store.DefaultDir)
+	fs := flag.NewFlagSet("collection "+args[0], flag.ExitOnError)
+	switch args[0] {
+	case "create":
+		metricName := fs.String("metric", "cosine", "distance metric: cosine, dot or l2")
+		quantize := fs.String("quantize", "none", "search compressed vectors: none, int8 or pq (cosine only)")
+		model := fs.String("model", "", "embedding model ID, e.g. ollama/nomic-embed-text or hash/fnv-256 (default: the backend used for the first documents)")
+		backend := fs.String("backend", store.BackendJSON, "storage backend: json, sqlite or bolt")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: collection create [-metric cosine] [-quantize none] [-model provider/model] [-backend json] <name>")
+		}
+		metric, err := vector.ParseMetric(*metricName)
+		if err != nil {
+			return err
+		}
+		quantization, err := vector.ParseQuantization(*quantize)
+		if err != nil {
+			return err
+		}
+		if *model != "" {
+			if _, err := defaultEmbedderConfig().WithModelID(*model); err != nil {
+				return err
+			}
+		}
+		if err := collections.Create(fs.Arg(0), metric, quantization, *model, *backend); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Created collection %s (metric: %s, backend: %s)\n", fs.Arg(0), metric, *backend)
+		return nil
+	case "quantize":
+		fs.Parse(args[1:])
+		if fs.NArg() != 2 {
+			return fmt.Errorf("usage: collection quantize <name> <none|int8|pq>")
+		}
+		quantization, err := vector.ParseQuantization(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		docStore, err := collections.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer docStore.Close()
+		if err := docStore.SetQuantization(quantization); err != nil {
+			return err
+		}
+		if err := docStore.Persist(); err != nil {
+			return err
+		}
+		if quantization == "" {
+			fmt.Printf("✅ Collection %s now searches full-precision vectors\n", fs.Arg(0))
+		} else {
+			fmt.Printf("✅ Collection %s now searches %s-quantized vectors\n", fs.Arg(0), quantization)
+		}
+		return nil
+	case "drop":
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: collection drop <name>")
+		}
+		if err := collections.Drop(fs.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️  Dropped collection %s\n", fs.Arg(0))
+		return nil
+	case "list":
+		fs.Parse(args[1:])
+		infos, err := collections.List()
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			model := info.Metadata.Model
+			if model == "" {
+				model = "-"
+			}
+			backend := info.Metadata.Backend
+			if backend == "" {
+				backend = store.BackendJSON
+			}
+			fmt.Printf("📚 %-20s %6d documents  %-6s %-6s dim %-5d %s\n",
+				info.Name, info.Documents, backend, info.Metadata.Metric, info.Metadata.Dimension, model)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown collection command: %s", args[0])
+	}
+}
@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"ollama_go/internal/ingest"
+	"ollama_go/internal/models"
+	"ollama_go/internal/store"
+)
+
+// Docs inspects and edits the indexed documents.
+//
+//	go run . docs list [-prefix url]
+//	go run . docs show <id|url>
+//	go run . docs find [-k 10] <words>...
+//	go run . docs delete [-dry-run] [-no-snapshot] <id>... | -url <url> | -prefix <url prefix>
+//	go run . docs update -id <id> [-version n] [-title t] [-file content.txt]
+func Docs(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: docs <list|show|find|delete|update> [flags]")
+	}
+
+	fs := flag.NewFlagSet("docs "+args[0], flag.ExitOnError)
+	collection := fs.String("collection", store.DefaultCollection, "collection to work on")
+	switch args[0] {
+	case "list":
+		prefix := fs.String("prefix", "", "only list URLs starting with this prefix")
+		fs.Parse(args[1:])
+		return docsList(*collection, *prefix)
+	case "show":
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: docs show <id|url>")
+		}
+		return docsShow(*collection, fs.Arg(0))
+	case "find":
+		k := fs.Int("k", 10, "number of results")
+		fs.Parse(args[1:])
+		if fs.NArg() == 0 {
+			return fmt.Errorf("usage: docs find [-k 10] <words>...")
+		}
+		return docsFind(*collection, strings.Join(fs.Args(), " "), *k)
+	case "delete":
+		url := fs.String("url", "", "delete every chunk of this page")
+		prefix := fs.String("prefix", "", "delete every document whose URL starts with this prefix")
+		dryRun := fs.Bool("dry-run", false, "only show what would be deleted")
+		noSnapshot := fs.Bool("no-snapshot", false, "do not snapshot the collection before deleting")
+		fs.Parse(args[1:])
+		return docsDelete(*collection, fs.Args(), *url, *prefix, *dryRun, *noSnapshot)
+	case "update":
+		id := fs.String("id", "", "ID of the document to update")
+		version := fs.Int64("version", store.AnyVersion, "fail unless the document is at this version")
+		title := fs.String("title", "", "new title")
+		file := fs.String("file", "", "file with the new content (- for stdin)")
+		fs.Parse(args[1:])
+		return docsUpdate(*collection, *id, *version, *title, *file)
+	default:
+		return fmt.Errorf("unknown docs command: %s", args[0])
+	}
+}
+
+func docsList(collection, prefix string) error {
+	docStore, err := openCollection(collection, "")
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+	urls := docStore.URLs(prefix)
+	for _, url := range urls {
+		fmt.Printf("%4d  %s\n", len(docStore.GetDocumentsByURL(url)), url)
+	}
+	fmt.Printf("📚 %d pages\n", len(urls))
+	return nil
+}
+
+func docsShow(collection, key string) error {
+	docStore, err := openCollection(collection, "")
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+	docs := docStore.GetDocumentsByURL(key)
+	if len(docs) == 0 {
+		doc, err := docStore.GetDocument(key)
+		if err != nil {
+			return err
+		}
+		docs = []*models.Document{doc}
+	}
+	for _, doc := range docs {
+		printDocument(doc, 0)
+	}
+	return nil
+}
+
+func docsFind(collection, query string, k int) error {
+	docStore, err := openCollection(collection, "")
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+	docs, err := docStore.SearchByKeyword(query, k)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		fmt.Println("🔍 No matching documents")
+		return nil
+	}
+	for _, doc := range docs {
+		printDocument(doc, 200)
+	}
+	return nil
+}
+
+// printDocument prints a document, truncating its content to limit
+// characters unless limit is 0
+func printDocument(doc *models.Document, limit int) {
+	content := doc.Content
+	if limit > 0 && len(content) > limit {
+		content = content[:limit] + "…"
+	}
+	chunk := ""
+	if c, ok := doc.Metadata["chunk"]; ok {
+		chunk = " chunk " + c
+	}
+	fmt.Printf("📄 %s (v%d%s)\n   %s\n   %s\n   %s\n\n", doc.ID, doc.Version, chunk, doc.Title, doc.URL, content)
+}
+
+func docsDelete(collection string, ids []string, url, prefix string, dryRun, noSnapshot bool) error {
+	selectors := 0
+	for _, set := range []bool{len(ids) > 0, url != "", prefix != ""} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return fmt.Errorf("usage: docs delete [-dry-run] [-no-snapshot] <id>... | -url <url> | -prefix <url prefix>")
+	}
+
+	docStore, err := openCollection(collection, "")
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+
+	if dryRun {
+		var urls []string
+		switch {
+		case url != "":
+			urls = []string{url}
+		case prefix != "":
+			urls = docStore.URLs(prefix)
+		}
+		count := 0
+		for _, u := range urls {
+			n := len(docStore.GetDocumentsByURL(u))
+			count += n
+			fmt.Printf("🗑️  %s (%d chunks)\n", u, n)
+		}
+		for _, id := range ids {
+			if _, err := docStore.GetDocument(id); err != nil {
+				return err
+			}
+			count++
+			fmt.Printf("🗑️  %s\n", id)
+		}
+		fmt.Printf("Would delete %d documents\n", count)
+		return nil
+	}
+
+	// URL and prefix deletes can remove many documents at once
+	if (url != "" || prefix != "") && !noSnapshot {
+		if err := SnapshotBefore(docStore, "delete"); err != nil {
+			return err
+		}
+	}
+
+	var deleted int
+	switch {
+	case url != "":
+		deleted, err = docStore.DeleteByURL(url)
+	case prefix != "":
+		deleted, err = docStore.DeleteByURLPrefix(prefix)
+	default:
+		for _, id := range ids {
+			if err = docStore.Delete(id); err != nil {
+				break
+			}
+			deleted++
+		}
+	}
+	fmt.Printf("🗑️  Deleted %d documents\n", deleted)
+	return err
+}
+
+// docsUpdate replaces the title or content of a document. New content is
+// re-embedded with the model of the collection.
+func docsUpdate(collection, id string, version int64, title, file string) error {
+	if id == "" || (title == "" && file == "") {
+		return fmt.Errorf("usage: docs update -id <id> [-version n] [-title t] [-file content.txt]")
+	}
+
+	docStore, err := openCollection(collection, "")
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+	current, err := docStore.GetDocument(id)
+	if err != nil {
+		return err
+	}
+
+	updated := *current
+	if version == store.AnyVersion {
+		version = current.Version
+	}
+	if title != "" {
+		updated.Title = title
+	}
+	if file != "" {
+		var data []byte
+		if file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read content: %w", err)
+		}
+		updated.Content = strings.TrimSpace(string(data))
+	}
+
+	embedder, err := collectionEmbedder(docStore)
+	if err != nil {
+		return err
+	}
+	defer closeEmbedder(embedder)
+
+	text := ingest.EmbeddingText(updated.Title, updated.Description, strings.Split(updated.Content, "\n"))
+	if updated.Embedding, err = embedder.Embed(context.Background(), text); err != nil {
+		return fmt.Errorf("failed to embed the updated document: %w", err)
+	}
+
+	if err := docStore.Upsert(&updated, version); err != nil {
+		if errors.Is(err, store.ErrVersionConflict) {
+			return fmt.Errorf("%w; show the document again and retry", err)
+		}
+		return err
+	}
+	fmt.Printf("✅ Updated %s to version %d\n", id, updated.Version)
+	return nil
+}
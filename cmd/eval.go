@@ -0,0 +1,413 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"ollama_go/internal"
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/eval"
+	"ollama_go/internal/models"
+	"ollama_go/internal/store"
+
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+// defaultGolden is the golden question set read by eval
+const defaultGolden = "data/golden.jsonl"
+
+// Eval measures retrieval quality against a golden question set, compares
+// two runs, or judges the quality of generated answers. Each side of a diff
+// is a saved report or an index directory.
+//
+//	go run . eval [-golden file] [-k 1,3,5,10] [-index dir] [-out report.json]
+//	go run . eval diff [-golden file] [-out diff.json] <base> <head>
+//	go run . eval answers [-golden file] [-judge model] [-min-faithfulness 0.8]
+//	go run . eval generate [-n 50] [-per-chunk 2] [-out file] [-append]
+func Eval(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "diff":
+			return evalDiff(args[1:])
+		case "answers":
+			return evalAnswers(args[1:])
+		case "generate":
+			return evalGenerate(args[1:])
+		}
+	}
+
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	golden := fs.String("golden", defaultGolden, "JSONL file of questions and their relevant URLs")
+	cutoffs := fs.String("k", "1,3,5,10", "comma-separated rank cutoffs")
+	index := fs.String("index", store.DefaultDir, "index directory to evaluate")
+	label := fs.String("label", "", "name of this run in reports (default: the index directory)")
+	out := fs.String("out", "data/eval_report.json", "where to write the JSON report")
+	fs.Parse(args)
+
+	ks, err := parseCutoffs(*cutoffs)
+	if err != nil {
+		return err
+	}
+	questions, err := eval.LoadQuestions(*golden)
+	if err != nil {
+		return err
+	}
+
+	embedders, err := newEmbedderSet()
+	if err != nil {
+		return err
+	}
+	defer closeEmbedderSet(embedders)
+
+	report, err := evaluateIndex(context.Background(), embedders, *index, *golden, questions, ks)
+	if err != nil {
+		return err
+	}
+	if *label != "" {
+		report.Label = *label
+	}
+
+	fmt.Println()
+	report.Print()
+	if err := report.WriteFile(*out); err != nil {
+		return err
+	}
+	fmt.Printf("💾 Report written to %s\n", *out)
+	return nil
+}
+
+func evalDiff(args []string) error {
+	fs := flag.NewFlagSet("eval diff", flag.ExitOnError)
+	golden := fs.String("golden", defaultGolden, "golden set used when a side is an index directory")
+	cutoffs := fs.String("k", "1,3,5,10", "rank cutoffs used when a side is an index directory")
+	out := fs.String("out", "", "also write the diff as JSON to this file")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: eval diff [flags] <base report|index dir> <head report|index dir>")
+	}
+
+	// Embedders and questions are only needed if a side must be evaluated
+	var (
+		embedders *embedding.Set
+		questions []eval.Question
+		ks        []int
+	)
+	load := func(path string) (*eval.Report, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return eval.LoadReport(path)
+		}
+
+		if embedders == nil {
+			if ks, err = parseCutoffs(*cutoffs); err != nil {
+				return nil, err
+			}
+			if questions, err = eval.LoadQuestions(*golden); err != nil {
+				return nil, err
+			}
+			if embedders, err = newEmbedderSet(); err != nil {
+				return nil, err
+			}
+		}
+		return evaluateIndex(context.Background(), embedders, path, *golden, questions, ks)
+	}
+
+	base, err := load(fs.Arg(0))
+	if err == nil {
+		var head *eval.Report
+		if head, err = load(fs.Arg(1)); err == nil {
+			err = printDiff(base, head, *out)
+		}
+	}
+	if embedders != nil {
+		closeEmbedderSet(embedders)
+	}
+	return err
+}
+
+func printDiff(base, head *eval.Report, out string) error {
+	diff, err := eval.Compare(base, head)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	diff.Print()
+	if out == "" {
+		return nil
+	}
+	if err := diff.WriteFile(out); err != nil {
+		return err
+	}
+	fmt.Printf("💾 Diff written to %s\n", out)
+	return nil
+}
+
+// evalAnswers generates an answer to every golden question and has an LLM
+// judge score them; it fails when an average is below its threshold
+func evalAnswers(args []string) error {
+	fs := flag.NewFlagSet("eval answers", flag.ExitOnError)
+	golden := fs.String("golden", defaultGolden, "JSONL file of questions")
+	index := fs.String("index", store.DefaultDir, "index directory to answer from")
+	model := fs.String("model", defaultModel, "Ollama model that answers")
+	judgeModel := fs.String("judge", "", "Ollama model that judges (default: -model)")
+	topK := fs.Int("top-k", 3, "documents retrieved per question")
+	label := fs.String("label", "", "name of this run in the report (default: the index directory)")
+	out := fs.String("out", "data/eval_answers.json", "where to write the JSON report")
+	var thresholds eval.Thresholds
+	fs.Float64Var(&thresholds.Faithfulness, "min-faithfulness", 0.8, "fail below this average faithfulness (0-1, 0 disables)")
+	fs.Float64Var(&thresholds.Relevance, "min-relevance", 0.7, "fail below this average answer relevance (0-1, 0 disables)")
+	fs.Float64Var(&thresholds.Citations, "min-citations", 0, "fail below this average citation correctness (0-1, 0 disables)")
+	fs.Float64Var(&thresholds.MaxUnjudged, "max-unjudged", 0, "fail when more than this fraction of answers could not be judged (0-1)")
+	fs.Parse(args)
+
+	if *judgeModel == "" {
+		*judgeModel = *model
+	}
+	questions, err := eval.LoadQuestions(*golden)
+	if err != nil {
+		return err
+	}
+
+	docStore, documents, err := openIndex(*index)
+	if err != nil {
+		return err
+	}
+	embedder, err := collectionEmbedder(docStore)
+	if err != nil {
+		return err
+	}
+	defer closeEmbedder(embedder)
+	ragService, err := internal.NewRAGService(*model, embedder, docStore, *topK)
+	if err != nil {
+		return err
+	}
+	judgeLLM, err := ollama.New(ollama.WithModel(*judgeModel), ollama.WithFormat("json"))
+	if err != nil {
+		return fmt.Errorf("failed to initialize judge: %w", err)
+	}
+
+	fmt.Printf("🧪 Answering %d questions with %s, judged by %s\n", len(questions), *model, *judgeModel)
+	asked := 0
+	answer := func(ctx context.Context, question string) (string, []*models.Document, error) {
+		asked++
+		fmt.Printf("💬 [%d/%d] %s\n", asked, len(questions), question)
+		return ragService.QueryWithSources(ctx, question, nil)
+	}
+
+	report, err := eval.RunAnswers(context.Background(), answer, eval.NewJudge(judgeLLM), questions)
+	if err != nil {
+		return err
+	}
+	report.Label = *index
+	if *label != "" {
+		report.Label = *label
+	}
+	report.Config = eval.AnswerConfig{
+		Golden:    *golden,
+		Index:     *index,
+		Model:     *model,
+		Judge:     *judgeModel,
+		Documents: documents,
+	}
+	passed := report.Gate(thresholds)
+
+	fmt.Println()
+	report.Print()
+	if err := report.WriteFile(*out); err != nil {
+		return err
+	}
+	fmt.Printf("💾 Report written to %s\n", *out)
+
+	if !passed {
+		return fmt.Errorf("answer quality is below the thresholds")
+	}
+	return nil
+}
+
+// evalGenerate bootstraps a golden set: it samples chunks from the index,
+// has the LLM write questions each chunk answers, and keeps the questions
+// that pass the quality and duplicate filters
+func evalGenerate(args []string) error {
+	fs := flag.NewFlagSet("eval generate", flag.ExitOnError)
+	index := fs.String("index", store.DefaultDir, "index directory to sample chunks from")
+	model := fs.String("model", defaultModel, "Ollama model that writes the questions")
+	chunks := fs.Int("n", 50, "number of chunks to sample")
+	perChunk := fs.Int("per-chunk", 2, "questions requested per chunk")
+	seed := fs.Int64("seed", 1, "seed for sampling chunks")
+	out := fs.String("out", defaultGolden, "JSONL file to write")
+	appendFile := fs.Bool("append", false, "add to an existing golden set, skipping duplicates of its questions")
+	force := fs.Bool("force", false, "overwrite an existing golden set")
+	fs.Parse(args)
+
+	var existing []eval.Question
+	if _, err := os.Stat(*out); err == nil {
+		switch {
+		case *appendFile:
+			if existing, err = eval.LoadQuestions(*out); err != nil {
+				return err
+			}
+		case !*force:
+			return fmt.Errorf("%s already exists; use -append to add to it or -force to overwrite it", *out)
+		}
+	}
+
+	docStore, _, err := openIndex(*index)
+	if err != nil {
+		return err
+	}
+	llm, err := ollama.New(ollama.WithModel(*model), ollama.WithFormat("json"))
+	if err != nil {
+		return fmt.Errorf("failed to initialize LLM: %w", err)
+	}
+	generator := eval.NewGenerator(llm)
+	filter := eval.NewFilter(existing)
+
+	ids := make(map[string]bool, len(existing))
+	for _, q := range existing {
+		ids[q.ID] = true
+	}
+
+	sample := eval.SampleChunks(docStore.GetAllDocuments(), *chunks, *seed)
+	fmt.Printf("🧬 Generating questions from %d chunks with %s\n", len(sample), *model)
+
+	var questions []eval.Question
+	generated := 0
+	for i, doc := range sample {
+		fmt.Printf("📄 [%d/%d] %s\n", i+1, len(sample), doc.URL)
+		candidates, err := generator.Questions(context.Background(), doc, *perChunk)
+		if errors.Is(err, eval.ErrMalformed) {
+			fmt.Printf("⚠️  Skipping chunk: %v\n", err)
+			filter.Dropped["malformed response"]++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		generated += len(candidates)
+		for n, question := range candidates {
+			if !filter.Accept(question) {
+				continue
+			}
+			id := fmt.Sprintf("gen-%.8s-%d", doc.ID, n+1)
+			for suffix := 2; ids[id]; suffix++ {
+				id = fmt.Sprintf("gen-%.8s-%d-%d", doc.ID, n+1, suffix)
+			}
+			ids[id] = true
+			questions = append(questions, eval.Question{
+				ID:         id,
+				Question:   strings.TrimSpace(question),
+				Relevant:   []string{doc.URL},
+				DocumentID: doc.ID,
+			})
+		}
+	}
+
+	fmt.Printf("\n✅ Kept %d of %d generated questions\n", len(questions), generated)
+	for _, reason := range slices.Sorted(maps.Keys(filter.Dropped)) {
+		fmt.Printf("   dropped %d: %s\n", filter.Dropped[reason], reason)
+	}
+	if len(questions) == 0 {
+		return fmt.Errorf("no usable questions were generated")
+	}
+
+	if err := eval.WriteQuestions(*out, questions, *appendFile); err != nil {
+		return err
+	}
+	fmt.Printf("💾 Wrote %d questions to %s\n", len(questions), *out)
+	return nil
+}
+
+// openIndex loads the document store in dir and returns its document count
+func openIndex(dir string) (store.Store, int, error) {
+	docStore, err := store.Open(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	documents := docStore.Len()
+	if documents == 0 {
+		return nil, 0, fmt.Errorf("no documents in %s; index some first", dir)
+	}
+	return docStore, documents, nil
+}
+
+// evaluateIndex runs the golden questions against the index in dir, using
+// the embedding model the index was built with
+func evaluateIndex(ctx context.Context, embedders *embedding.Set, dir, golden string, questions []eval.Question, cutoffs []int) (*eval.Report, error) {
+	docStore, documents, err := openIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	meta := docStore.Metadata()
+	embedder, err := embedders.For(meta.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := 0
+	for _, k := range cutoffs {
+		depth = max(depth, k)
+	}
+	ragService, err := internal.NewRAGService(defaultModel, embedder, docStore, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🧪 Evaluating %d questions against %s (%d documents)\n", len(questions), dir, documents)
+	asked := 0
+	retrieve := func(ctx context.Context, question string) ([]string, error) {
+		asked++
+		fmt.Printf("🔎 [%d/%d] %s\n", asked, len(questions), question)
+
+		docs, err := ragService.GetRetrievedDocuments(ctx, question)
+		if err != nil {
+			return nil, err
+		}
+		if dim := embedder.Dimension(); dim != 0 && meta.Dimension != 0 && dim != meta.Dimension {
+			return nil, fmt.Errorf("%s produces %d-dimensional embeddings but %s was indexed with %d", embedder.ModelID(), dim, dir, meta.Dimension)
+		}
+		urls := make([]string, len(docs))
+		for i, doc := range docs {
+			urls[i] = doc.URL
+		}
+		return urls, nil
+	}
+
+	report, err := eval.Run(ctx, retrieve, questions, cutoffs)
+	if err != nil {
+		return nil, err
+	}
+	report.Label = dir
+	report.Config = eval.Config{
+		Golden:    golden,
+		Index:     dir,
+		Model:     embedder.ModelID(),
+		Metric:    string(meta.Metric),
+		Documents: documents,
+	}
+	return report, nil
+}
+
+// parseCutoffs parses a comma-separated list of rank cutoffs
+func parseCutoffs(list string) ([]int, error) {
+	var ks []int
+	for _, field := range strings.Split(list, ",") {
+		k, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || k <= 0 {
+			return nil, fmt.Errorf("invalid cutoff %q: want positive integers like 1,3,5,10", field)
+		}
+		ks = append(ks, k)
+	}
+	return ks, nil
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+
+	"ollama_go/internal/archive"
+	"ollama_go/internal/store"
+)
+
+// Export writes the documents of a collection in a portable format.
+//
+//	go run . export [-collection name] [-format jsonl|npy] [-no-embeddings] [-prefix url] -out <path>
+func Export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	collection := fs.String("collection", store.DefaultCollection, "collection to export")
+	format := fs.String("format", archive.FormatJSONL, "jsonl (one file) or npy (directory with vectors.npy)")
+	noEmbeddings := fs.Bool("no-embeddings", false, "leave out the embeddings; they are recomputed on import")
+	prefix := fs.String("prefix", "", "only export documents whose URL starts with this prefix")
+	out := fs.String("out", "", "file (jsonl) or directory (npy) to write")
+	fs.Parse(args)
+
+	if *out == "" {
+		return fmt.Errorf("usage: export [-collection name] [-format jsonl|npy] [-no-embeddings] [-prefix url] -out <path>")
+	}
+
+	docStore, err := openCollection(*collection, "")
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+
+	meta := docStore.Metadata()
+	docs := docStore.Filter(store.Filter{URLPrefix: *prefix, Embeddings: !*noEmbeddings})
+	manifest, err := archive.Write(*out, docs, archive.Options{
+		Format:   *format,
+		Metric:   meta.Metric,
+		Model:    meta.Model,
+		NoVector: *noEmbeddings,
+	})
+	if err != nil {
+		return err
+	}
+
+	embeddings := "without embeddings"
+	if manifest.Embeddings {
+		embeddings = fmt.Sprintf("with %d-dimensional %s embeddings", manifest.Dimension, manifest.Model)
+	}
+	fmt.Printf("📦 Exported %d documents %s to %s\n", manifest.Documents, embeddings, *out)
+	return nil
+}
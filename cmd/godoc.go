@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"strings"
+
+	"ollama_go/internal/ingest"
+	"ollama_go/internal/store"
+)
+
+// GoDoc indexes the documentation of a local Go module without the network.
+//
+//	go run . godoc [-goroot] [dir]
+func GoDoc(args []string) error {
+	fs := flag.NewFlagSet("godoc", flag.ExitOnError)
+	goroot := fs.Bool("goroot", false, "index the standard library in GOROOT/src")
+	workers := fs.Int("workers", 3, "number of parallel embedding workers")
+	metric := fs.String("metric", "", "distance metric of a new index: cosine, dot or l2")
+	collection := fs.String("collection", store.DefaultCollection, "collection to add the documents to")
+	fs.Parse(args)
+
+	root := "."
+	if fs.NArg() > 0 {
+		root = fs.Arg(0)
+	}
+	if *goroot {
+		root = filepath.Join(build.Default.GOROOT, "src")
+	}
+
+	fmt.Printf("📚 Extracting Go documentation from %s...\n", root)
+	pages, err := ingest.LoadGoDoc(root)
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		fmt.Println("\n⚠️  No documented packages found!")
+		return nil
+	}
+	fmt.Printf("✅ Extracted %d symbols\n", len(pages))
+
+	docStore, err := openCollection(*collection, *metric)
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+
+	embedder, err := collectionEmbedder(docStore)
+	if err != nil {
+		return err
+	}
+	defer closeEmbedder(embedder)
+
+	fmt.Println("\n🔄 Generating embeddings for extracted documentation...")
+	saved := ingest.NewIndexer(embedder, docStore, *workers).Index(context.Background(), pages)
+
+	fmt.Println("\n" + strings.Repeat("=", 80))
+	fmt.Printf("✅ Generated and saved %d embeddings\n", saved)
+	fmt.Println(strings.Repeat("=", 80))
+	return nil
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"ollama_go/internal/archive"
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/ingest"
+	"ollama_go/internal/models"
+	"ollama_go/internal/store"
+)
+
+// Import loads an export into a collection. Exported embeddings are kept if
+// they come from the collection's model and dimension; an export without
+// embeddings, or -reembed, embeds the documents with the collection's model.
+//
+//	go run . import [-collection name] [-reembed] <export>
+func Import(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	collection := fs.String("collection", store.DefaultCollection, "collection to import into")
+	reembed := fs.Bool("reembed", false, "ignore the exported embeddings and embed the documents with the collection's model")
+	batchSize := fs.Int("batch", 32, "documents per embedding request")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: import [-collection name] [-reembed] <export file or directory>")
+	}
+
+	manifest, docs, err := archive.Read(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("📦 %s: %d documents exported %s\n", fs.Arg(0), manifest.Documents, manifest.ExportedAt.Format("2006-01-02 15:04"))
+
+	docStore, err := openCollection(*collection, "")
+	if err != nil {
+		return err
+	}
+	defer docStore.Close()
+
+	if manifest.Embeddings && !*reembed {
+		if err := checkCompatible(manifest, docStore.Metadata(), *collection); err != nil {
+			return err
+		}
+		if manifest.Metric != "" {
+			if err := docStore.SetMetric(manifest.Metric); err != nil {
+				return err
+			}
+		}
+		if err := docStore.SetModel(manifest.Model); err != nil {
+			return err
+		}
+	} else {
+		embedder, err := collectionEmbedder(docStore)
+		if err != nil {
+			return err
+		}
+		defer closeEmbedder(embedder)
+		fmt.Printf("🔄 Embedding %d documents with %s...\n", len(docs), embedder.ModelID())
+		if err := embedDocuments(context.Background(), embedder, docs, *batchSize); err != nil {
+			return err
+		}
+	}
+
+	if err := docStore.SaveDocuments(docs); err != nil {
+		return fmt.Errorf("failed to save imported documents: %w", err)
+	}
+	fmt.Printf("✅ Imported %d documents into %s\n", len(docs), *collection)
+	return nil
+}
+
+// checkCompatible verifies that exported embeddings can be searched together
+// with the documents of the collection. An empty collection takes the metric
+// of the export.
+func checkCompatible(m *archive.Manifest, meta store.IndexMetadata, collection string) error {
+	if meta.Dimension != 0 && m.Metric != "" && m.Metric != meta.Metric {
+		return fmt.Errorf("export was indexed with the %s metric but collection %s uses %s; import with -reembed", m.Metric, collection, meta.Metric)
+	}
+	if meta.Dimension != 0 && m.Dimension != meta.Dimension {
+		return fmt.Errorf("export has %d-dimensional embeddings but collection %s has %d; import with -reembed", m.Dimension, collection, meta.Dimension)
+	}
+	if meta.Model != "" && m.Model != "" && m.Model != meta.Model {
+		return fmt.Errorf("export was embedded with %s but collection %s uses %s; import with -reembed", m.Model, collection, meta.Model)
+	}
+	return nil
+}
+
+// embedDocuments replaces the embeddings of docs, embedding batchSize
+// documents per request
+func embedDocuments(ctx context.Context, embedder embedding.Embedder, docs []*models.Document, batchSize int) error {
+	batchSize = max(batchSize, 1)
+	for start := 0; start < len(docs); start += batchSize {
+		batch := docs[start:min(start+batchSize, len(docs))]
+		texts := make([]string, len(batch))
+		for i, doc := range batch {
+			texts[i] = ingest.EmbeddingText(doc.Title, doc.Description, strings.Split(doc.Content, "\n"))
+		}
+		embeddings, err := embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed documents: %w", err)
+		}
+		for i, doc := range batch {
+			doc.Embedding = embeddings[i]
+		}
+		fmt.Printf("🔄 Embedded %d/%d\n", start+len(batch), len(docs))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/ingest"
+	"ollama_go/internal/models"
+	"ollama_go/internal/store"
+)
+
+// Reembed switches a collection to another embedding model without crawling
+// again. The documents are embedded in batches into a shadow index next to
+// the live one, which keeps answering queries with the old model, and the
+// shadow index is swapped in once every document has a new vector. An
+// interrupted run continues with -resume.
+//
+//	go run . reembed [-collection name] -model provider/model [-batch 32] [-resume] [-no-snapshot]
+func Reembed(args []string) error {
+	fs := flag.NewFlagSet("reembed", flag.ExitOnError)
+	collection := fs.String("collection", store.DefaultCollection, "collection to re-embed")
+	model := fs.String("model", "", "embedding model ID to switch to, e.g. ollama/nomic-embed-text or hash/fnv-256")
+	batchSize := fs.Int("batch", 32, "documents per embedding request")
+	resume := fs.Bool("resume", false, "continue an interrupted re-embedding")
+	noSnapshot := fs.Bool("no-snapshot", false, "do not snapshot the collection before swapping in the new index")
+	fs.Parse(args)
+
+	if *model == "" || fs.NArg() != 0 {
+		return fmt.Errorf("usage: reembed [-collection name] -model provider/model [-batch 32] [-resume]")
+	}
+	cfg, err := defaultEmbedderConfig().WithModelID(*model)
+	if err != nil {
+		return err
+	}
+	embedder, err := embedding.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize embedding service: %w", err)
+	}
+	defer closeEmbedder(embedder)
+
+	source, err := openCollection(*collection, "")
+	if err != nil {
+		return err
+	}
+	dir := source.Dir()
+	// A run interrupted while swapping is finished when the store is opened
+	_, statErr := os.Stat(filepath.Join(store.ShadowDir(dir), "index.json"))
+	if source.Metadata().Model == embedder.ModelID() && (!*resume || os.IsNotExist(statErr)) {
+		source.Close()
+		fmt.Printf("✅ %s is already embedded with %s\n", *collection, embedder.ModelID())
+		return nil
+	}
+	shadow, err := store.OpenShadow(source, embedder.ModelID(), *resume)
+	if err != nil {
+		source.Close()
+		return err
+	}
+	fmt.Printf("🔄 Re-embedding %s: %s → %s\n", *collection, modelName(source.Metadata().Model), embedder.ModelID())
+
+	// Cancel on Ctrl-C; every saved batch stays in the shadow index
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	embedded, err := reembedPending(ctx, source, shadow, embedder, *batchSize)
+	source.Close()
+	if err == nil {
+		// Catch up with documents written by other processes meanwhile,
+		// holding the lock until the swap so that no more are written
+		var lock *store.Lock
+		if lock, err = store.LockExclusive(dir); err != nil {
+			shadow.Close()
+			return err
+		}
+		defer lock.Unlock()
+		if source, err = openCollection(*collection, ""); err == nil {
+			var n int
+			n, err = reembedPending(ctx, source, shadow, embedder, *batchSize)
+			embedded += n
+			if err == nil && !*noSnapshot {
+				err = SnapshotBefore(source, "reembed")
+			}
+			source.Close()
+		}
+	}
+	total := shadow.Len()
+	dimension := shadow.Metadata().Dimension
+	if closeErr := shadow.Close(); err == nil {
+		err = closeErr
+	}
+	if ctx.Err() != nil {
+		fmt.Printf("\n💾 Re-embedded documents are kept in %s. Run 'go run . reembed -collection %s -model %s -resume' to continue.\n",
+			store.ShadowDir(dir), *collection, *model)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := store.SwapShadow(dir); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Re-embedded %d documents (%d total) of %s with %s, dimension %d\n",
+		embedded, total, *collection, embedder.ModelID(), dimension)
+	return nil
+}
+
+// reembedPending embeds the documents of source that are missing or out of
+// date in shadow, and removes from shadow those source no longer has. It
+// returns the number of documents embedded.
+func reembedPending(ctx context.Context, source, shadow store.Store, embedder embedding.Embedder, batchSize int) (int, error) {
+	docs := source.Filter(store.Filter{})
+	var pending []*models.Document
+	for _, doc := range docs {
+		done, err := shadow.GetDocument(doc.ID)
+		if err == nil && done.Version == doc.Version && done.Content == doc.Content {
+			continue
+		}
+		pending = append(pending, doc)
+	}
+	for _, doc := range shadow.GetAllDocuments() {
+		if _, err := source.GetDocument(doc.ID); errors.Is(err, store.ErrNotFound) {
+			if err := shadow.Delete(doc.ID); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	if done := len(docs) - len(pending); done > 0 {
+		fmt.Printf("♻️  %d of %d documents already re-embedded\n", done, len(docs))
+	}
+
+	batchSize = max(batchSize, 1)
+	start := time.Now()
+	for i := 0; i < len(pending); i += batchSize {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+
+		// Copies, so the live index keeps its own vectors until the swap
+		batch := pending[i:min(i+batchSize, len(pending))]
+		copies := make([]*models.Document, len(batch))
+		texts := make([]string, len(batch))
+		for j, doc := range batch {
+			c := *doc
+			c.Metadata = maps.Clone(doc.Metadata)
+			copies[j] = &c
+			texts[j] = ingest.EmbeddingText(doc.Title, doc.Description, strings.Split(doc.Content, "\n"))
+		}
+		embeddings, err := embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return i, fmt.Errorf("failed to embed documents: %w", err)
+		}
+		for j, c := range copies {
+			c.Embedding = embeddings[j]
+		}
+		if err := shadow.CopyDocuments(copies); err != nil {
+			return i, fmt.Errorf("failed to save re-embedded documents: %w", err)
+		}
+
+		n := i + len(batch)
+		rate := float64(n) / time.Since(start).Seconds()
+		eta := time.Duration(float64(len(pending)-n) / rate * float64(time.Second))
+		fmt.Printf("🔄 Re-embedded %d/%d (%.1f docs/s, %s left)\n", len(docs)-len(pending)+n, len(docs), rate, eta.Round(time.Second))
+	}
+	return len(pending), nil
+}
+
+// modelName returns id, or a placeholder for an index without a recorded model
+func modelName(id string) string {
+	if id == "" {
+		return "(unknown model)"
+	}
+	return id
+}
@@ -1 +1,95 @@
-package commands
\ No newline at end of file
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/store"
+	"ollama_go/internal/vector"
+)
+
+// defaultModel is the Ollama model used for embeddings and generation
+const defaultModel = "llama3:latest"
+
+// defaultEmbedderConfig is the embedding backend selected by the environment
+func defaultEmbedderConfig() embedding.Config {
+	return embedding.ConfigFromEnv(defaultModel)
+}
+
+// newEmbedderSet creates embedders for indexes built with any model; the
+// default one is the backend selected by the environment
+func newEmbedderSet() (*embedding.Set, error) {
+	set, err := embedding.NewSet(defaultEmbedderConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize embedding service: %w", err)
+	}
+	return set, nil
+}
+
+// closeEmbedder persists the embedding cache; a failure only costs cache hits
+func closeEmbedder(embedder embedding.Embedder) {
+	if err := embedding.Close(embedder); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+}
+
+// closeEmbedderSet persists the cache shared by the embedders of set
+func closeEmbedderSet(set *embedding.Set) {
+	if err := set.Close(); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+}
+
+// openCollection loads the named collection and, if metricName is set,
+// selects the metric of a new index (an existing index keeps its own)
+func openCollection(name, metricName string) (store.Store, error) {
+	docStore, err := store.NewCollections(store.DefaultDir).Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if metricName == "" {
+		return docStore, nil
+	}
+
+	metric, err := vector.ParseMetric(metricName)
+	if err != nil {
+		return nil, err
+	}
+	if err := docStore.SetMetric(metric); err != nil {
+		return nil, err
+	}
+	return docStore, nil
+}
+
+// collectionEmbedder creates the embedder of the model docStore was indexed
+// with. A store without a recorded model gets the backend selected by the
+// environment, which is recorded with the next save.
+func collectionEmbedder(docStore store.Store) (embedding.Embedder, error) {
+	cfg := defaultEmbedderConfig()
+	if model := docStore.Metadata().Model; model != "" {
+		var err error
+		if cfg, err = cfg.WithModelID(model); err != nil {
+			return nil, err
+		}
+	}
+	embedder, err := embedding.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize embedding service: %w", err)
+	}
+	if err := docStore.SetModel(embedder.ModelID()); err != nil {
+		closeEmbedder(embedder)
+		return nil, err
+	}
+	return embedder, nil
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeated flag
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"ollama_go/internal/store"
+)
+
+// Snapshot creates, lists, restores, deletes and prunes snapshots of a
+// collection. Automatic snapshots are also taken before every crawl and bulk
+// delete and pruned by store.DefaultRetention.
+//
+//	go run . snapshot create [-collection name] [snapshot]
+//	go run . snapshot list [-collection name]
+//	go run . snapshot restore [-collection name] <snapshot> | -at 2024-05-01T12:00:00Z
+//	go run . snapshot delete [-collection name] <snapshot>
+//	go run . snapshot prune [-collection name] [-keep 10] [-max-age 720h]
+func Snapshot(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: snapshot <create|list|restore|delete|prune> [flags]")
+	}
+
+	fs := flag.NewFlagSet("snapshot "+args[0], flag.ExitOnError)
+	collection := fs.String("collection", store.DefaultCollection, "collection to work on")
+	switch args[0] {
+	case "create":
+		fs.Parse(args[1:])
+		if fs.NArg() > 1 {
+			return fmt.Errorf("usage: snapshot create [-collection name] [snapshot]")
+		}
+		docStore, err := openCollection(*collection, "")
+		if err != nil {
+			return err
+		}
+		defer docStore.Close()
+		info, err := docStore.Snapshot(fs.Arg(0), "manual", false)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("📸 Created snapshot %s of %s (%d documents)\n", info.Name, *collection, info.Documents)
+		return nil
+	case "list":
+		fs.Parse(args[1:])
+		dir, err := snapshotDir(*collection)
+		if err != nil {
+			return err
+		}
+		infos, err := store.ListSnapshots(dir)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			fmt.Printf("No snapshots of %s\n", *collection)
+		}
+		for _, info := range infos {
+			kind := "manual"
+			if info.Auto {
+				kind = "auto"
+			}
+			fmt.Printf("📸 %-36s %s  %6d documents  %-6s %s\n",
+				info.Name, info.CreatedAt.Local().Format(time.DateTime), info.Documents, kind, info.Reason)
+		}
+		return nil
+	case "restore":
+		at := fs.String("at", "", "restore the newest snapshot taken at or before this RFC 3339 time")
+		fs.Parse(args[1:])
+		if (fs.NArg() == 1) == (*at != "") {
+			return fmt.Errorf("usage: snapshot restore [-collection name] <snapshot> | -at <time>")
+		}
+		return snapshotRestore(*collection, fs.Arg(0), *at)
+	case "delete":
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: snapshot delete [-collection name] <snapshot>")
+		}
+		dir, err := snapshotDir(*collection)
+		if err != nil {
+			return err
+		}
+		if err := store.DeleteSnapshot(dir, fs.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️  Deleted snapshot %s\n", fs.Arg(0))
+		return nil
+	case "prune":
+		keep := fs.Int("keep", store.DefaultRetention.Keep, "newest automatic snapshots to keep (0 for no limit)")
+		maxAge := fs.Duration("max-age", store.DefaultRetention.MaxAge, "delete automatic snapshots older than this (0 for no limit)")
+		fs.Parse(args[1:])
+		dir, err := snapshotDir(*collection)
+		if err != nil {
+			return err
+		}
+		pruned, err := store.PruneSnapshots(dir, store.Retention{Keep: *keep, MaxAge: *maxAge})
+		for _, name := range pruned {
+			fmt.Printf("🗑️  Deleted snapshot %s\n", name)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Pruned %d snapshots\n", len(pruned))
+		return nil
+	default:
+		return fmt.Errorf("unknown snapshot command: %s", args[0])
+	}
+}
+
+// SnapshotBefore takes an automatic snapshot of docStore before a destructive
+// operation such as a crawl or bulk delete, and prunes old ones
+func SnapshotBefore(docStore store.Store, reason string) error {
+	info, pruned, err := store.AutoSnapshot(docStore, reason, store.DefaultRetention)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot before %s (skip with -no-snapshot): %w", reason, err)
+	}
+	if info != nil {
+		fmt.Printf("📸 Saved snapshot %s (%d documents)\n", info.Name, info.Documents)
+	}
+	if len(pruned) > 0 {
+		fmt.Printf("🗑️  Pruned %d old snapshots\n", len(pruned))
+	}
+	return nil
+}
+
+// snapshotRestore replaces a collection with a snapshot, after snapshotting
+// its current documents so the restore itself can be undone
+func snapshotRestore(collection, name, at string) error {
+	dir, err := snapshotDir(collection)
+	if err != nil {
+		return err
+	}
+	if at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return fmt.Errorf("invalid -at time: %w", err)
+		}
+		info, err := store.SnapshotAt(dir, t)
+		if err != nil {
+			return err
+		}
+		name = info.Name
+	}
+
+	// Not pruned here, which could delete the snapshot being restored
+	docStore, err := openCollection(collection, "")
+	if err != nil {
+		return err
+	}
+	if docStore.Len() > 0 {
+		var info store.SnapshotInfo
+		if info, err = docStore.Snapshot("", "restore", true); err == nil {
+			fmt.Printf("📸 Saved snapshot %s (%d documents)\n", info.Name, info.Documents)
+		}
+	}
+	if closeErr := docStore.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := store.RestoreSnapshot(dir, name); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Restored %s from snapshot %s\n", collection, name)
+	return nil
+}
+
+// snapshotDir returns the directory of an existing collection
+func snapshotDir(collection string) (string, error) {
+	collections := store.NewCollections(store.DefaultDir)
+	if !collections.Exists(collection) {
+		return "", fmt.Errorf("%w: collection %s", store.ErrNotFound, collection)
+	}
+	return collections.Dir(collection)
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	commands "ollama_go/cmd"
+	"ollama_go/internal"
+	"ollama_go/internal/crawl"
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/eval"
+	"ollama_go/internal/models"
+	"ollama_go/internal/ollamatest"
+	"ollama_go/internal/resilience"
+	"ollama_go/internal/store"
+	"ollama_go/internal/vector"
+
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+const testModel = "llama3:latest"
+
+// setup starts the fake Ollama server and the fixture site, and runs the test
+// in a temporary directory so data/ does not touch the repository
+func setup(t *testing.T) (*ollamatest.Server, *httptest.Server) {
+	t.Helper()
+
+	site, err := filepath.Abs(filepath.Join("testdata", "site"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	siteServer := httptest.NewServer(http.FileServer(http.Dir(site)))
+	t.Cleanup(siteServer.Close)
+
+	t.Chdir(t.TempDir())
+	t.Setenv("RAG_EMBEDDER", "ollama")
+	t.Setenv("RAG_EMBED_CACHE", "off")
+
+	return ollamatest.New(t, 128), siteServer
+}
+
+// crawlFixture crawls the fixture site and returns the resulting store
+func crawlFixture(t *testing.T, siteURL string) *store.DocumentStore {
+	t.Helper()
+
+	crawling([]string{"-seed", siteURL + "/", "-delay", "0", "-max-pages", "10"})
+
+	docStore := store.NewDocumentStore()
+	if err := docStore.LoadFromDisk(); err != nil {
+		t.Fatalf("failed to load crawled documents: %v", err)
+	}
+	return docStore
+}
+
+func newRAGService(t *testing.T, docStore *store.DocumentStore) *internal.RAGService {
+	t.Helper()
+
+	embedder, err := embedding.New(embedding.ConfigFromEnv(testModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ragService, err := internal.NewRAGService(testModel, embedder, docStore, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ragService
+}
+
+func TestCrawlStoreQuery(t *testing.T) {
+	fake, site := setup(t)
+	docStore := crawlFixture(t, site.URL)
+
+	urls := make(map[string]bool)
+	for _, doc := range docStore.Filter(store.Filter{Embeddings: true}) {
+		urls[strings.TrimPrefix(doc.URL, site.URL)] = true
+		if len(doc.Embedding) != 128 {
+			t.Errorf("%s: embedding dimension %d, want 128", doc.URL, len(doc.Embedding))
+		}
+	}
+	for _, path := range []string{"/", "/goroutines.html", "/maps.html"} {
+		if !urls[path] {
+			t.Errorf("%s was not indexed; got %v", path, urls)
+		}
+	}
+	if urls["/private/secret.html"] {
+		t.Error("page disallowed by robots.txt was indexed")
+	}
+
+	ragService := newRAGService(t, docStore)
+	ctx := context.Background()
+
+	docs, err := ragService.GetRetrievedDocuments(ctx, "How do goroutines communicate over channels?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) == 0 || !strings.HasSuffix(docs[0].URL, "/goroutines.html") {
+		t.Errorf("top document should be the goroutines page, got %v", docs)
+	}
+
+	answer := "Goroutines communicate over channels [Document 1]."
+	fake.Script(answer)
+
+	var streamed strings.Builder
+	response, err := ragService.Query(ctx, "How do goroutines communicate over channels?", func(chunk string) {
+		streamed.WriteString(chunk)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != answer || streamed.String() != answer {
+		t.Errorf("response %q, streamed %q; want %q", response, streamed.String(), answer)
+	}
+
+	chats := fake.Requests("/api/chat")
+	if len(chats) != 1 {
+		t.Fatalf("got %d chat requests, want 1", len(chats))
+	}
+	if !strings.Contains(chats[0].Prompt, "lightweight thread managed by the Go runtime") {
+		t.Errorf("prompt does not contain the retrieved context:\n%s", chats[0].Prompt)
+	}
+}
+
+func TestQueryRetriesTransientFailure(t *testing.T) {
+	fake, site := setup(t)
+	ragService := newRAGService(t, crawlFixture(t, site.URL))
+
+	// The next request (embedding the query) fails with 503 and is retried
+	fake.Fail(1, http.StatusServiceUnavailable, "server busy")
+	fake.Script("Maps are hash tables.")
+
+	response, err := ragService.Query(context.Background(), "What is a map?", nil)
+	if err != nil {
+		t.Fatalf("transient failure was not retried: %v", err)
+	}
+	if response != "Maps are hash tables." {
+		t.Errorf("response %q", response)
+	}
+}
+
+func TestQueryModelNotPulled(t *testing.T) {
+	fake, site := setup(t)
+	ragService := newRAGService(t, crawlFixture(t, site.URL))
+
+	fake.SetModels("mistral:latest")
+
+	_, err := ragService.Query(context.Background(), "What is a map?", nil)
+	if !errors.Is(err, resilience.ErrModelNotFound) {
+		t.Fatalf("got %v, want ErrModelNotFound", err)
+	}
+	if got := len(fake.Requests("/api/embed")); got == 0 {
+		t.Error("query was not embedded")
+	}
+}
+
+func TestAnswerEvaluation(t *testing.T) {
+	fake, site := setup(t)
+	ragService := newRAGService(t, crawlFixture(t, site.URL))
+
+	judgeLLM, err := ollama.New(ollama.WithModel("judge:latest"), ollama.WithFormat("json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.SetModels(testModel, "judge:latest")
+
+	// Answers and verdicts alternate: the first answer cites a document that
+	// was never retrieved, the second verdict is not JSON
+	fake.Script(
+		"Goroutines communicate over channels [Document 1] [Document 7].",
+		`{"faithfulness": 5, "relevance": 4, "citations": 5, "unsupported_claims": []}`,
+		"Maps are hash tables.",
+		"looks fine to me",
+	)
+	questions := []eval.Question{
+		{ID: "goroutines", Question: "How do goroutines communicate?", Relevant: []string{site.URL + "/goroutines.html"}},
+		{ID: "maps", Question: "What is a map?", Relevant: []string{site.URL + "/maps.html"}},
+	}
+	answer := func(ctx context.Context, question string) (string, []*models.Document, error) {
+		return ragService.QueryWithSources(ctx, question, nil)
+	}
+
+	report, err := eval.RunAnswers(context.Background(), answer, eval.NewJudge(judgeLLM), questions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := report.Questions[0]
+	if first.Verdict.Faithfulness != 1 || first.Verdict.Relevance != 0.75 || first.Verdict.Citations != 0.5 {
+		t.Errorf("verdict %+v, want faithfulness 1, relevance 0.75 and citations halved to 0.5", first.Verdict)
+	}
+	if len(first.InvalidCitations) != 1 || first.InvalidCitations[0] != 7 {
+		t.Errorf("invalid citations %v, want [7]", first.InvalidCitations)
+	}
+	if report.Summary.Judged != 1 || report.Summary.Unjudged != 1 || report.Questions[1].Error == "" {
+		t.Errorf("summary %+v; the unparsable verdict should be recorded, not averaged", report.Summary)
+	}
+
+	if report.Gate(eval.Thresholds{Faithfulness: 0.8, Relevance: 0.7}) || len(report.Failures) != 1 {
+		t.Errorf("an unjudged answer should fail the default gate, got %v", report.Failures)
+	}
+	if !report.Gate(eval.Thresholds{Faithfulness: 0.8, Relevance: 0.7, MaxUnjudged: 0.5}) {
+		t.Errorf("gate failed: %v", report.Failures)
+	}
+	if report.Gate(eval.Thresholds{Faithfulness: 0.8, Citations: 0.6, MaxUnjudged: 0.5}) || len(report.Failures) != 1 {
+		t.Errorf("citations below threshold should fail exactly one check, got %v", report.Failures)
+	}
+
+	chats := fake.Requests("/api/chat")
+	if len(chats) != 4 || chats[1].Model != "judge:latest" || !strings.Contains(chats[1].Prompt, "[Document 1]") {
+		t.Errorf("judge was not asked with the retrieved context: %+v", chats)
+	}
+}
+
+func TestGenerateQuestions(t *testing.T) {
+	fake, site := setup(t)
+	crawlFixture(t, site.URL)
+
+	fake.Script(
+		`{"questions": ["How do goroutines communicate with each other?", "What does the passage say about goroutines?"]}`,
+		`{"questions": ["How do goroutines communicate with each other in Go?", "Maps?", "How is a Go map implemented internally?"]}`,
+	)
+	if err := commands.Eval([]string{"generate", "-n", "2", "-out", "data/golden.jsonl"}); err != nil {
+		t.Fatal(err)
+	}
+
+	questions, err := eval.LoadQuestions("data/golden.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 2 {
+		t.Fatalf("kept %d questions, want the 2 usable ones: %+v", len(questions), questions)
+	}
+	for _, q := range questions {
+		if q.DocumentID == "" || len(q.Relevant) != 1 || !strings.HasPrefix(q.Relevant[0], site.URL) {
+			t.Errorf("question %+v does not point to its source chunk", q)
+		}
+	}
+
+	if err := commands.Eval([]string{"generate", "-n", "1", "-out", "data/golden.jsonl"}); err == nil {
+		t.Error("existing golden set was overwritten without -force")
+	}
+}
+
+func TestCollectionsWithDifferentModels(t *testing.T) {
+	_, site := setup(t)
+	crawlFixture(t, site.URL)
+
+	if err := commands.Collection([]string{"create", "-metric", "l2", "-model", "hash/fnv-64", "notes"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("channels.md", []byte("# Channels\n\nUnbuffered channels block the sender until a receiver is ready."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := commands.Add([]string{"-collection", "notes", "channels.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := commands.Add([]string{"-collection", "missing", "channels.md"}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("adding to a missing collection: got %v, want ErrNotFound", err)
+	}
+
+	collections := store.NewCollections(store.DefaultDir)
+	embedders, err := embedding.NewSet(embedding.ConfigFromEnv(testModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sources []internal.Source
+	for _, name := range []string{store.DefaultCollection, "notes"} {
+		docStore, err := collections.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		embedder, err := embedders.For(docStore.Metadata().Model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, internal.Source{Name: name, Store: docStore, Embedder: embedder})
+	}
+	if got := sources[0].Embedder.ModelID(); got != "ollama/"+testModel {
+		t.Errorf("default collection embedded with %s", got)
+	}
+	if meta := sources[1].Store.Metadata(); meta.Model != "hash/fnv-64" || meta.Dimension != 64 {
+		t.Errorf("notes collection has metadata %+v, want hash/fnv-64 at dimension 64", meta)
+	}
+
+	ragService, err := internal.NewMultiRAGService(testModel, sources, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := ragService.GetRetrievedDocuments(context.Background(), "How do goroutines communicate over channels?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromNotes, fromSite bool
+	for _, doc := range docs {
+		fromNotes = fromNotes || strings.HasSuffix(doc.URL, "/channels.md")
+		fromSite = fromSite || strings.HasPrefix(doc.URL, site.URL)
+	}
+	if len(docs) != 3 || !fromNotes || !fromSite {
+		t.Errorf("retrieved %v, want 3 documents from both collections", docs)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	_, site := setup(t)
+	docStore := crawlFixture(t, site.URL)
+	crawled := len(docStore.GetAllDocuments())
+
+	if err := commands.Export([]string{"-out", "export.jsonl"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := commands.Export([]string{"-format", "npy", "-out", "export-npy"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Exported vectors keep their model in a new collection
+	if err := commands.Collection([]string{"create", "copy"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := commands.Import([]string{"-collection", "copy", "export-npy"}); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := store.NewCollections(store.DefaultDir).Open("copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, model := len(imported.GetAllDocuments()), imported.Metadata().Model; n != crawled || model != "ollama/"+testModel {
+		t.Errorf("imported %d of %d documents with model %s", n, crawled, model)
+	}
+	for _, doc := range docStore.Filter(store.Filter{Embeddings: true}) {
+		if got, err := imported.GetDocument(doc.ID); err != nil || !slices.Equal(got.Embedding, doc.Embedding) {
+			t.Errorf("%s was not imported with its embedding (%v)", doc.ID, err)
+		}
+	}
+
+	// A collection of another model needs the documents re-embedded
+	if err := commands.Collection([]string{"create", "-model", "hash/fnv-64", "hashed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := commands.Import([]string{"-collection", "hashed", "export.jsonl"}); err == nil || !strings.Contains(err.Error(), "-reembed") {
+		t.Errorf("importing embeddings of another model: got %v, want a hint to re-embed", err)
+	}
+	if err := commands.Import([]string{"-collection", "hashed", "-reembed", "export.jsonl"}); err != nil {
+		t.Fatal(err)
+	}
+	hashed, err := store.NewCollections(store.DefaultDir).Open("hashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, dim := len(hashed.GetAllDocuments()), hashed.Metadata().Dimension; n != crawled || dim != 64 {
+		t.Errorf("re-embedded %d of %d documents at dimension %d, want 64", n, crawled, dim)
+	}
+
+	// An empty collection takes the metric of the exported embeddings; a
+	// filled one of another metric needs them re-embedded
+	if err := commands.Collection([]string{"create", "-metric", "l2", "empty"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := commands.Import([]string{"-collection", "empty", "export.jsonl"}); err != nil {
+		t.Fatal(err)
+	}
+	empty, err := store.NewCollections(store.DefaultDir).Open("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metric := empty.Metadata().Metric; metric != vector.Cosine {
+		t.Errorf("empty collection imported cosine embeddings as %s", metric)
+	}
+	if err := commands.Collection([]string{"create", "-metric", "dot", "-model", "ollama/" + testModel, "dotted"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := commands.Import([]string{"-collection", "dotted", "-reembed", "export.jsonl"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := commands.Import([]string{"-collection", "dotted", "export.jsonl"}); err == nil || !strings.Contains(err.Error(), "metric") {
+		t.Errorf("importing cosine embeddings into a dot collection: got %v, want a metric mismatch", err)
+	}
+}
+
+func TestReembed(t *testing.T) {
+	_, site := setup(t)
+	docStore := crawlFixture(t, site.URL)
+	crawled := docStore.GetAllDocuments()
+	if err := docStore.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An interrupted run left one document in the shadow index
+	live, err := store.Open(store.DefaultDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadow, err := store.OpenShadow(live, "hash/fnv-64", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	planted := *crawled[0]
+	planted.Embedding = make([]float32, 64)
+	planted.Embedding[0] = 1
+	if err := shadow.CopyDocuments([]*models.Document{&planted}); err != nil {
+		t.Fatal(err)
+	}
+	shadow.Close()
+	live.Close()
+
+	if err := commands.Reembed([]string{"-model", "hash/fnv-64", "-resume"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.ShadowDir(store.DefaultDir)); !os.IsNotExist(err) {
+		t.Errorf("shadow index left behind after the swap: %v", err)
+	}
+
+	reembedded, err := store.Open(store.DefaultDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reembedded.Close()
+	if meta := reembedded.Metadata(); meta.Model != "hash/fnv-64" || meta.Dimension != 64 {
+		t.Errorf("metadata after re-embedding = %+v", meta)
+	}
+	for _, doc := range crawled {
+		got, err := reembedded.GetDocument(doc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != doc.Version || got.Content != doc.Content || len(got.Embedding) != 64 {
+			t.Errorf("%s: version %d, %d-dimensional embedding after re-embedding", doc.ID, got.Version, len(got.Embedding))
+		}
+	}
+	if got, _ := reembedded.GetDocument(planted.ID); !slices.Equal(got.Embedding, planted.Embedding) {
+		t.Errorf("resume embedded %s again", planted.ID)
+	}
+
+	// The old index can be restored from the snapshot taken before the swap
+	snapshots, err := store.ListSnapshots(store.DefaultDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Reason != "reembed" || snapshots[0].Documents != len(crawled) {
+		t.Errorf("snapshots after re-embedding = %+v", snapshots)
+	}
+}
+
+func TestChatFollowsReembeddedCollection(t *testing.T) {
+	_, site := setup(t)
+	crawlFixture(t, site.URL)
+
+	opened, err := store.Open(store.DefaultDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docStore, err := store.NewLive(opened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer docStore.Close()
+	embedders, err := embedding.NewSet(embedding.ConfigFromEnv(testModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ragService, err := internal.NewMultiRAGService(testModel, []internal.Source{{Name: store.DefaultCollection, Store: docStore, Embedders: embedders}}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs, err := ragService.GetRetrievedDocuments(context.Background(), "goroutines"); err != nil || len(docs) == 0 {
+		t.Fatalf("retrieval before re-embedding = %d documents, %v", len(docs), err)
+	}
+
+	// Another process moves the collection to another model
+	if err := commands.Reembed([]string{"-model", "hash/fnv-64"}); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := docStore.ReloadIfChanged(); err != nil || !reloaded {
+		t.Fatalf("ReloadIfChanged = %v, %v; want a reload", reloaded, err)
+	}
+	if model := docStore.Metadata().Model; model != "hash/fnv-64" {
+		t.Errorf("model after reload = %s", model)
+	}
+	docs, err := ragService.GetRetrievedDocuments(context.Background(), "goroutines")
+	if err != nil || len(docs) == 0 {
+		t.Errorf("retrieval after re-embedding = %d documents, %v", len(docs), err)
+	}
+}
+
+func TestCrawlResumeKeepsTarget(t *testing.T) {
+	saved := crawl.Target{Seeds: []string{"https://example.com/docs/"}, Scope: "example.com/docs", Collection: "notes"}
+
+	// A plain -resume continues the saved crawl rather than the defaults
+	cfg := parseCrawlFlags([]string{"-resume", "-state", "crawl.json"})
+	if err := cfg.restore(saved); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.seeds, saved.Seeds) || cfg.scope != saved.Scope || cfg.collection != saved.Collection {
+		t.Errorf("resumed with seeds %v, scope %q, collection %q, want %+v", cfg.seeds, cfg.scope, cfg.collection, saved)
+	}
+
+	for _, args := range [][]string{
+		{"-resume", "-seed", "https://go.dev/doc/"},
+		{"-resume", "-scope", "go.dev"},
+		{"-resume", "-state", "crawl.json", "-collection", "other"},
+		{"-resume"}, // the default state file belongs to the default collection
+	} {
+		cfg := parseCrawlFlags(args)
+		if err := cfg.restore(saved); err == nil {
+			t.Errorf("%v resumed a crawl of %+v", args, saved)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+// Package archive exports documents and their embeddings in portable formats
+// and reads them back, so an index can be shared without copying the store.
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+// Export formats
+const (
+	// FormatJSONL is one file: a manifest line, then one document per line
+	FormatJSONL = "jsonl"
+	// FormatNPY is a directory with manifest.json, documents.jsonl without
+	// embeddings and vectors.npy, a float32 NumPy matrix with one row per
+	// document in the same order
+	FormatNPY = "npy"
+)
+
+// formatVersion is bumped on incompatible changes to the layout
+const formatVersion = 1
+
+// Manifest describes an export
+type Manifest struct {
+	Format     string        `json:"format"`
+	Version    int           `json:"version"`
+	Documents  int           `json:"documents"`
+	Embeddings bool          `json:"embeddings"` // false when exported without embeddings
+	Dimension  int           `json:"dimension,omitempty"`
+	Metric     vector.Metric `json:"metric"`
+	Model      string        `json:"model,omitempty"` // embedding model ID of the source index
+	ExportedAt time.Time     `json:"exported_at"`
+}
+
+// Options control what is exported
+type Options struct {
+	Format   string // FormatJSONL (default) or FormatNPY
+	Metric   vector.Metric
+	Model    string
+	NoVector bool // leave out the embeddings, e.g. to re-embed with another model on import
+}
+
+// header is the first line of a JSONL export
+type header struct {
+	Export *Manifest `json:"export"`
+}
+
+// Write exports docs to path: a file for FormatJSONL, a directory for
+// FormatNPY. Every document must have an embedding of the same dimension
+// unless opts.NoVector is set.
+func Write(path string, docs []*models.Document, opts Options) (*Manifest, error) {
+	m := &Manifest{
+		Format:     opts.Format,
+		Version:    formatVersion,
+		Documents:  len(docs),
+		Embeddings: !opts.NoVector,
+		Metric:     opts.Metric,
+		Model:      opts.Model,
+		ExportedAt: time.Now().UTC(),
+	}
+	if m.Format == "" {
+		m.Format = FormatJSONL
+	}
+	if m.Embeddings {
+		for _, doc := range docs {
+			if m.Dimension == 0 {
+				m.Dimension = len(doc.Embedding)
+			}
+			if len(doc.Embedding) == 0 || len(doc.Embedding) != m.Dimension {
+				return nil, fmt.Errorf("document %s has a %d-dimensional embedding, not %d", doc.ID, len(doc.Embedding), m.Dimension)
+			}
+		}
+	}
+
+	switch m.Format {
+	case FormatJSONL:
+		return m, writeJSONL(path, m, docs)
+	case FormatNPY:
+		return m, writeNPYDir(path, m, docs)
+	default:
+		return nil, fmt.Errorf("unknown export format %q: use %s or %s", m.Format, FormatJSONL, FormatNPY)
+	}
+}
+
+// Read loads an export written by Write, detecting the format from path
+func Read(path string) (*Manifest, []*models.Document, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return readNPYDir(path)
+	}
+	return readJSONL(path)
+}
+
+func writeJSONL(path string, m *Manifest, docs []*models.Document) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(header{Export: m}); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	for _, doc := range docs {
+		if err := enc.Encode(withoutEmbedding(doc, !m.Embeddings)); err != nil {
+			return fmt.Errorf("failed to write document %s: %w", doc.ID, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return f.Close()
+}
+
+func readJSONL(path string) (*Manifest, []*models.Document, error) {
+	var m *Manifest
+	var docs []*models.Document
+	err := eachLine(path, func(line int, data []byte) error {
+		if m == nil {
+			var h header
+			if err := json.Unmarshal(data, &h); err != nil || h.Export == nil {
+				return fmt.Errorf("%s: line %d is not an export manifest", path, line)
+			}
+			m = h.Export
+			return nil
+		}
+		doc := &models.Document{}
+		if err := json.Unmarshal(data, doc); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if m == nil {
+		return nil, nil, fmt.Errorf("%s is empty", path)
+	}
+	return m, docs, validate(m, docs)
+}
+
+func writeNPYDir(dir string, m *Manifest, docs []*models.Document) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "documents.jsonl"))
+	if err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, doc := range docs {
+		if err := enc.Encode(withoutEmbedding(doc, true)); err != nil {
+			return fmt.Errorf("failed to write document %s: %w", doc.ID, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	vectorsPath := filepath.Join(dir, "vectors.npy")
+	if !m.Embeddings {
+		// Do not leave the vectors of an earlier export next to new documents
+		if err := os.Remove(vectorsPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	rows := make([][]float32, len(docs))
+	for i, doc := range docs {
+		rows[i] = doc.Embedding
+	}
+	return writeNPY(vectorsPath, rows, m.Dimension)
+}
+
+func readNPYDir(dir string) (*Manifest, []*models.Document, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal manifest: %w", err)
+	}
+
+	docs, err := readDocuments(filepath.Join(dir, "documents.jsonl"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.Embeddings {
+		rows, dim, err := readNPY(filepath.Join(dir, "vectors.npy"))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rows) != len(docs) {
+			return nil, nil, fmt.Errorf("vectors.npy has %d rows for %d documents", len(rows), len(docs))
+		}
+		if dim != m.Dimension {
+			return nil, nil, fmt.Errorf("vectors.npy has %d columns but the manifest says %d", dim, m.Dimension)
+		}
+		for i, doc := range docs {
+			doc.Embedding = rows[i]
+		}
+	}
+	return m, docs, validate(m, docs)
+}
+
+// readDocuments reads one JSON document per line
+func readDocuments(path string) ([]*models.Document, error) {
+	var docs []*models.Document
+	err := eachLine(path, func(line int, data []byte) error {
+		doc := &models.Document{}
+		if err := json.Unmarshal(data, doc); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// eachLine calls fn with every non-empty line of the file at path
+func eachLine(path string, fn func(line int, data []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open export: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(line, scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// validate checks the documents against the manifest
+func validate(m *Manifest, docs []*models.Document) error {
+	if m.Version > formatVersion {
+		return fmt.Errorf("export format version %d is newer than the supported %d", m.Version, formatVersion)
+	}
+	if len(docs) != m.Documents {
+		return fmt.Errorf("export holds %d documents but its manifest says %d", len(docs), m.Documents)
+	}
+	if m.Metric != "" {
+		metric, err := vector.ParseMetric(string(m.Metric))
+		if err != nil {
+			return err
+		}
+		m.Metric = metric
+	}
+	for _, doc := range docs {
+		if doc.ID == "" {
+			return fmt.Errorf("export holds a document without an ID")
+		}
+		if m.Embeddings && len(doc.Embedding) != m.Dimension {
+			return fmt.Errorf("document %s has a %d-dimensional embedding, not %d", doc.ID, len(doc.Embedding), m.Dimension)
+		}
+		if !m.Embeddings {
+			doc.Embedding = nil
+		}
+	}
+	return nil
+}
+
+// withoutEmbedding returns doc, or a copy without its embedding if strip is set
+func withoutEmbedding(doc *models.Document, strip bool) *models.Document {
+	if !strip {
+		return doc
+	}
+	c := *doc
+	c.Embedding = nil
+	return &c
+}
@@ -0,0 +1,129 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+func testDocs() []*models.Document {
+	return []*models.Document{
+		{ID: "a", URL: "https://go.dev/a", Title: "A", Content: "first", Embedding: []float32{1, 2, 3}, Metadata: map[string]string{"chunk": "0"}},
+		{ID: "b", URL: "https://go.dev/b", Title: "B", Content: "second", Embedding: []float32{-1, 0.5, 0}},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSONL, FormatNPY} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export")
+			opts := Options{Format: format, Metric: vector.L2, Model: "hash/fnv-3"}
+			if _, err := Write(path, testDocs(), opts); err != nil {
+				t.Fatal(err)
+			}
+
+			m, docs, err := Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Format != format || m.Dimension != 3 || m.Metric != vector.L2 || m.Model != "hash/fnv-3" || !m.Embeddings {
+				t.Errorf("manifest %+v", m)
+			}
+			want := testDocs()
+			if len(docs) != len(want) {
+				t.Fatalf("read %d documents, want %d", len(docs), len(want))
+			}
+			for i, doc := range docs {
+				if doc.ID != want[i].ID || doc.Content != want[i].Content || !slices.Equal(doc.Embedding, want[i].Embedding) {
+					t.Errorf("document %d: got %+v, want %+v", i, doc, want[i])
+				}
+			}
+			if docs[0].Metadata["chunk"] != "0" {
+				t.Errorf("metadata lost: %v", docs[0].Metadata)
+			}
+		})
+	}
+}
+
+func TestWithoutEmbeddings(t *testing.T) {
+	for _, format := range []string{FormatJSONL, FormatNPY} {
+		path := filepath.Join(t.TempDir(), "export")
+		if _, err := Write(path, testDocs(), Options{Format: format, NoVector: true}); err != nil {
+			t.Fatal(err)
+		}
+		m, docs, err := Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Embeddings || m.Dimension != 0 {
+			t.Errorf("%s: manifest %+v claims embeddings", format, m)
+		}
+		for _, doc := range docs {
+			if doc.Embedding != nil {
+				t.Errorf("%s: %s was exported with its embedding", format, doc.ID)
+			}
+		}
+	}
+}
+
+func TestMismatchedDimensions(t *testing.T) {
+	docs := testDocs()
+	docs[1].Embedding = []float32{1, 2}
+	if _, err := Write(filepath.Join(t.TempDir(), "export.jsonl"), docs, Options{}); err == nil {
+		t.Error("exported embeddings of different dimensions")
+	}
+}
+
+func TestNPYHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.npy")
+	if err := writeNPY(path, [][]float32{{1, 2, 3}, {4, 5, 6}}, 3); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headerLen := int(binary.LittleEndian.Uint16(data[8:10]))
+	if (10+headerLen)%64 != 0 || data[10+headerLen-1] != '\n' {
+		t.Errorf("data starts at %d, want a newline-terminated header padded to 64 bytes", 10+headerLen)
+	}
+	if !bytes.Contains(data[:10+headerLen], []byte("'shape': (2, 3)")) {
+		t.Errorf("header %q lacks the shape", data[10:10+headerLen])
+	}
+	if len(data) != 10+headerLen+2*3*4 {
+		t.Errorf("file has %d bytes, want the header and 6 float32 values", len(data))
+	}
+}
+
+func TestReadNPYChecksShape(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.npy")
+	if err := writeNPY(path, [][]float32{{1, 2, 3}, {4, 5, 6}}, 3); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, dim, err := readNPY(path); err != nil || dim != 3 || len(rows) != 2 || rows[1][2] != 6 {
+		t.Fatalf("read %v of dimension %d, %v", rows, dim, err)
+	}
+
+	// A header claiming more rows than the file holds is rejected before
+	// anything is allocated for them
+	for _, shape := range []string{"(2000000000, 3)", "(99999999999999999999, 3)", "(3, 0)", "(3, 3)"} {
+		forged := bytes.Replace(data, []byte("(2, 3)"), []byte(shape), 1)
+		if err := os.WriteFile(path, forged, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := readNPY(path); err == nil {
+			t.Errorf("read a file with the shape %s", shape)
+		}
+	}
+}
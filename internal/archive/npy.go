@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// npyMagic starts every .npy file
+const npyMagic = "\x93NUMPY"
+
+// writeNPY writes rows as a little-endian float32 matrix in NumPy's .npy
+// format (version 1.0), loadable with numpy.load
+func writeNPY(path string, rows [][]float32, dim int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	// The header is padded with spaces so the data starts 64-byte aligned
+	dict := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d), }", len(rows), dim)
+	prefix := len(npyMagic) + 4
+	padding := 64 - (prefix+len(dict)+1)%64
+	if padding == 64 {
+		padding = 0
+	}
+	dict += strings.Repeat(" ", padding) + "\n"
+
+	w := bufio.NewWriter(f)
+	w.WriteString(npyMagic)
+	w.Write([]byte{1, 0})
+	binary.Write(w, binary.LittleEndian, uint16(len(dict)))
+	w.WriteString(dict)
+
+	buf := make([]byte, 4*dim)
+	for _, row := range rows {
+		for i, x := range row {
+			binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+		}
+		w.Write(buf)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+var (
+	npyDescr = regexp.MustCompile(`'descr':\s*'([^']*)'`)
+	npyOrder = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShape = regexp.MustCompile(`'shape':\s*\((\d+),\s*(\d+)\)`)
+)
+
+// readNPY reads a two-dimensional little-endian float32 .npy matrix
+func readNPY(path string) ([][]float32, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	r := bufio.NewReader(f)
+
+	magic := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic[:len(npyMagic)]) != npyMagic {
+		return nil, 0, fmt.Errorf("%s is not a .npy file", path)
+	}
+	var headerLen uint32
+	offset := int64(len(magic))
+	switch magic[len(npyMagic)] {
+	case 1:
+		var n uint16
+		err = binary.Read(r, binary.LittleEndian, &n)
+		headerLen = uint32(n)
+		offset += 2
+	case 2, 3:
+		err = binary.Read(r, binary.LittleEndian, &headerLen)
+		offset += 4
+	default:
+		return nil, 0, fmt.Errorf("%s: unsupported .npy version %d", path, magic[len(npyMagic)])
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	offset += int64(headerLen)
+	if offset > info.Size() {
+		return nil, 0, fmt.Errorf("%s: header of %d bytes is longer than the file", path, headerLen)
+	}
+	dict := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, dict); err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	descr := npyDescr.FindSubmatch(dict)
+	order := npyOrder.FindSubmatch(dict)
+	shape := npyShape.FindSubmatch(dict)
+	if descr == nil || order == nil || shape == nil {
+		return nil, 0, fmt.Errorf("%s: unsupported header %q, want a 2-D matrix", path, strings.TrimSpace(string(dict)))
+	}
+	if string(descr[1]) != "<f4" || string(order[1]) != "False" {
+		return nil, 0, fmt.Errorf("%s holds %s values (fortran order %s), want little-endian float32 in C order", path, descr[1], order[1])
+	}
+	n, errN := strconv.Atoi(string(shape[1]))
+	dim, errDim := strconv.Atoi(string(shape[2]))
+	if errN != nil || errDim != nil {
+		return nil, 0, fmt.Errorf("%s: invalid shape (%s, %s)", path, shape[1], shape[2])
+	}
+
+	// The shape is untrusted: check it against the file before allocating
+	if data := info.Size() - offset; dim == 0 && n > 0 || dim > 0 && int64(n) > data/(4*int64(dim)) {
+		return nil, 0, fmt.Errorf("%s: shape (%d, %d) needs more data than the file holds", path, n, dim)
+	}
+
+	rows := make([][]float32, n)
+	buf := make([]byte, 4*dim)
+	for i := range rows {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, 0, fmt.Errorf("%s: row %d: %w", path, i, err)
+		}
+		row := make([]float32, dim)
+		for j := range row {
+			row[j] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*j:]))
+		}
+		rows[i] = row
+	}
+	return rows, dim, nil
+}
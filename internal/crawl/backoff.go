@@ -0,0 +1,66 @@
+package crawl
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ollama_go/internal/resilience"
+)
+
+// Backoff computes wait times for retrying throttled requests
+type Backoff struct {
+	Base       time.Duration // wait before the first retry
+	Max        time.Duration // upper bound for any single wait
+	MaxRetries int           // attempts before giving up
+}
+
+// NewBackoff creates a backoff starting at one second and capped at five minutes
+func NewBackoff(maxRetries int) *Backoff {
+	return &Backoff{
+		Base:       time.Second,
+		Max:        5 * time.Minute,
+		MaxRetries: maxRetries,
+	}
+}
+
+// Retryable reports whether a response status asks us to slow down
+func Retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// Wait returns how long to wait before retry number attempt (starting at 0).
+// A Retry-After header takes precedence over the jittered exponential backoff.
+func (b *Backoff) Wait(attempt int, retryAfter string) time.Duration {
+	if d, ok := ParseRetryAfter(retryAfter, time.Now()); ok {
+		return min(d, b.Max)
+	}
+
+	return resilience.Backoff(attempt, b.Base, b.Max)
+}
+
+// ParseRetryAfter parses a Retry-After header given either as delay seconds
+// or as an HTTP date
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
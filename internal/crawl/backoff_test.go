@@ -0,0 +1,59 @@
+package crawl
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffWait(t *testing.T) {
+	b := &Backoff{Base: time.Second, Max: 8 * time.Second, MaxRetries: 5}
+	for attempt, ceiling := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		for range 20 {
+			if d := b.Wait(attempt, ""); d < ceiling/2 || d > ceiling {
+				t.Fatalf("attempt %d waits %v, want between %v and %v", attempt, d, ceiling/2, ceiling)
+			}
+		}
+	}
+	if d := b.Wait(100, ""); d < b.Max/2 || d > b.Max {
+		t.Errorf("attempt 100 waits %v, want at most %v", d, b.Max)
+	}
+
+	// Retry-After wins over the exponential wait, up to the maximum
+	if d := b.Wait(0, "3"); d != 3*time.Second {
+		t.Errorf("Retry-After: 3 waits %v", d)
+	}
+	if d := b.Wait(0, "120"); d != b.Max {
+		t.Errorf("Retry-After: 120 waits %v, want the maximum %v", d, b.Max)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for status, want := range map[int]bool{429: true, 503: true, 500: false, 404: false, 200: false} {
+		if got := Retryable(status); got != want {
+			t.Errorf("Retryable(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
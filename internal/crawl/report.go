@@ -0,0 +1,94 @@
+package crawl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Skip reasons recorded in the crawl report
+const (
+	ReasonRobots     = "disallowed by robots.txt"
+	ReasonRobotsErr  = "robots.txt unavailable"
+	ReasonOutOfScope = "outside crawl scope"
+	ReasonMaxDepth   = "max depth reached"
+	ReasonDomain     = "domain not allowed"
+	ReasonPageLimit  = "page limit reached"
+	ReasonRetries    = "retries exhausted"
+	ReasonHTTPError  = "fetch failed"
+)
+
+// maxListed is the number of URLs printed per skip reason
+const maxListed = 20
+
+// Report collects what happened during a crawl
+type Report struct {
+	mu      sync.Mutex
+	fetched int
+	retries int
+	skipped map[string]string // URL -> reason
+}
+
+// NewReport creates an empty crawl report
+func NewReport() *Report {
+	return &Report{skipped: make(map[string]string)}
+}
+
+// Fetched records a successfully fetched page
+func (r *Report) Fetched() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetched++
+}
+
+// Retried records a retry after throttling
+func (r *Report) Retried() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries++
+}
+
+// Skip records a URL that was not crawled and why. The first reason wins.
+func (r *Report) Skip(url, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.skipped[url]; !exists {
+		r.skipped[url] = reason
+	}
+}
+
+// Print writes the report grouped by skip reason
+func (r *Report) Print() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Println("\n" + strings.Repeat("=", 80))
+	fmt.Println("📋 Crawl report")
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Printf("Fetched: %d | Retries: %d | Skipped: %d\n", r.fetched, r.retries, len(r.skipped))
+
+	byReason := make(map[string][]string)
+	for url, reason := range r.skipped {
+		byReason[reason] = append(byReason[reason], url)
+	}
+
+	reasons := make([]string, 0, len(byReason))
+	for reason := range byReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	for _, reason := range reasons {
+		urls := byReason[reason]
+		sort.Strings(urls)
+		fmt.Printf("\n⏭️  %s (%d)\n", reason, len(urls))
+		for i, url := range urls {
+			if i == maxListed {
+				fmt.Printf("   ... and %d more\n", len(urls)-maxListed)
+				break
+			}
+			fmt.Printf("   - %s\n", url)
+		}
+	}
+}
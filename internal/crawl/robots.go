@@ -0,0 +1,128 @@
+package crawl
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// RobotsPolicy fetches and caches robots.txt per host and answers whether a
+// URL may be crawled by our user agent, and how long to wait between requests.
+type RobotsPolicy struct {
+	mu        sync.Mutex // guards hosts, not the fetches
+	client    *http.Client
+	userAgent string
+	respect   bool
+	hosts     map[string]*robotsEntry
+}
+
+// robotsEntry caches the outcome of fetching one host's robots.txt. Callers
+// asking for the host while it is fetched wait for ready.
+type robotsEntry struct {
+	ready  chan struct{} // closed once robots and err are set
+	robots *robotstxt.RobotsData
+	err    error
+}
+
+// NewRobotsPolicy creates a robots.txt policy. When respect is false every
+// URL is allowed and no Crawl-delay is applied.
+func NewRobotsPolicy(userAgent string, respect bool) *RobotsPolicy {
+	return &RobotsPolicy{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		userAgent: userAgent,
+		respect:   respect,
+		hosts:     make(map[string]*robotsEntry),
+	}
+}
+
+// Allowed reports whether rawURL may be fetched
+func (p *RobotsPolicy) Allowed(rawURL string) (bool, error) {
+	if !p.respect {
+		return true, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, fmt.Errorf("invalid URL %s: %w", rawURL, err)
+	}
+
+	robots, err := p.robots(u)
+	if err != nil {
+		return false, err
+	}
+
+	// TestAgent also honours the blanket disallow of a robots.txt failing with 5xx
+	return robots.TestAgent(u.EscapedPath(), p.userAgent), nil
+}
+
+// CrawlDelay returns the Crawl-delay robots.txt asks of us for the host of rawURL
+func (p *RobotsPolicy) CrawlDelay(rawURL string) time.Duration {
+	if !p.respect {
+		return 0
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+
+	robots, err := p.robots(u)
+	if err != nil {
+		return 0
+	}
+
+	return robots.FindGroup(p.userAgent).CrawlDelay
+}
+
+// robots returns the cached robots.txt of u's host, fetching it on first
+// use. Fetch failures are cached too so an unreachable host is not retried.
+// Only callers for the same host wait for a fetch; other hosts go on.
+func (p *RobotsPolicy) robots(u *url.URL) (*robotstxt.RobotsData, error) {
+	p.mu.Lock()
+	entry, ok := p.hosts[u.Host]
+	if !ok {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		p.hosts[u.Host] = entry
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		entry.robots, entry.err = p.fetch(u)
+		close(entry.ready)
+	}
+	<-entry.ready
+	return entry.robots, entry.err
+}
+
+// fetch downloads and parses robots.txt for u's host
+func (p *RobotsPolicy) fetch(u *url.URL) (*robotstxt.RobotsData, error) {
+	req, err := http.NewRequest(http.MethodGet, u.Scheme+"://"+u.Host+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", p.userAgent)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt for %s: %w", u.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read robots.txt for %s: %w", u.Host, err)
+	}
+
+	// 4xx means no restrictions, 5xx means disallow everything
+	robots, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse robots.txt for %s: %w", u.Host, err)
+	}
+
+	return robots, nil
+}
@@ -0,0 +1,112 @@
+package crawl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRobotsPolicy(t *testing.T) {
+	var fetches atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fetches.Add(1)
+		w.Write([]byte("User-agent: *\nDisallow: /private/\nCrawl-delay: 2\n"))
+	}))
+	defer server.Close()
+
+	policy := NewRobotsPolicy("test-bot", true)
+	for path, want := range map[string]bool{"/": true, "/docs/a.html": true, "/private/notes.html": false} {
+		allowed, err := policy.Allowed(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != want {
+			t.Errorf("Allowed(%s) = %v, want %v", path, allowed, want)
+		}
+	}
+	if delay := policy.CrawlDelay(server.URL + "/"); delay != 2*time.Second {
+		t.Errorf("crawl delay %v, want 2s", delay)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched robots.txt %d times, want once per host", n)
+	}
+
+	ignoring := NewRobotsPolicy("test-bot", false)
+	if allowed, err := ignoring.Allowed(server.URL + "/private/notes.html"); err != nil || !allowed {
+		t.Errorf("policy not respecting robots.txt disallowed a URL: %v", err)
+	}
+	if delay := ignoring.CrawlDelay(server.URL + "/"); delay != 0 {
+		t.Errorf("policy not respecting robots.txt applies a delay of %v", delay)
+	}
+}
+
+func TestRobotsPolicyFetchesHostsIndependently(t *testing.T) {
+	release := make(chan struct{})
+	var slowFetches atomic.Int64
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slowFetches.Add(1)
+		<-release
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer fast.Close()
+
+	policy := NewRobotsPolicy("test-bot", true)
+	results := make(chan bool, 3)
+	for range 3 {
+		go func() {
+			allowed, err := policy.Allowed(slow.URL + "/private/a.html")
+			results <- err == nil && !allowed
+		}()
+	}
+
+	// Another host is answered while the slow one is still fetched
+	done := make(chan error, 1)
+	go func() {
+		_, err := policy.Allowed(fast.URL + "/page")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow robots.txt blocked another host")
+	}
+
+	close(release)
+	for range 3 {
+		if ok := <-results; !ok {
+			t.Error("a caller waiting for the slow host got the wrong answer")
+		}
+	}
+	if n := slowFetches.Load(); n != 1 {
+		t.Errorf("fetched the slow robots.txt %d times, want once", n)
+	}
+}
+
+func TestRobotsPolicyStatus(t *testing.T) {
+	for status, want := range map[int]bool{http.StatusNotFound: true, http.StatusInternalServerError: false} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		allowed, err := NewRobotsPolicy("test-bot", true).Allowed(server.URL + "/page")
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != want {
+			t.Errorf("robots.txt answering %d: allowed %v, want %v", status, allowed, want)
+		}
+	}
+}
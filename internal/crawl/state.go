@@ -0,0 +1,220 @@
+package crawl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"ollama_go/internal/models"
+)
+
+// Target is what a crawl covers. It is saved with the state so a resumed
+// crawl follows the same links into the same collection.
+type Target struct {
+	Seeds      []string `json:"seeds"`
+	Scope      string   `json:"scope"` // links must contain it; "" allows anything on the seed hosts
+	Collection string   `json:"collection"`
+}
+
+// State is the resumable progress of a crawl: the frontier of URLs queued
+// but not finished, the set of visited URLs and pages that were fetched but
+// not yet embedded. It is persisted as JSON so an interrupted crawl can be
+// resumed with `crawl -resume`.
+type State struct {
+	mu        sync.Mutex
+	saveMu    sync.Mutex // serializes writers of the state file
+	path      string
+	target    Target
+	frontier  map[string]bool
+	visited   map[string]bool
+	pending   map[string]*models.PageContent
+	pageCount int
+}
+
+// stateFile is the on-disk representation of State
+type stateFile struct {
+	Target    *Target               `json:"target,omitempty"` // nil in states saved before it was recorded
+	Frontier  []string              `json:"frontier"`
+	Visited   []string              `json:"visited"`
+	Pending   []*models.PageContent `json:"pending"`
+	PageCount int                   `json:"page_count"`
+}
+
+// NewState creates an empty crawl state persisted at path
+func NewState(path string) *State {
+	return &State{
+		path:     path,
+		frontier: make(map[string]bool),
+		visited:  make(map[string]bool),
+		pending:  make(map[string]*models.PageContent),
+	}
+}
+
+// LoadState reads a crawl state previously saved at path
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read crawl state: %w", err)
+	}
+
+	var file stateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal crawl state: %w", err)
+	}
+
+	s := NewState(path)
+	if file.Target != nil {
+		s.target = *file.Target
+	}
+	for _, u := range file.Frontier {
+		s.frontier[u] = true
+	}
+	for _, u := range file.Visited {
+		s.visited[u] = true
+	}
+	for _, page := range file.Pending {
+		s.pending[page.URL] = page
+	}
+	s.pageCount = file.PageCount
+
+	return s, nil
+}
+
+// SetTarget records the seeds, scope and collection of the crawl
+func (s *State) SetTarget(t Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.target = t
+}
+
+// Target returns the seeds, scope and collection of the crawl; its Seeds are
+// empty if the state was saved without them
+func (s *State) Target() Target {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.target
+}
+
+// Enqueue adds a URL to the frontier
+func (s *State) Enqueue(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frontier[url] = true
+}
+
+// Finish removes a URL from the frontier once it has been handled either way
+func (s *State) Finish(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.frontier, url)
+}
+
+// Visited reports whether a URL was already fetched in this or a previous run
+func (s *State) Visited(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.visited[url]
+}
+
+// AddPending records a fetched page that still needs embedding and removes
+// it from the frontier
+func (s *State) AddPending(page *models.PageContent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.frontier, page.URL)
+	s.visited[page.URL] = true
+	s.pending[page.URL] = page
+	s.pageCount++
+}
+
+// Embedded removes a page from the pending set once it is in the store
+func (s *State) Embedded(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, url)
+}
+
+// Frontier returns the queued URLs in a stable order
+func (s *State) Frontier() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.frontier)
+}
+
+// Pending returns the fetched-but-not-embedded pages
+func (s *State) Pending() []*models.PageContent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pages := make([]*models.PageContent, 0, len(s.pending))
+	for _, url := range sortedKeys(s.pending) {
+		pages = append(pages, s.pending[url])
+	}
+	return pages
+}
+
+// PageCount returns the number of pages fetched across all runs
+func (s *State) PageCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pageCount
+}
+
+// Save writes the state to disk atomically (write to a temp file, then rename)
+func (s *State) Save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	target := s.target
+	file := stateFile{
+		Target:    &target,
+		Frontier:  sortedKeys(s.frontier),
+		Visited:   sortedKeys(s.visited),
+		Pending:   make([]*models.PageContent, 0, len(s.pending)),
+		PageCount: s.pageCount,
+	}
+	for _, url := range sortedKeys(s.pending) {
+		file.Pending = append(file.Pending, s.pending[url])
+	}
+	s.mu.Unlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal crawl state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write crawl state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace crawl state: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes the state file after a crawl completes
+func (s *State) Remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove crawl state: %w", err)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,63 @@
+package crawl
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"ollama_go/internal/models"
+)
+
+func TestStateResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "crawl.json")
+	s := NewState(path)
+	target := Target{Seeds: []string{"https://go.dev/doc/"}, Scope: "go.dev/doc", Collection: "go-docs"}
+	s.SetTarget(target)
+	s.Enqueue("https://go.dev/a")
+	s.Enqueue("https://go.dev/b")
+	s.Enqueue("https://go.dev/c")
+	s.AddPending(&models.PageContent{URL: "https://go.dev/a", Title: "A", MainContent: []string{"a"}})
+	s.AddPending(&models.PageContent{URL: "https://go.dev/b", Title: "B", MainContent: []string{"b"}})
+	s.Embedded("https://go.dev/a")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An interrupted crawl resumes with what was queued and not embedded
+	resumed, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resumed.Target(); !slices.Equal(got.Seeds, target.Seeds) || got.Scope != target.Scope || got.Collection != target.Collection {
+		t.Errorf("resumed target %+v, want %+v", got, target)
+	}
+	if got := resumed.Frontier(); !slices.Equal(got, []string{"https://go.dev/c"}) {
+		t.Errorf("frontier %v, want c", got)
+	}
+	pending := resumed.Pending()
+	if len(pending) != 1 || pending[0].URL != "https://go.dev/b" || pending[0].Title != "B" {
+		t.Errorf("pending %v, want page b", pending)
+	}
+	for url, want := range map[string]bool{"https://go.dev/a": true, "https://go.dev/b": true, "https://go.dev/c": false} {
+		if resumed.Visited(url) != want {
+			t.Errorf("Visited(%s) = %v, want %v", url, !want, want)
+		}
+	}
+	if n := resumed.PageCount(); n != 2 {
+		t.Errorf("page count %d, want 2", n)
+	}
+
+	resumed.Finish("https://go.dev/c")
+	if got := resumed.Frontier(); len(got) != 0 {
+		t.Errorf("frontier after finishing every URL: %v", got)
+	}
+	if err := resumed.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadState(path); err == nil {
+		t.Error("loaded a removed crawl state")
+	}
+	if err := resumed.Remove(); err != nil {
+		t.Errorf("removing a missing state: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"ollama_go/internal/resilience"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	h := NewHash(64)
+	ctx := context.Background()
+
+	a, err := h.Embed(ctx, "Goroutines communicate over channels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := h.Embed(ctx, "goroutines communicate over channels")
+	related, _ := h.Embed(ctx, "channels connect goroutines")
+	unrelated, _ := h.Embed(ctx, "maps are hash tables")
+	if len(a) != 64 || h.Dimension() != 64 || h.ModelID() != "hash/fnv-64" {
+		t.Fatalf("embedding of size %d, dimension %d, model %s", len(a), h.Dimension(), h.ModelID())
+	}
+	if !slices.Equal(a, again) {
+		t.Error("the same words embedded differently")
+	}
+	if dot(a, related) <= dot(a, unrelated) {
+		t.Error("texts sharing words are not more similar than unrelated ones")
+	}
+	if _, err := h.Embed(ctx, ""); err == nil {
+		t.Error("embedded an empty text")
+	}
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Input[0] == "overloaded" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": {"message": "server busy"}}`))
+			return
+		}
+
+		// Answer in reverse order; the index says which input each is for
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		data := make([]item, 0, len(req.Input))
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, item{Index: i, Embedding: []float32{float32(i), 1, 0}})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	o, err := NewOpenAI(server.URL+"/v1/", "nomic-embed-text", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	embs, err := o.EmbedBatch(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, emb := range embs {
+		if emb[0] != float32(i) {
+			t.Errorf("embedding %d belongs to input %v", i, emb[0])
+		}
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("sent Authorization %q", auth)
+	}
+	if o.Dimension() != 3 || o.ModelID() != "openai/nomic-embed-text" {
+		t.Errorf("dimension %d, model %s", o.Dimension(), o.ModelID())
+	}
+
+	_, err = o.Embed(context.Background(), "overloaded")
+	if !resilience.Retryable(err) {
+		t.Errorf("a 503 answer gave %v, want a retryable error", err)
+	}
+
+	if _, err := NewOpenAI(server.URL, "", ""); err == nil {
+		t.Error("created an OpenAI embedder without a model")
+	}
+}
+
+func TestOllamaEmbedder(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+			Input string `json:"input"`
+		}
+		if r.URL.Path != "/api/embed" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.NotFound(w, r)
+			return
+		}
+		models = append(models, req.Model)
+		embedding := []float32{float32(len(req.Input)), 0.5}
+		json.NewEncoder(w).Encode(map[string]any{"model": req.Model, "embeddings": [][]float32{embedding}})
+	}))
+	defer server.Close()
+	t.Setenv("OLLAMA_HOST", server.URL)
+
+	o, err := NewOllama("nomic-embed-text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	embs, err := o.EmbedBatch(context.Background(), []string{"a", "bb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embs) != 2 || embs[0][0] != 1 || embs[1][0] != 2 {
+		t.Errorf("embeddings %v, want one per input in order", embs)
+	}
+	if !slices.Equal(models, []string{"nomic-embed-text", "nomic-embed-text"}) {
+		t.Errorf("requested models %v", models)
+	}
+	if o.Dimension() != 2 || o.ModelID() != "ollama/nomic-embed-text" {
+		t.Errorf("dimension %d, model %s", o.Dimension(), o.ModelID())
+	}
+}
+
+func TestNewSelectsBackend(t *testing.T) {
+	tests := []struct {
+		cfg   Config
+		model string
+	}{
+		{Config{Provider: ProviderHash, Dimension: 32}, "hash/fnv-32"},
+		{Config{Provider: ProviderOllama, Model: "llama3:latest"}, "ollama/llama3:latest"},
+		{Config{Provider: ProviderOpenAI, Model: "text-embedding-3-small"}, "openai/text-embedding-3-small"},
+	}
+	for _, tt := range tests {
+		embedder, err := New(tt.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := embedder.ModelID(); got != tt.model {
+			t.Errorf("provider %s created %s, want %s", tt.cfg.Provider, got, tt.model)
+		}
+	}
+	if _, err := New(Config{Provider: "word2vec"}); err == nil {
+		t.Error("created an embedder of an unknown provider")
+	}
+
+	// ModelID round-trips through WithModelID
+	cfg, err := Config{Provider: ProviderOllama, Model: "llama3:latest"}.WithModelID("hash/fnv-128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embedder, err := New(cfg); err != nil || embedder.ModelID() != "hash/fnv-128" {
+		t.Errorf("WithModelID(hash/fnv-128) created %v, %v", embedder, err)
+	}
+}
+
+func TestOpenAIRejectsIncompleteResponses(t *testing.T) {
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	o, err := NewOpenAI(server.URL, "nomic-embed-text", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{
+		"duplicate index":  `{"data": [{"index": 0, "embedding": [1, 0]}, {"index": 0, "embedding": [0, 1]}]}`,
+		"missing index":    `{"data": [{"index": 1, "embedding": [1, 0]}, {"index": 1, "embedding": [0, 1]}]}`,
+		"out of range":     `{"data": [{"index": 0, "embedding": [1, 0]}, {"index": 2, "embedding": [0, 1]}]}`,
+		"empty embedding":  `{"data": [{"index": 0, "embedding": [1, 0]}, {"index": 1, "embedding": []}]}`,
+		"mixed dimensions": `{"data": [{"index": 0, "embedding": [1, 0]}, {"index": 1, "embedding": [0, 1, 0]}]}`,
+		"too few":          `{"data": [{"index": 0, "embedding": [1, 0]}]}`,
+	} {
+		response = body
+		if embs, err := o.EmbedBatch(context.Background(), []string{"a", "b"}); err == nil {
+			t.Errorf("%s: accepted %v", name, embs)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package embedding
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCacheSize is the number of embeddings kept when no limit is
+// configured, about 160 MB of 4096-dimensional vectors
+const DefaultCacheSize = 10000
+
+// autoSaveEvery is the number of new embeddings after which the cache saves
+// itself, so a crash loses at most that many
+const autoSaveEvery = 1000
+
+// cacheKey identifies an embedding by model and normalized text
+type cacheKey struct {
+	Model string
+	Hash  [sha256.Size]byte
+}
+
+// cacheEntry is one cached embedding; entries are persisted oldest first so
+// loading the file restores the LRU order
+type cacheEntry struct {
+	Key    cacheKey
+	Vector []float32
+}
+
+// CacheStats is a snapshot of the cache counters
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Entries   int
+}
+
+// HitRate returns the fraction of lookups served from the cache
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Cache is a disk-backed LRU cache of embeddings keyed by (model ID,
+// SHA-256 of the normalized text). It is stored with encoding/gob rather than
+// JSON because it holds nothing but float vectors. Saving merges the entries
+// other processes saved meanwhile, so concurrent runs share one file. The file
+// also counts the purges of each model, so a purge in one process makes the
+// others drop their entries of the model instead of saving them back.
+type Cache struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int
+	order   *list.List // front = most recently used
+	entries map[cacheKey]*list.Element
+	dirty   bool
+	unsaved int             // entries added since the last save
+	purged  map[string]bool // models purged since the last save, not merged back
+
+	// generations counts the purges of each model as of the last load or
+	// save, plus those made here since
+	generations map[string]uint64
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+// NewCache creates an empty cache persisted at path holding at most maxSize
+// embeddings (DefaultCacheSize if maxSize <= 0)
+func NewCache(path string, maxSize int) *Cache {
+	if maxSize <= 0 {
+		maxSize = DefaultCacheSize
+	}
+	return &Cache{
+		path:    path,
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[cacheKey]*list.Element),
+		purged:  make(map[string]bool),
+
+		generations: make(map[string]uint64),
+	}
+}
+
+// LoadCache opens the cache at path; a missing file yields an empty cache
+func LoadCache(path string, maxSize int) (*Cache, error) {
+	c := NewCache(path, maxSize)
+
+	stored, generations, err := readCacheFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range stored {
+		c.put(e.Key, e.Vector)
+	}
+	if generations != nil {
+		c.generations = generations
+	}
+	c.evictions.Store(0)
+	c.dirty, c.unsaved = false, 0
+
+	return c, nil
+}
+
+// readCacheFile reads the entries saved at path, oldest first, and the purge
+// generations of their models; a missing file holds none. The generations
+// follow the entries, so files written before they existed still load.
+func readCacheFile(path string) ([]cacheEntry, map[string]uint64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open embedding cache: %w", err)
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+	var stored []cacheEntry
+	if err := dec.Decode(&stored); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode embedding cache: %w", err)
+	}
+	var generations map[string]uint64
+	if err := dec.Decode(&generations); err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("failed to decode embedding cache purges: %w", err)
+	}
+	return stored, generations, nil
+}
+
+// normalizeText collapses whitespace so formatting-only differences share an entry
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func newCacheKey(model, text string) cacheKey {
+	return cacheKey{Model: model, Hash: sha256.Sum256([]byte(normalizeText(text)))}
+}
+
+// Get returns the cached embedding of text for model
+func (c *Cache) Get(model, text string) ([]float32, bool) {
+	key := newCacheKey(model, text)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	c.hits.Add(1)
+	return elem.Value.(*cacheEntry).Vector, true
+}
+
+// Put stores the embedding of text for model, evicting the least recently
+// used entries beyond the size limit
+func (c *Cache) Put(model, text string, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(newCacheKey(model, text), vector)
+}
+
+func (c *Cache) put(key cacheKey, vector []float32) {
+	c.dirty = true
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).Vector = vector
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{Key: key, Vector: vector})
+	c.unsaved++
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).Key)
+		c.evictions.Add(1)
+	}
+}
+
+// Purge removes every entry of the given model ID and returns how many were
+// removed. Saving records the purge, so other processes drop their entries
+// of the model too.
+func (c *Cache) Purge(model string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := c.removeModel(model)
+	c.purged[model] = true
+	c.generations[model]++
+	c.dirty = true
+	return removed
+}
+
+func (c *Cache) removeModel(model string) int {
+	removed := 0
+	for key, elem := range c.entries {
+		if key.Model == model {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Models returns the number of cached entries per model ID, sorted by model
+func (c *Cache) Models() ([]string, map[string]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int)
+	for key := range c.entries {
+		counts[key.Model]++
+	}
+	models := make([]string, 0, len(counts))
+	for model := range counts {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models, counts
+}
+
+// Stats returns the hit/miss counters since the cache was opened
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// Save writes the cache to disk atomically if it changed since the last
+// save. Entries another process saved to the file meanwhile are kept as the
+// least recently used ones, unless their model was purged here; a model
+// another process purged meanwhile loses the entries held here.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	dirty := c.dirty
+	c.mu.Unlock()
+	if !dirty {
+		return nil
+	}
+
+	// A damaged file is replaced rather than merged
+	onDisk, onDiskGenerations, _ := readCacheFile(c.path)
+
+	c.mu.Lock()
+	for model, generation := range onDiskGenerations {
+		if generation > c.generations[model] {
+			c.removeModel(model)
+			c.generations[model] = generation
+		}
+	}
+	for i := len(onDisk) - 1; i >= 0 && c.order.Len() < c.maxSize; i-- {
+		e := onDisk[i]
+		if _, ok := c.entries[e.Key]; ok || c.purged[e.Key.Model] {
+			continue
+		}
+		c.entries[e.Key] = c.order.PushBack(&cacheEntry{Key: e.Key, Vector: e.Vector})
+	}
+	stored := make([]cacheEntry, 0, c.order.Len())
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		stored = append(stored, *elem.Value.(*cacheEntry))
+	}
+	generations := maps.Clone(c.generations)
+	clear(c.purged)
+	c.dirty, c.unsaved = false, 0
+	c.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// A temporary file of its own, since another process may be saving too
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create embedding cache: %w", err)
+	}
+	defer os.Remove(f.Name())
+	enc := gob.NewEncoder(f)
+	if err := enc.Encode(stored); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode embedding cache: %w", err)
+	}
+	if err := enc.Encode(generations); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode embedding cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+	if err := os.Rename(f.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to replace embedding cache: %w", err)
+	}
+
+	return nil
+}
+
+// saveIfDue saves the cache once autoSaveEvery entries were added since the
+// last save. A failure is only logged: the embeddings are still returned.
+func (c *Cache) saveIfDue() {
+	c.mu.Lock()
+	due := c.unsaved >= autoSaveEvery
+	c.mu.Unlock()
+	if !due {
+		return
+	}
+	if err := c.Save(); err != nil {
+		log.Printf("⚠️  Failed to save the embedding cache: %v\n", err)
+	}
+}
+
+// Cached is an Embedder that serves repeated texts from a Cache and only
+// sends misses to the wrapped embedder
+type Cached struct {
+	Embedder
+	cache *Cache
+}
+
+// NewCached wraps embedder with cache
+func NewCached(embedder Embedder, cache *Cache) *Cached {
+	return &Cached{Embedder: embedder, cache: cache}
+}
+
+// Cache returns the underlying cache
+func (c *Cached) Cache() *Cache {
+	return c.cache
+}
+
+// Embed creates an embedding for a single text, using the cache when possible
+func (c *Cached) Embed(ctx context.Context, text string) ([]float32, error) {
+	if vector, ok := c.cache.Get(c.ModelID(), text); ok {
+		return vector, nil
+	}
+	vector, err := c.Embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Put(c.ModelID(), text, vector)
+	c.cache.saveIfDue()
+	return vector, nil
+}
+
+// EmbedBatch creates embeddings for multiple texts, sending only the cache
+// misses to the wrapped embedder in a single batch
+func (c *Cached) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	model := c.ModelID()
+	vectors := make([][]float32, len(texts))
+	missing := make([]int, 0, len(texts))
+	for i, text := range texts {
+		if vector, ok := c.cache.Get(model, text); ok {
+			vectors[i] = vector
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	missTexts := make([]string, len(missing))
+	for j, i := range missing {
+		missTexts[j] = texts[i]
+	}
+	embs, err := c.Embedder.EmbedBatch(ctx, missTexts)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missing {
+		vectors[i] = embs[j]
+		c.cache.Put(model, texts[i], embs[j])
+	}
+	c.cache.saveIfDue()
+	return vectors, nil
+}
+
+// Close saves the embedding cache of e, if it has one, and prints its statistics
+func Close(e Embedder) error {
+	c, ok := e.(*Cached)
+	if !ok {
+		return nil
+	}
+	return closeCache(c.cache)
+}
+
+// closeCache prints the statistics of cache and saves it
+func closeCache(cache *Cache) error {
+	stats := cache.Stats()
+	if stats.Hits+stats.Misses > 0 {
+		fmt.Printf("🗄️  Embedding cache: %d hits, %d misses (%.0f%% hit rate), %d entries\n",
+			stats.Hits, stats.Misses, stats.HitRate()*100, stats.Entries)
+	}
+	return cache.Save()
+}
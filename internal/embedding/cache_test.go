@@ -0,0 +1,218 @@
+package embedding
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(filepath.Join(t.TempDir(), "cache.gob"), 2)
+	c.Put("m", "a", []float32{1})
+	c.Put("m", "b", []float32{2})
+	if _, ok := c.Get("m", "a"); !ok {
+		t.Fatal("a missing")
+	}
+	c.Put("m", "c", []float32{3}) // evicts b, the least recently used
+
+	if _, ok := c.Get("m", "b"); ok {
+		t.Error("b was kept over the more recently used a")
+	}
+	for _, text := range []string{"a", "c"} {
+		if _, ok := c.Get("m", text); !ok {
+			t.Errorf("%s was evicted", text)
+		}
+	}
+	if stats := c.Stats(); stats.Evictions != 1 || stats.Entries != 2 || stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// Whitespace differences share an entry, models do not
+	if v, ok := c.Get("m", "  a\n"); !ok || v[0] != 1 {
+		t.Error("normalized text missed the cache")
+	}
+	if _, ok := c.Get("other", "a"); ok {
+		t.Error("entry served for another model")
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	c := NewCache(path, 10)
+	c.Put("old", "a", []float32{1})
+	c.Put("old", "b", []float32{2})
+	c.Put("new", "a", []float32{3})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := c.Purge("old"); n != 2 {
+		t.Errorf("purged %d entries, want 2", n)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, counts := loaded.Models()
+	if len(models) != 1 || models[0] != "new" || counts["new"] != 1 {
+		t.Errorf("after purging: %v %v, want only new", models, counts)
+	}
+}
+
+func TestCachePersistsLRUOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	c := NewCache(path, 3)
+	for i, text := range []string{"a", "b", "c"} {
+		c.Put("m", text, []float32{float32(i)})
+	}
+	c.Get("m", "a")
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadCache(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := loaded.Get("m", "c"); !ok || v[0] != 2 {
+		t.Fatalf("c = %v, %v after loading", v, ok)
+	}
+	loaded.Put("m", "d", []float32{3}) // b is now the least recently used
+	if _, ok := loaded.Get("m", "b"); ok {
+		t.Error("loading lost the LRU order")
+	}
+}
+
+func TestCacheSaveMergesOtherProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	first, err := LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Put("m", "a", []float32{1})
+	second.Put("m", "b", []float32{2})
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"a", "b"} {
+		if _, ok := loaded.Get("m", text); !ok {
+			t.Errorf("%s lost when both processes saved", text)
+		}
+	}
+}
+
+func TestCachePurgeReachesOtherProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	crawl, err := LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crawl.Put("old", "a", []float32{1})
+	if err := crawl.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// cache purge runs while the crawl keeps its entries in memory
+	purge, err := LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	purge.Purge("old")
+	if err := purge.Save(); err != nil {
+		t.Fatal(err)
+	}
+	crawl.Put("new", "b", []float32{2})
+	if err := crawl.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := crawl.Get("old", "a"); ok {
+		t.Error("the crawl still serves a purged entry")
+	}
+
+	loaded, err := LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if models, _ := loaded.Models(); len(models) != 1 || models[0] != "new" {
+		t.Errorf("after the crawl saved: %v, want only new", models)
+	}
+
+	// Entries saved after the purge are kept by processes that saw it
+	crawl.Put("old", "c", []float32{3})
+	if err := crawl.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := purge.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Get("old", "c"); !ok {
+		t.Error("an entry embedded after the purge was dropped")
+	}
+}
+
+func TestLoadCacheWithoutPurges(t *testing.T) {
+	// Files written before purges were recorded hold only the entries
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []cacheEntry{{Key: newCacheKey("m", "a"), Vector: []float32{1}}}
+	if err := gob.NewEncoder(f).Encode(entries); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	c, err := LoadCache(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := c.Get("m", "a"); !ok || v[0] != 1 {
+		t.Errorf("a = %v, %v from a file without purges", v, ok)
+	}
+}
+
+func TestCachedSavesPeriodically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	cache := NewCache(path, 2*autoSaveEvery)
+	embedder := NewCached(NewHash(4), cache)
+
+	texts := make([]string, autoSaveEvery)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+	if _, err := embedder.EmbedBatch(context.Background(), texts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saved without Close, as a crash would leave it
+	loaded, err := LoadCache(path, 2*autoSaveEvery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := loaded.Stats().Entries; n != autoSaveEvery {
+		t.Errorf("file holds %d entries, want %d", n, autoSaveEvery)
+	}
+}
@@ -0,0 +1,99 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"ollama_go/internal/resilience"
+)
+
+// Hash is a deterministic feature-hashing embedder. Each lowercased word and
+// word bigram is hashed into one of Dimension buckets with a hash-derived
+// sign, and the result is L2-normalized. It needs no model server, so it
+// suits tests and offline smoke runs; texts sharing words get similar vectors.
+type Hash struct {
+	dimension int
+}
+
+// NewHash creates a hashing embedder producing vectors of the given size (default 256)
+func NewHash(dimension int) *Hash {
+	if dimension <= 0 {
+		dimension = 256
+	}
+	return &Hash{dimension: dimension}
+}
+
+// Embed creates an embedding for a single text
+func (h *Hash) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, resilience.Invalid(errors.New("text cannot be empty"))
+	}
+
+	vec := make([]float32, h.dimension)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, word := range words {
+		h.add(vec, word)
+		if i > 0 {
+			h.add(vec, words[i-1]+" "+word)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+
+	return vec, nil
+}
+
+// add hashes a feature into the vector
+func (h *Hash) add(vec []float32, feature string) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(feature))
+	sum := hasher.Sum64()
+
+	sign := float32(1)
+	if sum>>63 == 1 {
+		sign = -1
+	}
+	vec[sum%uint64(h.dimension)] += sign
+}
+
+// EmbedBatch creates embeddings for multiple texts
+func (h *Hash) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, resilience.Invalid(errors.New("texts cannot be empty"))
+	}
+	embs := make([][]float32, len(texts))
+	for i, text := range texts {
+		emb, err := h.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		embs[i] = emb
+	}
+	return embs, nil
+}
+
+// Dimension returns the configured vector size
+func (h *Hash) Dimension() int {
+	return h.dimension
+}
+
+// ModelID identifies the hashing scheme and its size
+func (h *Hash) ModelID() string {
+	return ProviderHash + "/fnv-" + strconv.Itoa(h.dimension)
+}
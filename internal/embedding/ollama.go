@@ -0,0 +1,69 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"ollama_go/internal/resilience"
+
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+// Ollama generates embeddings with a local Ollama server
+type Ollama struct {
+	llm       *ollama.LLM
+	model     string
+	dimension atomic.Int64
+}
+
+// NewOllama creates an embedder for the given Ollama model
+func NewOllama(modelName string) (*Ollama, error) {
+	llm, err := ollama.New(ollama.WithModel(modelName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Ollama LLM: %w", err)
+	}
+	return &Ollama{
+		llm:   llm,
+		model: modelName,
+	}, nil
+}
+
+// Embed creates an embedding for a single text
+func (o *Ollama) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, resilience.Invalid(errors.New("text cannot be empty"))
+	}
+	embs, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embs[0], nil
+}
+
+// EmbedBatch creates embeddings for multiple texts
+func (o *Ollama) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, resilience.Invalid(errors.New("texts cannot be empty"))
+	}
+	embs, err := o.llm.CreateEmbedding(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings: %w", err)
+	}
+	if len(embs) != len(texts) {
+		return nil, resilience.Invalid(fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embs)))
+	}
+	o.dimension.Store(int64(len(embs[0])))
+	return embs, nil
+}
+
+// Dimension returns the vector size observed on the last call
+func (o *Ollama) Dimension() int {
+	return int(o.dimension.Load())
+}
+
+// ModelID identifies the Ollama model
+func (o *Ollama) ModelID() string {
+	return ProviderOllama + "/" + o.model
+}
@@ -0,0 +1,155 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"ollama_go/internal/resilience"
+)
+
+// OpenAI generates embeddings with any server implementing the OpenAI
+// /v1/embeddings API (OpenAI itself, llama.cpp server, vLLM, LocalAI, ...)
+type OpenAI struct {
+	client    *http.Client
+	baseURL   string
+	model     string
+	apiKey    string
+	dimension atomic.Int64
+}
+
+// NewOpenAI creates an embedder for an OpenAI-compatible endpoint. baseURL
+// defaults to https://api.openai.com/v1 and should include the /v1 prefix.
+func NewOpenAI(baseURL, model, apiKey string) (*OpenAI, error) {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		return nil, fmt.Errorf("an embedding model is required for the openai provider")
+	}
+	return &OpenAI{
+		client:  &http.Client{Timeout: 2 * time.Minute},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		apiKey:  apiKey,
+	}, nil
+}
+
+type openAIRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Embed creates an embedding for a single text
+func (o *OpenAI) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, resilience.Invalid(errors.New("text cannot be empty"))
+	}
+	embs, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embs[0], nil
+}
+
+// EmbedBatch creates embeddings for multiple texts in a single request
+func (o *OpenAI) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, resilience.Invalid(errors.New("texts cannot be empty"))
+	}
+
+	body, err := json.Marshal(openAIRequest{Model: o.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var parsed openAIResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			// Proxies and overloaded servers often answer with plain text
+			return nil, fmt.Errorf("embedding request failed: %w",
+				&resilience.StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))})
+		}
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &resilience.StatusError{StatusCode: resp.StatusCode}
+		if parsed.Error != nil {
+			statusErr.Message = parsed.Error.Message
+		}
+		return nil, fmt.Errorf("embedding request failed: %w", statusErr)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, resilience.Invalid(fmt.Errorf("expected %d embeddings, got %d", len(texts), len(parsed.Data)))
+	}
+
+	// Results carry their input index and are not guaranteed to be ordered.
+	// With as many results as inputs, rejecting duplicates and empty vectors
+	// ensures every input got an embedding.
+	embs := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(embs) {
+			return nil, resilience.Invalid(fmt.Errorf("embedding index %d out of range", d.Index))
+		}
+		if embs[d.Index] != nil {
+			return nil, resilience.Invalid(fmt.Errorf("duplicate embedding for index %d", d.Index))
+		}
+		if len(d.Embedding) == 0 {
+			return nil, resilience.Invalid(fmt.Errorf("empty embedding for index %d", d.Index))
+		}
+		if len(d.Embedding) != len(parsed.Data[0].Embedding) {
+			return nil, resilience.Invalid(fmt.Errorf("embedding %d has dimension %d, want %d", d.Index, len(d.Embedding), len(parsed.Data[0].Embedding)))
+		}
+		embs[d.Index] = d.Embedding
+	}
+
+	o.dimension.Store(int64(len(embs[0])))
+	return embs, nil
+}
+
+// Dimension returns the vector size observed on the last call
+func (o *OpenAI) Dimension() int {
+	return int(o.dimension.Load())
+}
+
+// ModelID identifies the endpoint's model
+func (o *OpenAI) ModelID() string {
+	return ProviderOpenAI + "/" + o.model
+}
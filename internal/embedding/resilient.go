@@ -0,0 +1,43 @@
+package embedding
+
+import (
+	"context"
+
+	"ollama_go/internal/resilience"
+)
+
+// Resilient is an Embedder that applies timeouts, retries and a circuit
+// breaker to a remote embedder. Errors are *resilience.Error values, so
+// callers can tell a missing model (resilience.ErrModelNotFound) from a
+// server that is down (resilience.ErrUnreachable, resilience.ErrCircuitOpen).
+type Resilient struct {
+	Embedder
+	client *resilience.Client
+}
+
+// NewResilient wraps embedder with the given policy
+func NewResilient(embedder Embedder, policy resilience.Policy) *Resilient {
+	return &Resilient{Embedder: embedder, client: resilience.NewClient(policy)}
+}
+
+// Embed creates an embedding for a single text
+func (r *Resilient) Embed(ctx context.Context, text string) ([]float32, error) {
+	var vector []float32
+	err := r.client.Do(ctx, "embed", func(ctx context.Context) error {
+		var err error
+		vector, err = r.Embedder.Embed(ctx, text)
+		return err
+	})
+	return vector, err
+}
+
+// EmbedBatch creates embeddings for multiple texts
+func (r *Resilient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := r.client.Do(ctx, "embed", func(ctx context.Context) error {
+		var err error
+		vectors, err = r.Embedder.EmbedBatch(ctx, texts)
+		return err
+	})
+	return vectors, err
+}
@@ -3,51 +3,163 @@ package embedding
 import (
 	"context"
 	"fmt"
-	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/tmc/langchaingo/llms/ollama"
+	"ollama_go/internal/resilience"
 )
 
-// Service handles embedding generation
-type Service struct {
-	llm *ollama.LLM
+// Embedder turns text into vectors. Implementations must be safe for
+// concurrent use.
+type Embedder interface {
+	// Embed creates an embedding for a single text
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// EmbedBatch creates embeddings for multiple texts, in order
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	// Dimension returns the vector size, or 0 if not known until the first call
+	Dimension() int
+	// ModelID identifies the backend and model, e.g. "ollama/llama3:latest"
+	ModelID() string
 }
 
-// NewService creates a new embedding service
-func NewService(modelName string) (*Service, error) {
-	llm, err := ollama.New(ollama.WithModel(modelName))
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize Ollama LLM: %w", err)
+// Supported embedding providers
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai"
+	ProviderHash   = "hash"
+)
+
+// Config selects and configures an embedding backend
+type Config struct {
+	Provider  string // ollama, openai or hash
+	Model     string
+	BaseURL   string // OpenAI-compatible endpoint, e.g. http://localhost:8080/v1
+	APIKey    string
+	Dimension int    // hash embedder only
+	CachePath string // embedding cache file; empty disables the cache
+	CacheSize int    // maximum cached embeddings
+
+	// Retry policy of remote backends (timeouts, retries, circuit breaker)
+	Policy resilience.Policy
+}
+
+// DefaultCachePath is where the embedding cache is stored unless configured
+const DefaultCachePath = "data/embedding_cache.gob"
+
+// ConfigFromEnv reads the embedding configuration from the environment:
+//
+//	RAG_EMBEDDER    ollama (default), openai or hash
+//	RAG_EMBED_MODEL model name (defaults to defaultModel for ollama)
+//	RAG_EMBED_URL   base URL of an OpenAI-compatible server
+//	RAG_EMBED_KEY   API key for the OpenAI-compatible server (or OPENAI_API_KEY)
+//	RAG_EMBED_DIM   vector size of the hash embedder
+//	RAG_EMBED_CACHE embedding cache file, or "off" to disable caching
+//	RAG_EMBED_CACHE_SIZE maximum number of cached embeddings
+//	RAG_EMBED_TIMEOUT    per-request timeout of remote backends, e.g. 30s
+//	RAG_EMBED_RETRIES    retries of transient failures
+func ConfigFromEnv(defaultModel string) Config {
+	cfg := Config{
+		Provider:  strings.ToLower(os.Getenv("RAG_EMBEDDER")),
+		Model:     os.Getenv("RAG_EMBED_MODEL"),
+		BaseURL:   os.Getenv("RAG_EMBED_URL"),
+		APIKey:    os.Getenv("RAG_EMBED_KEY"),
+		CachePath: os.Getenv("RAG_EMBED_CACHE"),
+		Policy:    resilience.DefaultPolicy(),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderOllama
+	}
+	if cfg.Model == "" && cfg.Provider == ProviderOllama {
+		cfg.Model = defaultModel
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if dim, err := strconv.Atoi(os.Getenv("RAG_EMBED_DIM")); err == nil {
+		cfg.Dimension = dim
+	}
+	switch strings.ToLower(cfg.CachePath) {
+	case "":
+		cfg.CachePath = DefaultCachePath
+	case "off", "none", "false", "0":
+		cfg.CachePath = ""
 	}
-	return &Service{
-		llm: llm,
-	}, nil
+	if size, err := strconv.Atoi(os.Getenv("RAG_EMBED_CACHE_SIZE")); err == nil {
+		cfg.CacheSize = size
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("RAG_EMBED_TIMEOUT")); err == nil {
+		cfg.Policy.Timeout = timeout
+	}
+	if retries, err := strconv.Atoi(os.Getenv("RAG_EMBED_RETRIES")); err == nil {
+		cfg.Policy.MaxRetries = retries
+	}
+	return cfg
 }
 
-// GenerateEmbedding creates an embedding for a single text
-func (s *Service) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	if text == "" {
-		return nil, fmt.Errorf("text cannot be empty")
+// New creates the embedder selected by cfg. Remote backends are wrapped with
+// cfg.Policy, and everything with the embedding cache when cfg.CachePath is
+// set. Call Close when done to persist the cache.
+func New(cfg Config) (Embedder, error) {
+	embedder, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CachePath == "" {
+		return embedder, nil
 	}
-	embs, err := s.llm.CreateEmbedding(ctx, []string{text})
+
+	cache, err := LoadCache(cfg.CachePath, cfg.CacheSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create embedding: %w", err)
+		return nil, err
 	}
-	if len(embs) == 0 {
-		return nil, fmt.Errorf("no embeddings returned")
+	return NewCached(embedder, cache), nil
+}
+
+// newBackend creates the uncached embedder selected by cfg
+func newBackend(cfg Config) (Embedder, error) {
+	switch cfg.Provider {
+	case ProviderOllama, "":
+		backend, err := NewOllama(cfg.Model)
+		if err != nil {
+			return nil, err
+		}
+		return NewResilient(backend, cfg.Policy), nil
+	case ProviderOpenAI:
+		backend, err := NewOpenAI(cfg.BaseURL, cfg.Model, cfg.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewResilient(backend, cfg.Policy), nil
+	case ProviderHash:
+		return NewHash(cfg.Dimension), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.Provider)
 	}
-	return embs[0], nil
 }
 
-// GenerateBatchEmbeddings creates embeddings for multiple texts
-func (s *Service) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
-	if len(texts) == 0 {
-		return nil, fmt.Errorf("texts cannot be empty")
+// WithModelID returns cfg switched to the backend and model identified by
+// id, as returned by Embedder.ModelID (e.g. "ollama/llama3:latest" or
+// "hash/fnv-256"). Endpoint, key, cache and policy settings are kept.
+func (cfg Config) WithModelID(id string) (Config, error) {
+	provider, model, ok := strings.Cut(id, "/")
+	if !ok || model == "" {
+		return cfg, fmt.Errorf("invalid embedding model ID %q (want provider/model)", id)
 	}
-	embs, err := s.llm.CreateEmbedding(ctx, texts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create embeddings: %w", err)
+
+	switch provider {
+	case ProviderOllama, ProviderOpenAI:
+		cfg.Model = model
+	case ProviderHash:
+		dim, err := strconv.Atoi(strings.TrimPrefix(model, "fnv-"))
+		if err != nil {
+			return cfg, fmt.Errorf("invalid hash embedder ID %q", id)
+		}
+		cfg.Model, cfg.Dimension = "", dim
+	default:
+		return cfg, fmt.Errorf("unknown embedding provider: %s", provider)
 	}
-	log.Printf("Generated %d embeddings (dimension: %d)\n", len(embs), len(embs[0]))
-	return embs, nil
-}
\ No newline at end of file
+	cfg.Provider = provider
+	return cfg, nil
+}
@@ -0,0 +1,62 @@
+package embedding
+
+import "sync"
+
+// Set hands out one embedder per model, all sharing one embedding cache, for
+// work that spans collections indexed with different models
+type Set struct {
+	base  Config
+	cache *Cache
+
+	mu        sync.Mutex
+	embedders map[string]Embedder
+}
+
+// NewSet creates a set whose default embedder is configured by base
+func NewSet(base Config) (*Set, error) {
+	s := &Set{base: base, embedders: make(map[string]Embedder)}
+	if base.CachePath != "" {
+		cache, err := LoadCache(base.CachePath, base.CacheSize)
+		if err != nil {
+			return nil, err
+		}
+		s.cache = cache
+	}
+	return s, nil
+}
+
+// For returns the embedder of the model identified by modelID (see
+// Embedder.ModelID), or the default embedder if modelID is empty
+func (s *Set) For(modelID string) (Embedder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.embedders[modelID]; ok {
+		return e, nil
+	}
+
+	cfg := s.base
+	if modelID != "" {
+		var err error
+		if cfg, err = s.base.WithModelID(modelID); err != nil {
+			return nil, err
+		}
+	}
+	embedder, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		embedder = NewCached(embedder, s.cache)
+	}
+	s.embedders[modelID] = embedder
+	return embedder, nil
+}
+
+// Close prints the cache statistics and saves the shared cache
+func (s *Set) Close() error {
+	if s.cache == nil {
+		return nil
+	}
+	return closeCache(s.cache)
+}
@@ -0,0 +1,180 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ollama_go/internal/models"
+)
+
+// Answerer generates an answer to a question and returns the documents it
+// was generated from, numbered from 1 in the order given
+type Answerer func(ctx context.Context, question string) (string, []*models.Document, error)
+
+// AnswerConfig records what generated and judged the answers
+type AnswerConfig struct {
+	Golden    string `json:"golden"`
+	Index     string `json:"index"`
+	Model     string `json:"model"`
+	Judge     string `json:"judge"`
+	Documents int    `json:"documents"`
+}
+
+// AnswerResult is the judged answer to one golden question
+type AnswerResult struct {
+	ID               string   `json:"id"`
+	Question         string   `json:"question"`
+	Answer           string   `json:"answer"`
+	Sources          []string `json:"sources"`
+	Cited            []int    `json:"cited,omitempty"`
+	InvalidCitations []int    `json:"invalid_citations,omitempty"` // cited numbers with no such document
+	Verdict          Verdict  `json:"verdict"`
+	Error            string   `json:"error,omitempty"` // the verdict could not be parsed
+}
+
+// AnswerSummary averages the verdicts of the judged answers
+type AnswerSummary struct {
+	Faithfulness float64 `json:"faithfulness"`
+	Relevance    float64 `json:"relevance"`
+	Citations    float64 `json:"citations"`
+	Judged       int     `json:"judged"`
+	Unjudged     int     `json:"unjudged"`
+}
+
+// Thresholds are the minimum average scores for a run to pass; zero
+// disables a check. MaxUnjudged is the largest fraction of answers whose
+// verdict may fail to parse; at zero every answer must be judged.
+type Thresholds struct {
+	Faithfulness float64 `json:"faithfulness"`
+	Relevance    float64 `json:"relevance"`
+	Citations    float64 `json:"citations"`
+	MaxUnjudged  float64 `json:"max_unjudged"`
+}
+
+// AnswerReport is the machine-readable result of an answer-quality run
+type AnswerReport struct {
+	Label      string         `json:"label"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Config     AnswerConfig   `json:"config"`
+	Summary    AnswerSummary  `json:"summary"`
+	Thresholds Thresholds     `json:"thresholds"`
+	Passed     bool           `json:"passed"`
+	Failures   []string       `json:"failures,omitempty"`
+	Questions  []AnswerResult `json:"questions"`
+}
+
+// RunAnswers answers every question and has judge score the answers.
+// Generation and judge call failures abort the run; a verdict that cannot be
+// parsed is recorded on the question and left out of the averages.
+func RunAnswers(ctx context.Context, answer Answerer, judge *Judge, questions []Question) (*AnswerReport, error) {
+	report := &AnswerReport{
+		CreatedAt: time.Now().UTC(),
+		Questions: make([]AnswerResult, 0, len(questions)),
+	}
+
+	for _, q := range questions {
+		text, docs, err := answer(ctx, q.Question)
+		if err != nil {
+			return nil, fmt.Errorf("failed to answer question %s: %w", q.ID, err)
+		}
+
+		result := AnswerResult{ID: q.ID, Question: q.Question, Answer: text, Sources: make([]string, len(docs))}
+		for i, doc := range docs {
+			result.Sources[i] = doc.URL
+		}
+		result.Cited, result.InvalidCitations = citations(text, len(docs))
+
+		verdict, err := judge.Score(ctx, q.Question, text, docs)
+		if err != nil {
+			if errors.Is(err, ErrMalformed) {
+				result.Error = err.Error()
+				report.Summary.Unjudged++
+				report.Questions = append(report.Questions, result)
+				continue
+			}
+			return nil, fmt.Errorf("failed to judge question %s: %w", q.ID, err)
+		}
+
+		// Citations of documents that were never retrieved are wrong no
+		// matter what the judge thought of them
+		if len(result.Cited) > 0 {
+			verdict.Citations *= float64(len(result.Cited)-len(result.InvalidCitations)) / float64(len(result.Cited))
+		}
+		result.Verdict = verdict
+
+		report.Summary.Judged++
+		report.Summary.Faithfulness += verdict.Faithfulness
+		report.Summary.Relevance += verdict.Relevance
+		report.Summary.Citations += verdict.Citations
+		report.Questions = append(report.Questions, result)
+	}
+
+	if n := float64(report.Summary.Judged); n > 0 {
+		report.Summary.Faithfulness /= n
+		report.Summary.Relevance /= n
+		report.Summary.Citations /= n
+	}
+	return report, nil
+}
+
+// Gate checks the summary against thresholds and records the outcome
+func (r *AnswerReport) Gate(thresholds Thresholds) bool {
+	r.Thresholds = thresholds
+	r.Failures = nil
+
+	if r.Summary.Judged == 0 {
+		r.Failures = append(r.Failures, "no answer could be judged")
+	} else if total := r.Summary.Judged + r.Summary.Unjudged; float64(r.Summary.Unjudged) > thresholds.MaxUnjudged*float64(total) {
+		r.Failures = append(r.Failures, fmt.Sprintf("%d of %d answers could not be judged, more than %.3f", r.Summary.Unjudged, total, thresholds.MaxUnjudged))
+	}
+	for _, check := range []struct {
+		name     string
+		got, min float64
+	}{
+		{"faithfulness", r.Summary.Faithfulness, thresholds.Faithfulness},
+		{"relevance", r.Summary.Relevance, thresholds.Relevance},
+		{"citations", r.Summary.Citations, thresholds.Citations},
+	} {
+		if check.min > 0 && check.got < check.min {
+			r.Failures = append(r.Failures, fmt.Sprintf("%s %.3f is below %.3f", check.name, check.got, check.min))
+		}
+	}
+
+	r.Passed = len(r.Failures) == 0
+	return r.Passed
+}
+
+// Print writes a human-readable summary to stdout
+func (r *AnswerReport) Print() {
+	fmt.Printf("⚖️  %s: %d answers by %s judged by %s\n", r.Label, len(r.Questions), r.Config.Model, r.Config.Judge)
+	fmt.Printf("   %-13s %6.3f\n", "faithfulness", r.Summary.Faithfulness)
+	fmt.Printf("   %-13s %6.3f\n", "relevance", r.Summary.Relevance)
+	fmt.Printf("   %-13s %6.3f\n", "citations", r.Summary.Citations)
+
+	for _, q := range r.Questions {
+		switch {
+		case q.Error != "":
+			fmt.Printf("⚠️  [%s] could not be judged: %s\n", q.ID, q.Error)
+		case len(q.Verdict.Unsupported) > 0:
+			fmt.Printf("🚩 [%s] unsupported: %v\n", q.ID, q.Verdict.Unsupported)
+		}
+		if len(q.InvalidCitations) > 0 {
+			fmt.Printf("🚩 [%s] cites missing documents %v\n", q.ID, q.InvalidCitations)
+		}
+	}
+
+	if r.Passed {
+		fmt.Println("✅ Passed")
+		return
+	}
+	for _, failure := range r.Failures {
+		fmt.Printf("❌ %s\n", failure)
+	}
+}
+
+// WriteFile saves the report as indented JSON
+func (r *AnswerReport) WriteFile(path string) error {
+	return writeJSON(path, r)
+}
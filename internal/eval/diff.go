@@ -0,0 +1,146 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+)
+
+// Delta is the change of one metric between two reports
+type Delta struct {
+	Metric string  `json:"metric"`
+	Base   float64 `json:"base"`
+	Head   float64 `json:"head"`
+	Delta  float64 `json:"delta"`
+}
+
+// QuestionDelta is a question whose ranking changed between two reports
+type QuestionDelta struct {
+	ID         string  `json:"id"`
+	Question   string  `json:"question"`
+	BaseRR     float64 `json:"base_reciprocal_rank"`
+	HeadRR     float64 `json:"head_reciprocal_rank"`
+	BaseRecall float64 `json:"base_recall"`
+	HeadRecall float64 `json:"head_recall"`
+}
+
+// Diff compares a head report against a base report. Metrics are compared
+// at the cutoffs both reports share, and questions are matched by ID.
+type Diff struct {
+	Base      string          `json:"base"`
+	Head      string          `json:"head"`
+	K         int             `json:"k"` // cutoff of the per-question recall
+	Summary   []Delta         `json:"summary"`
+	Improved  []QuestionDelta `json:"improved"`
+	Regressed []QuestionDelta `json:"regressed"`
+	Missing   []string        `json:"missing,omitempty"` // question IDs in only one report
+}
+
+// Compare builds the diff of head against base
+func Compare(base, head *Report) (*Diff, error) {
+	diff := &Diff{Base: base.Label, Head: head.Label}
+
+	for _, b := range base.Summary.At {
+		h, ok := head.At(b.K)
+		if !ok {
+			continue
+		}
+		diff.K = b.K
+		diff.Summary = append(diff.Summary,
+			delta(fmt.Sprintf("recall@%d", b.K), b.Recall, h.Recall),
+			delta(fmt.Sprintf("precision@%d", b.K), b.Precision, h.Precision),
+			delta(fmt.Sprintf("ndcg@%d", b.K), b.NDCG, h.NDCG),
+		)
+	}
+	if diff.K == 0 {
+		return nil, fmt.Errorf("reports share no cutoff (%v vs %v)", base.Cutoffs, head.Cutoffs)
+	}
+	diff.Summary = append(diff.Summary, delta("mrr", base.Summary.MRR, head.Summary.MRR))
+
+	headByID := make(map[string]QuestionResult, len(head.Questions))
+	for _, q := range head.Questions {
+		headByID[q.ID] = q
+	}
+	for _, b := range base.Questions {
+		h, ok := headByID[b.ID]
+		if !ok {
+			diff.Missing = append(diff.Missing, b.ID)
+			continue
+		}
+		delete(headByID, b.ID)
+
+		q := QuestionDelta{
+			ID:         b.ID,
+			Question:   b.Question,
+			BaseRR:     b.RR,
+			HeadRR:     h.RR,
+			BaseRecall: b.recallAt(diff.K),
+			HeadRecall: h.recallAt(diff.K),
+		}
+		switch {
+		case q.HeadRR > q.BaseRR || (q.HeadRR == q.BaseRR && q.HeadRecall > q.BaseRecall):
+			diff.Improved = append(diff.Improved, q)
+		case q.HeadRR < q.BaseRR || q.HeadRecall < q.BaseRecall:
+			diff.Regressed = append(diff.Regressed, q)
+		}
+	}
+	for _, q := range head.Questions {
+		if _, ok := headByID[q.ID]; ok {
+			diff.Missing = append(diff.Missing, q.ID)
+		}
+	}
+	return diff, nil
+}
+
+func delta(metric string, base, head float64) Delta {
+	return Delta{Metric: metric, Base: base, Head: head, Delta: head - base}
+}
+
+func (q QuestionResult) recallAt(k int) float64 {
+	for _, at := range q.At {
+		if at.K == k {
+			return at.Recall
+		}
+	}
+	return 0
+}
+
+// Print writes a human-readable diff to stdout
+func (d *Diff) Print() {
+	fmt.Printf("🔀 %s → %s\n", d.Base, d.Head)
+	fmt.Printf("   %-14s %8s %8s %9s\n", "metric", "base", "head", "delta")
+	for _, m := range d.Summary {
+		fmt.Printf("   %-14s %8.3f %8.3f %s\n", m.Metric, m.Base, m.Head, arrow(m.Delta))
+	}
+
+	if len(d.Improved) > 0 {
+		fmt.Printf("\n✅ Improved (%d):\n", len(d.Improved))
+		for _, q := range d.Improved {
+			fmt.Printf("   [%s] RR %.2f → %.2f, recall@%d %.2f → %.2f  %s\n", q.ID, q.BaseRR, q.HeadRR, d.K, q.BaseRecall, q.HeadRecall, q.Question)
+		}
+	}
+	if len(d.Regressed) > 0 {
+		fmt.Printf("\n❌ Regressed (%d):\n", len(d.Regressed))
+		for _, q := range d.Regressed {
+			fmt.Printf("   [%s] RR %.2f → %.2f, recall@%d %.2f → %.2f  %s\n", q.ID, q.BaseRR, q.HeadRR, d.K, q.BaseRecall, q.HeadRecall, q.Question)
+		}
+	}
+	if len(d.Missing) > 0 {
+		fmt.Printf("\n⚠️  %d questions appear in only one report: %v\n", len(d.Missing), d.Missing)
+	}
+}
+
+// WriteFile saves the diff as indented JSON
+func (d *Diff) WriteFile(path string) error {
+	return writeJSON(path, d)
+}
+
+func arrow(delta float64) string {
+	switch {
+	case math.Abs(delta) < 0.0005:
+		return fmt.Sprintf("%9s", "=")
+	case delta > 0:
+		return fmt.Sprintf("%+8.3f▲", delta)
+	default:
+		return fmt.Sprintf("%+8.3f▼", delta)
+	}
+}
@@ -0,0 +1,221 @@
+package eval
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"unicode"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/resilience"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	// minChunkLength skips chunks too short to ask anything specific about
+	minChunkLength = 200
+	// duplicateOverlap is the share of words two questions may have in
+	// common before the later one counts as a duplicate
+	duplicateOverlap = 0.75
+)
+
+// Generator writes questions that a document chunk answers
+type Generator struct {
+	model  llms.Model
+	client *resilience.Client
+}
+
+// NewGenerator creates a question generator backed by model
+func NewGenerator(model llms.Model) *Generator {
+	return &Generator{model: model, client: resilience.NewClient(resilience.GeneratePolicy())}
+}
+
+const generatePrompt = `Write %d questions that a user of this documentation might ask and that
+the passage below answers. Each question must make sense on its own, without
+seeing the passage: name the concept, function or feature it asks about, and
+do not refer to "the passage", "the text" or "this document".
+
+Title: %s
+URL: %s
+Passage:
+%s
+
+Respond with JSON only, in this form:
+{"questions": ["first question?", "second question?"]}`
+
+// Questions asks the model for n questions answerable from doc
+func (g *Generator) Questions(ctx context.Context, doc *models.Document, n int) ([]string, error) {
+	prompt := fmt.Sprintf(generatePrompt, n, doc.Title, doc.URL, doc.Content)
+
+	var response string
+	err := g.client.Do(ctx, "generate questions", func(ctx context.Context) error {
+		var err error
+		response, err = llms.GenerateFromSinglePrompt(ctx, g.model, prompt, llms.WithTemperature(0.7))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate questions: %w", err)
+	}
+
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: no JSON in %q", ErrMalformed, response)
+	}
+	var parsed struct {
+		Questions []string `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return parsed.Questions, nil
+}
+
+// SampleChunks picks up to n documents long enough to question, spreading
+// the sample over as many pages as possible before taking a second chunk
+// of any page. The same seed picks the same chunks from the same index.
+func SampleChunks(docs []*models.Document, n int, seed int64) []*models.Document {
+	shuffled := make([]*models.Document, 0, len(docs))
+	for _, doc := range docs {
+		if len(doc.Content) >= minChunkLength {
+			shuffled = append(shuffled, doc)
+		}
+	}
+	slices.SortFunc(shuffled, func(a, b *models.Document) int {
+		return cmp.Or(strings.Compare(a.URL, b.URL), strings.Compare(a.ID, b.ID))
+	})
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	// Round-robin over pages in shuffled order
+	var pages []string
+	byPage := make(map[string][]*models.Document)
+	for _, doc := range shuffled {
+		if _, ok := byPage[doc.URL]; !ok {
+			pages = append(pages, doc.URL)
+		}
+		byPage[doc.URL] = append(byPage[doc.URL], doc)
+	}
+
+	sample := make([]*models.Document, 0, min(n, len(shuffled)))
+	for round := 0; len(sample) < n && len(sample) < len(shuffled); round++ {
+		for _, page := range pages {
+			if round < len(byPage[page]) && len(sample) < n {
+				sample = append(sample, byPage[page][round])
+			}
+		}
+	}
+	return sample
+}
+
+// Filter drops generated questions that are unusable or repeat a question
+// already accepted, and counts the reasons
+type Filter struct {
+	accepted [][]string // word sets of accepted questions
+	seen     map[string]bool
+	Dropped  map[string]int
+}
+
+// NewFilter creates a filter that also rejects duplicates of existing
+func NewFilter(existing []Question) *Filter {
+	f := &Filter{seen: make(map[string]bool), Dropped: make(map[string]int)}
+	for _, q := range existing {
+		f.remember(q.Question)
+	}
+	return f
+}
+
+// selfReferences are phrases that only make sense next to the source chunk
+var selfReferences = []string{
+	"the passage", "this passage", "the text", "this text", "the document",
+	"this document", "the context", "the above", "the author", "this section",
+	"the article", "this article",
+}
+
+// Accept reports whether question is kept, and remembers it if so
+func (f *Filter) Accept(question string) bool {
+	question = strings.TrimSpace(question)
+	reason := f.reject(question)
+	if reason != "" {
+		f.Dropped[reason]++
+		return false
+	}
+	f.remember(question)
+	return true
+}
+
+func (f *Filter) reject(question string) string {
+	words := wordsOf(question)
+	lower := strings.ToLower(question)
+	switch {
+	case !strings.HasSuffix(question, "?"):
+		return "not a question"
+	case len(words) < 4:
+		return "too short"
+	case len(words) > 40:
+		return "too long"
+	}
+	for _, phrase := range selfReferences {
+		if strings.Contains(lower, phrase) {
+			return "refers to the passage"
+		}
+	}
+
+	if f.seen[strings.Join(words, " ")] {
+		return "duplicate"
+	}
+	set := uniqueWords(words)
+	for _, other := range f.accepted {
+		if jaccard(set, other) >= duplicateOverlap {
+			return "duplicate"
+		}
+	}
+	return ""
+}
+
+func (f *Filter) remember(question string) {
+	words := wordsOf(question)
+	f.seen[strings.Join(words, " ")] = true
+	f.accepted = append(f.accepted, uniqueWords(words))
+}
+
+// wordsOf lowercases text and splits it into words without punctuation
+func wordsOf(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func uniqueWords(words []string) []string {
+	seen := make(map[string]bool, len(words))
+	unique := make([]string, 0, len(words))
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			unique = append(unique, w)
+		}
+	}
+	return unique
+}
+
+// jaccard is the overlap of two word sets
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	in := make(map[string]bool, len(a))
+	for _, w := range a {
+		in[w] = true
+	}
+	shared := 0
+	for _, w := range b {
+		if in[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+
+	"ollama_go/internal/models"
+)
+
+func TestFilter(t *testing.T) {
+	f := NewFilter([]Question{{Question: "How do I declare a map in Go?"}})
+
+	tests := []struct {
+		question string
+		want     bool
+	}{
+		{"How do I declare a map in Go?", false},
+		{"how do I declare a map in go", false},
+		{"How do I declare a map in Go programs?", false},
+		{"What does the passage say about channels?", false},
+		{"Channels?", false},
+		{"What happens when a goroutine sends on a closed channel?", true},
+		{"What happens when a goroutine sends on a closed channel?", false},
+	}
+	for _, tt := range tests {
+		if got := f.Accept(tt.question); got != tt.want {
+			t.Errorf("Accept(%q) = %v, want %v", tt.question, got, tt.want)
+		}
+	}
+	if f.Dropped["duplicate"] != 3 {
+		t.Errorf("dropped %v, want 3 duplicates", f.Dropped)
+	}
+}
+
+func TestSampleChunksSpreadsOverPages(t *testing.T) {
+	content := strings.Repeat("x", minChunkLength)
+	var docs []*models.Document
+	for _, id := range []string{"a1", "a2", "a3", "b1", "c1"} {
+		docs = append(docs, &models.Document{ID: id, URL: id[:1], Content: content})
+	}
+	docs = append(docs, &models.Document{ID: "short", URL: "d", Content: "too short"})
+
+	sample := SampleChunks(docs, 3, 1)
+	pages := make(map[string]bool)
+	for _, doc := range sample {
+		pages[doc.URL] = true
+	}
+	if len(sample) != 3 || len(pages) != 3 || pages["d"] {
+		t.Errorf("sampled %d chunks from pages %v, want one from each of a, b and c", len(sample), pages)
+	}
+}
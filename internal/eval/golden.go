@@ -0,0 +1,97 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Question is one line of a golden question set
+type Question struct {
+	ID         string   `json:"id,omitempty"`
+	Question   string   `json:"question"`
+	Relevant   []string `json:"relevant"`              // URLs or URL#section of the passages that answer it
+	DocumentID string   `json:"document_id,omitempty"` // chunk a generated question was written from
+}
+
+// LoadQuestions reads a JSONL golden set. Blank lines and lines starting
+// with "//" are skipped; questions without an ID are numbered by line.
+func LoadQuestions(path string) ([]Question, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open golden set: %w", err)
+	}
+	defer file.Close()
+
+	var questions []Question
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+
+		var q Question
+		if err := json.Unmarshal([]byte(text), &q); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if strings.TrimSpace(q.Question) == "" {
+			return nil, fmt.Errorf("%s:%d: question is empty", path, line)
+		}
+		if len(q.Relevant) == 0 {
+			return nil, fmt.Errorf("%s:%d: no relevant URLs", path, line)
+		}
+		if q.ID == "" {
+			q.ID = strconv.Itoa(line)
+		}
+		if seen[q.ID] {
+			return nil, fmt.Errorf("%s:%d: duplicate question ID %q", path, line, q.ID)
+		}
+		seen[q.ID] = true
+		questions = append(questions, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read golden set: %w", err)
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("%s contains no questions", path)
+	}
+	return questions, nil
+}
+
+// WriteQuestions writes questions as JSONL, appending to an existing file
+// if appendFile is set
+func WriteQuestions(path string, questions []Question, appendFile bool) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendFile {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open golden set: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	for _, q := range questions {
+		if err := encoder.Encode(q); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write question %s: %w", q.ID, err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write golden set: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"ollama_go/internal"
+	"ollama_go/internal/models"
+	"ollama_go/internal/resilience"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Verdict is the judge's assessment of one answer. Scores are in [0, 1].
+type Verdict struct {
+	Faithfulness float64  `json:"faithfulness"` // claims are supported by the retrieved context
+	Relevance    float64  `json:"relevance"`    // the answer addresses the question
+	Citations    float64  `json:"citations"`    // cited documents support what they are cited for
+	Unsupported  []string `json:"unsupported_claims,omitempty"`
+	Reasoning    string   `json:"reasoning,omitempty"`
+}
+
+// ErrMalformed is returned when a model response cannot be parsed
+var ErrMalformed = errors.New("malformed model response")
+
+// ErrBadVerdict is the former name of ErrMalformed.
+//
+// Deprecated: use ErrMalformed, which also covers generated questions.
+var ErrBadVerdict = ErrMalformed
+
+// Judge scores answers with an LLM. Any llms.Model can judge, so the judge
+// can be a different (typically larger) model than the one answering.
+type Judge struct {
+	model  llms.Model
+	client *resilience.Client
+}
+
+// NewJudge creates a judge backed by model
+func NewJudge(model llms.Model) *Judge {
+	return &Judge{model: model, client: resilience.NewClient(resilience.GeneratePolicy())}
+}
+
+const judgePrompt = `You are grading the answer of a retrieval-augmented assistant.
+Only the numbered context documents may be used to support the answer.
+
+Context:
+%s
+
+---
+
+Question: %s
+
+Answer: %s
+
+---
+
+Rate the answer on each criterion from 1 (worst) to 5 (best):
+- faithfulness: every claim in the answer is supported by the context; 1 if it states things the context does not say
+- relevance: the answer directly addresses the question
+- citations: each [Document N] citation points to a document that supports the cited claim; 1 if citations are missing or wrong
+
+Respond with JSON only, in this form:
+{"faithfulness": 1-5, "relevance": 1-5, "citations": 1-5, "unsupported_claims": ["claim not supported by the context"], "reasoning": "one sentence"}`
+
+// Score asks the model to judge answer given the documents it was generated from
+func (j *Judge) Score(ctx context.Context, question, answer string, docs []*models.Document) (Verdict, error) {
+	prompt := fmt.Sprintf(judgePrompt, internal.BuildContext(docs), question, answer)
+
+	var response string
+	err := j.client.Do(ctx, "judge", func(ctx context.Context) error {
+		var err error
+		response, err = llms.GenerateFromSinglePrompt(ctx, j.model, prompt, llms.WithTemperature(0))
+		return err
+	})
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to judge answer: %w", err)
+	}
+	return parseVerdict(response)
+}
+
+// parseVerdict reads the judge's JSON, tolerating code fences and prose
+// around it, and maps the 1-5 ratings to [0, 1]
+func parseVerdict(response string) (Verdict, error) {
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return Verdict{}, fmt.Errorf("%w: no JSON in %q", ErrMalformed, response)
+	}
+
+	var raw struct {
+		Faithfulness json.Number `json:"faithfulness"`
+		Relevance    json.Number `json:"relevance"`
+		Citations    json.Number `json:"citations"`
+		Unsupported  []string    `json:"unsupported_claims"`
+		Reasoning    string      `json:"reasoning"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &raw); err != nil {
+		return Verdict{}, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	verdict := Verdict{Unsupported: raw.Unsupported, Reasoning: raw.Reasoning}
+	for _, field := range []struct {
+		name  string
+		value json.Number
+		dst   *float64
+	}{
+		{"faithfulness", raw.Faithfulness, &verdict.Faithfulness},
+		{"relevance", raw.Relevance, &verdict.Relevance},
+		{"citations", raw.Citations, &verdict.Citations},
+	} {
+		rating, err := strconv.ParseFloat(string(field.value), 64)
+		if err != nil || rating < 1 || rating > 5 {
+			return Verdict{}, fmt.Errorf("%w: %s rated %q, want 1-5", ErrMalformed, field.name, field.value)
+		}
+		*field.dst = (rating - 1) / 4
+	}
+	return verdict, nil
+}
+
+var citationPattern = regexp.MustCompile(`\[Document (\d+)\]`)
+
+// citations returns the document numbers cited in answer and those that do
+// not exist among the n retrieved documents
+func citations(answer string, n int) (cited, invalid []int) {
+	seen := make(map[int]bool)
+	for _, m := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		number, _ := strconv.Atoi(m[1])
+		if seen[number] {
+			continue
+		}
+		seen[number] = true
+		cited = append(cited, number)
+		if number < 1 || number > n {
+			invalid = append(invalid, number)
+		}
+	}
+	return cited, invalid
+}
@@ -0,0 +1,23 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseVerdict(t *testing.T) {
+	verdict, err := parseVerdict(`Sure: {"faithfulness": 5, "relevance": 4, "citations": 1, "unsupported_claims": ["x"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verdict.Faithfulness != 1 || verdict.Relevance != 0.75 || verdict.Citations != 0 || len(verdict.Unsupported) != 1 {
+		t.Errorf("verdict %+v, want scores 1, 0.75 and 0 with one unsupported claim", verdict)
+	}
+
+	for _, response := range []string{"no verdict", `{"faithfulness": "high"}`, `{"faithfulness": 9, "relevance": 1, "citations": 1}`} {
+		_, err := parseVerdict(response)
+		if !errors.Is(err, ErrMalformed) || !errors.Is(err, ErrBadVerdict) {
+			t.Errorf("parseVerdict(%q) = %v, want ErrMalformed", response, err)
+		}
+	}
+}
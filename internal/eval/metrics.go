@@ -0,0 +1,86 @@
+package eval
+
+import (
+	"math"
+	"strings"
+)
+
+// AtK holds the rank-cutoff metrics of one question or, averaged, of a run
+type AtK struct {
+	K         int     `json:"k"`
+	Recall    float64 `json:"recall"`
+	Precision float64 `json:"precision"`
+	NDCG      float64 `json:"ndcg"`
+}
+
+// Matches reports whether a retrieved URL satisfies an expected one. An
+// expected URL with a #section must match exactly; without one it matches
+// every section and chunk of the page; a trailing "*" matches a URL prefix.
+func Matches(expected, retrieved string) bool {
+	expected = strings.TrimSpace(expected)
+	if prefix, ok := strings.CutSuffix(expected, "*"); ok {
+		return strings.HasPrefix(retrieved, prefix)
+	}
+	if !strings.Contains(expected, "#") {
+		retrieved, _, _ = strings.Cut(retrieved, "#")
+	}
+	return trimSlash(expected) == trimSlash(retrieved)
+}
+
+func trimSlash(url string) string {
+	if strings.HasSuffix(url, "://") {
+		return url
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+// judge marks which ranks of retrieved are relevant. Each expected URL is
+// credited once, so further chunks of an already found page count as misses.
+func judge(relevant, retrieved []string) []bool {
+	credited := make([]bool, len(relevant))
+	hits := make([]bool, len(retrieved))
+	for rank, url := range retrieved {
+		for i, expected := range relevant {
+			if !credited[i] && Matches(expected, url) {
+				credited[i] = true
+				hits[rank] = true
+				break
+			}
+		}
+	}
+	return hits
+}
+
+// score computes recall, precision and nDCG (binary gains) at cutoff k
+func score(hits []bool, relevant, k int) AtK {
+	found := 0
+	dcg := 0.0
+	for rank := 0; rank < k && rank < len(hits); rank++ {
+		if hits[rank] {
+			found++
+			dcg += 1 / math.Log2(float64(rank+2))
+		}
+	}
+
+	ideal := 0.0
+	for rank := 0; rank < k && rank < relevant; rank++ {
+		ideal += 1 / math.Log2(float64(rank+2))
+	}
+
+	return AtK{
+		K:         k,
+		Recall:    float64(found) / float64(relevant),
+		Precision: float64(found) / float64(k),
+		NDCG:      dcg / ideal,
+	}
+}
+
+// reciprocalRank is 1/rank of the first relevant result, or 0 if none
+func reciprocalRank(hits []bool) (float64, int) {
+	for rank, hit := range hits {
+		if hit {
+			return 1 / float64(rank+1), rank + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,91 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		expected, retrieved string
+		want                bool
+	}{
+		{"https://go.dev/doc/", "https://go.dev/doc", true},
+		{"https://go.dev/doc/effective_go", "https://go.dev/doc/effective_go#goroutines", true},
+		{"https://go.dev/doc/effective_go#goroutines", "https://go.dev/doc/effective_go#maps", false},
+		{"https://go.dev/doc/effective_go#maps", "https://go.dev/doc/effective_go#maps", true},
+		{"https://go.dev/blog/*", "https://go.dev/blog/maps", true},
+		{"https://go.dev/blog/*", "https://go.dev/doc/", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.expected, tt.retrieved); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.expected, tt.retrieved, got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	retrieved := map[string][]string{
+		// The second chunk of page a earns no further credit
+		"first":  {"a", "a", "x", "b"},
+		"second": {"x", "y", "z", "w"},
+	}
+	retrieve := func(ctx context.Context, question string) ([]string, error) {
+		return retrieved[question], nil
+	}
+	questions := []Question{
+		{ID: "1", Question: "first", Relevant: []string{"a", "b"}},
+		{ID: "2", Question: "second", Relevant: []string{"a"}},
+	}
+
+	report, err := Run(context.Background(), retrieve, questions, []int{4, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := report.Questions[0]
+	if q.FirstHit != 1 || q.RR != 1 {
+		t.Errorf("first hit %d with RR %f, want rank 1", q.FirstHit, q.RR)
+	}
+	wantNDCG := (1 + 1/math.Log2(5)) / (1 + 1/math.Log2(3))
+	at4 := q.At[1]
+	if at4.K != 4 || at4.Recall != 1 || at4.Precision != 0.5 || math.Abs(at4.NDCG-wantNDCG) > 1e-9 {
+		t.Errorf("@4 = %+v, want recall 1, precision 0.5, nDCG %f", at4, wantNDCG)
+	}
+
+	if report.Summary.MRR != 0.5 {
+		t.Errorf("MRR = %f, want 0.5", report.Summary.MRR)
+	}
+	if at1, _ := report.At(1); at1.Recall != 0.25 || at1.Precision != 0.5 {
+		t.Errorf("summary @1 = %+v, want recall 0.25, precision 0.5", at1)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := &Report{Label: "base", Cutoffs: []int{1, 5}, Summary: Summary{MRR: 0.5, At: []AtK{{K: 1, Recall: 0.5}, {K: 5, Recall: 0.6}}},
+		Questions: []QuestionResult{
+			{ID: "1", RR: 1, At: []AtK{{K: 5, Recall: 1}}},
+			{ID: "2", RR: 0, At: []AtK{{K: 5, Recall: 0}}},
+		}}
+	head := &Report{Label: "head", Cutoffs: []int{5, 10}, Summary: Summary{MRR: 0.75, At: []AtK{{K: 5, Recall: 0.8}, {K: 10, Recall: 0.9}}},
+		Questions: []QuestionResult{
+			{ID: "1", RR: 0.5, At: []AtK{{K: 5, Recall: 1}}},
+			{ID: "2", RR: 1, At: []AtK{{K: 5, Recall: 1}}},
+			{ID: "3", RR: 1},
+		}}
+
+	diff, err := Compare(base, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.K != 5 || len(diff.Summary) != 4 || math.Abs(diff.Summary[0].Delta-0.2) > 1e-9 {
+		t.Errorf("summary at k=%d: %+v", diff.K, diff.Summary)
+	}
+	if len(diff.Improved) != 1 || diff.Improved[0].ID != "2" || len(diff.Regressed) != 1 || diff.Regressed[0].ID != "1" {
+		t.Errorf("improved %+v, regressed %+v", diff.Improved, diff.Regressed)
+	}
+	if len(diff.Missing) != 1 || diff.Missing[0] != "3" {
+		t.Errorf("missing = %v, want [3]", diff.Missing)
+	}
+}
@@ -0,0 +1,172 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Retriever returns the URLs of the documents retrieved for a question, best
+// first, at least as many as the largest cutoff when the index has them
+type Retriever func(ctx context.Context, question string) ([]string, error)
+
+// Config records what was evaluated so two reports can be told apart
+type Config struct {
+	Golden    string `json:"golden"`
+	Index     string `json:"index"`
+	Model     string `json:"model"`
+	Metric    string `json:"metric"`
+	Documents int    `json:"documents"`
+}
+
+// QuestionResult is the outcome of one golden question
+type QuestionResult struct {
+	ID        string   `json:"id"`
+	Question  string   `json:"question"`
+	Relevant  []string `json:"relevant"`
+	Retrieved []string `json:"retrieved"`
+	FirstHit  int      `json:"first_hit"` // rank of the first relevant result, 0 if none
+	RR        float64  `json:"reciprocal_rank"`
+	At        []AtK    `json:"at"`
+}
+
+// Summary averages the per-question metrics
+type Summary struct {
+	MRR float64 `json:"mrr"`
+	At  []AtK   `json:"at"`
+}
+
+// Report is the machine-readable result of an evaluation run
+type Report struct {
+	Label     string           `json:"label"`
+	CreatedAt time.Time        `json:"created_at"`
+	Config    Config           `json:"config"`
+	Cutoffs   []int            `json:"cutoffs"`
+	Summary   Summary          `json:"summary"`
+	Questions []QuestionResult `json:"questions"`
+}
+
+// Run retrieves every question and scores the results at each cutoff
+func Run(ctx context.Context, retrieve Retriever, questions []Question, cutoffs []int) (*Report, error) {
+	cutoffs = slices.Clone(cutoffs)
+	slices.Sort(cutoffs)
+	cutoffs = slices.Compact(cutoffs)
+	if len(cutoffs) == 0 || cutoffs[0] <= 0 {
+		return nil, fmt.Errorf("cutoffs must be positive")
+	}
+	depth := cutoffs[len(cutoffs)-1]
+
+	report := &Report{
+		CreatedAt: time.Now().UTC(),
+		Cutoffs:   cutoffs,
+		Questions: make([]QuestionResult, 0, len(questions)),
+		Summary:   Summary{At: make([]AtK, len(cutoffs))},
+	}
+
+	for _, q := range questions {
+		retrieved, err := retrieve(ctx, q.Question)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve question %s: %w", q.ID, err)
+		}
+		if len(retrieved) > depth {
+			retrieved = retrieved[:depth]
+		}
+
+		hits := judge(q.Relevant, retrieved)
+		result := QuestionResult{
+			ID:        q.ID,
+			Question:  q.Question,
+			Relevant:  q.Relevant,
+			Retrieved: retrieved,
+			At:        make([]AtK, len(cutoffs)),
+		}
+		result.RR, result.FirstHit = reciprocalRank(hits)
+		report.Summary.MRR += result.RR
+		for i, k := range cutoffs {
+			result.At[i] = score(hits, len(q.Relevant), k)
+			report.Summary.At[i].K = k
+			report.Summary.At[i].Recall += result.At[i].Recall
+			report.Summary.At[i].Precision += result.At[i].Precision
+			report.Summary.At[i].NDCG += result.At[i].NDCG
+		}
+		report.Questions = append(report.Questions, result)
+	}
+
+	n := float64(len(questions))
+	report.Summary.MRR /= n
+	for i := range report.Summary.At {
+		report.Summary.At[i].Recall /= n
+		report.Summary.At[i].Precision /= n
+		report.Summary.At[i].NDCG /= n
+	}
+	return report, nil
+}
+
+// At returns the summary metrics at cutoff k
+func (r *Report) At(k int) (AtK, bool) {
+	for _, at := range r.Summary.At {
+		if at.K == k {
+			return at, true
+		}
+	}
+	return AtK{}, false
+}
+
+// Print writes a human-readable summary to stdout
+func (r *Report) Print() {
+	fmt.Printf("📊 %s: %d questions against %d documents (%s, %s)\n",
+		r.Label, len(r.Questions), r.Config.Documents, r.Config.Model, r.Config.Metric)
+	fmt.Printf("   %-6s %8s %10s %8s\n", "cutoff", "recall", "precision", "nDCG")
+	for _, at := range r.Summary.At {
+		fmt.Printf("   @%-5d %8.3f %10.3f %8.3f\n", at.K, at.Recall, at.Precision, at.NDCG)
+	}
+	fmt.Printf("   MRR    %8.3f\n", r.Summary.MRR)
+
+	missed := 0
+	for _, q := range r.Questions {
+		if q.FirstHit == 0 {
+			missed++
+		}
+	}
+	if missed > 0 {
+		fmt.Printf("⚠️  %d questions retrieved nothing relevant\n", missed)
+	}
+}
+
+// WriteFile saves the report as indented JSON
+func (r *Report) WriteFile(path string) error {
+	return writeJSON(path, r)
+}
+
+// LoadReport reads a report saved by WriteFile
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+func writeJSON(path string, v any) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/resilience"
+)
+
+// batchSizer adapts the embedding batch size to observed latency and errors:
+// it grows additively while batches finish under the target latency, shrinks
+// by a quarter when they are slower and halves when the server times out or
+// is overloaded (AIMD). Rejected input says nothing about the size, so it
+// leaves the size alone.
+type batchSizer struct {
+	mu     sync.Mutex
+	size   int
+	max    int
+	target time.Duration
+}
+
+func newBatchSizer(maxSize int, target time.Duration) *batchSizer {
+	maxSize = max(maxSize, 1)
+	return &batchSizer{
+		size:   min(4, maxSize),
+		max:    maxSize,
+		target: target,
+	}
+}
+
+// Size returns the batch size to use for the next batch
+func (b *batchSizer) Size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// Observe records the outcome of a batch of n texts
+func (b *batchSizer) Observe(n int, latency time.Duration, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case err != nil:
+		if overloaded(err) {
+			b.size = max(b.size/2, 1)
+		}
+	case b.target > 0 && latency > b.target:
+		b.size = max(b.size*3/4, 1)
+	case n >= b.size:
+		// Only grow when the batch was full; a partial batch says nothing
+		// about whether a larger one would still be fast enough
+		b.size = min(b.size+1, b.max)
+	}
+}
+
+// overloaded reports whether err means the server could not keep up, which
+// a smaller batch may help with
+func overloaded(err error) bool {
+	switch resilience.Classify(err) {
+	case resilience.ErrTimeout, resilience.ErrOverloaded:
+		return true
+	}
+	return false
+}
+
+// embedResult is the outcome of embedding one text of a batch
+type embedResult struct {
+	vector []float32
+	err    error
+}
+
+// embedBatch embeds texts with as few requests as possible. Only the first
+// request, for the whole batch, is observed by sizer; the retries of a
+// failing batch are split off by splitBatch.
+func embedBatch(ctx context.Context, embedder embedding.Embedder, sizer *batchSizer, texts []string) []embedResult {
+	start := time.Now()
+	vectors, err := embedder.EmbedBatch(ctx, texts)
+	sizer.Observe(len(texts), time.Since(start), err)
+	return splitBatch(ctx, embedder, texts, vectors, err)
+}
+
+// splitBatch returns the results of a batch request. A failing batch is
+// split in half and each half retried, so a single bad input only fails
+// itself instead of the whole batch. Failures of the server itself (down,
+// overloaded, model missing) fail the whole batch without splitting.
+func splitBatch(ctx context.Context, embedder embedding.Embedder, texts []string, vectors [][]float32, err error) []embedResult {
+	results := make([]embedResult, len(texts))
+	if err == nil {
+		for i, v := range vectors {
+			results[i].vector = v
+		}
+		return results
+	}
+	if len(texts) == 1 || ctx.Err() != nil || resilience.ServerDown(err) {
+		for i := range results {
+			results[i].err = err
+		}
+		return results
+	}
+
+	mid := len(texts) / 2
+	for _, half := range [][2]int{{0, mid}, {mid, len(texts)}} {
+		part := texts[half[0]:half[1]]
+		vectors, err := embedder.EmbedBatch(ctx, part)
+		copy(results[half[0]:half[1]], splitBatch(ctx, embedder, part, vectors, err))
+	}
+	return results
+}
+
+// collectBatch blocks for the first job, then gathers up to size-1 more that
+// arrive within linger. ok is false once in is closed and drained.
+func collectBatch[T any](in <-chan T, size int, linger time.Duration) (batch []T, ok bool) {
+	first, ok := <-in
+	if !ok {
+		return nil, false
+	}
+	batch = append(make([]T, 0, size), first)
+
+	timer := time.NewTimer(linger)
+	defer timer.Stop()
+	for len(batch) < size {
+		select {
+		case job, ok := <-in:
+			if !ok {
+				return batch, true
+			}
+			batch = append(batch, job)
+		case <-timer.C:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// throughput accumulates embedding volume for the end-of-run report
+type throughput struct {
+	mu      sync.Mutex
+	start   time.Time
+	chunks  int
+	tokens  int
+	batches int
+}
+
+// Record adds one embedded batch
+func (t *throughput) Record(texts []string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.start.IsZero() {
+		t.start = time.Now().Add(-latency)
+	}
+	t.batches++
+	t.chunks += len(texts)
+	for _, text := range texts {
+		t.tokens += estimateTokens(text)
+	}
+}
+
+// Print writes chunks/sec and tokens/sec over the wall-clock embedding time
+func (t *throughput) Print() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.chunks == 0 {
+		return
+	}
+
+	elapsed := time.Since(t.start).Seconds()
+	fmt.Printf("⚡ Embedding throughput: %d chunks in %d batches (avg %.1f/batch), %.1f chunks/sec, ~%.0f tokens/sec\n",
+		t.chunks, t.batches, float64(t.chunks)/float64(t.batches),
+		float64(t.chunks)/elapsed, float64(t.tokens)/elapsed)
+}
+
+// estimateTokens approximates a tokenizer's count (about 4 characters per
+// token for English text) without depending on a specific model
+func estimateTokens(text string) int {
+	return max(len(strings.TrimSpace(text))/4, 1)
+}
@@ -0,0 +1,109 @@
+package ingest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/resilience"
+)
+
+func TestBatchSizer(t *testing.T) {
+	b := newBatchSizer(6, time.Second)
+	if b.Size() != 4 {
+		t.Fatalf("initial size = %d, want 4", b.Size())
+	}
+
+	steps := []struct {
+		name    string
+		n       int
+		latency time.Duration
+		err     error
+		want    int
+	}{
+		{"full fast batch grows", 4, 10 * time.Millisecond, nil, 5},
+		{"partial batch keeps the size", 2, 10 * time.Millisecond, nil, 5},
+		{"grows up to the maximum", 5, 10 * time.Millisecond, nil, 6},
+		{"stops at the maximum", 6, 10 * time.Millisecond, nil, 6},
+		{"slow batch shrinks by a quarter", 6, 2 * time.Second, nil, 4},
+		{"rejected input keeps the size", 4, 10 * time.Millisecond, &resilience.StatusError{StatusCode: http.StatusBadRequest}, 4},
+		{"overloaded server halves", 4, 10 * time.Millisecond, &resilience.StatusError{StatusCode: http.StatusServiceUnavailable}, 2},
+		{"timeout halves", 2, time.Second, context.DeadlineExceeded, 1},
+		{"never below one", 1, time.Second, context.DeadlineExceeded, 1},
+	}
+	for _, step := range steps {
+		b.Observe(step.n, step.latency, step.err)
+		if got := b.Size(); got != step.want {
+			t.Fatalf("%s: size = %d, want %d", step.name, got, step.want)
+		}
+	}
+}
+
+// rejectingEmbedder rejects every batch with a text containing "bad", as a
+// server does for input it cannot embed, and counts the requests
+type rejectingEmbedder struct {
+	*embedding.Hash
+	mu       sync.Mutex
+	requests int
+	down     bool
+}
+
+func (e *rejectingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	e.requests++
+	e.mu.Unlock()
+	if e.down {
+		return nil, &resilience.StatusError{StatusCode: http.StatusServiceUnavailable}
+	}
+	for _, text := range texts {
+		if strings.Contains(text, "bad") {
+			return nil, &resilience.StatusError{StatusCode: http.StatusBadRequest, Message: "invalid input"}
+		}
+	}
+	return e.Hash.EmbedBatch(ctx, texts)
+}
+
+func TestEmbedBatchSplitsOnFailure(t *testing.T) {
+	embedder := &rejectingEmbedder{Hash: embedding.NewHash(8)}
+	sizer := newBatchSizer(8, time.Second)
+	texts := []string{"a", "b", "bad", "d", "e", "f", "g", "h"}
+
+	results := embedBatch(context.Background(), embedder, sizer, texts)
+	for i, r := range results {
+		if (r.err != nil) != (texts[i] == "bad") {
+			t.Errorf("text %q: err = %v", texts[i], r.err)
+		}
+		if r.err == nil && len(r.vector) != 8 {
+			t.Errorf("text %q has a vector of %d dimensions", texts[i], len(r.vector))
+		}
+	}
+	// 8 → 4 + 4 → 2 + 2 → 1 + 1: one request for the batch and six retries
+	if embedder.requests != 7 {
+		t.Errorf("made %d requests, want 7", embedder.requests)
+	}
+	if got := sizer.Size(); got != 4 {
+		t.Errorf("size after a rejected input = %d, want 4", got)
+	}
+}
+
+func TestEmbedBatchDoesNotSplitWhenServerIsDown(t *testing.T) {
+	embedder := &rejectingEmbedder{Hash: embedding.NewHash(8), down: true}
+	sizer := newBatchSizer(8, time.Second)
+
+	results := embedBatch(context.Background(), embedder, sizer, []string{"a", "b", "c", "d"})
+	for i, r := range results {
+		if !resilience.ServerDown(r.err) {
+			t.Errorf("result %d: err = %v, want the server error", i, r.err)
+		}
+	}
+	if embedder.requests != 1 {
+		t.Errorf("made %d requests, want 1", embedder.requests)
+	}
+	if got := sizer.Size(); got != 2 {
+		t.Errorf("size after an overloaded server = %d, want 2", got)
+	}
+}
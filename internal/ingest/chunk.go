@@ -0,0 +1,115 @@
+package ingest
+
+import (
+	"strconv"
+	"strings"
+
+	"ollama_go/internal/models"
+)
+
+// Chunker splits long pages into overlapping chunks that fit the embedding window
+type Chunker struct {
+	Size    int // maximum characters per chunk
+	Overlap int // characters carried over from the previous chunk
+}
+
+// NewChunker creates a chunker with the default size and overlap
+func NewChunker() *Chunker {
+	return &Chunker{Size: 1500, Overlap: 200}
+}
+
+// ChunkPage splits a page into one or more pages. Pages that already fit are
+// returned unchanged; otherwise each chunk keeps the page's URL and title and
+// records its position in the "chunk" metadata key.
+func (c *Chunker) ChunkPage(page *models.PageContent) []*models.PageContent {
+	chunks := c.split(page.MainContent)
+	if len(chunks) <= 1 {
+		return []*models.PageContent{page}
+	}
+
+	pages := make([]*models.PageContent, 0, len(chunks))
+	for i, chunk := range chunks {
+		metadata := make(map[string]string, len(page.Metadata)+1)
+		for k, v := range page.Metadata {
+			metadata[k] = v
+		}
+		metadata["chunk"] = strconv.Itoa(i)
+
+		pages = append(pages, &models.PageContent{
+			URL:         page.URL,
+			Title:       page.Title,
+			Description: page.Description,
+			MainContent: chunk,
+			LinkCount:   page.LinkCount,
+			Metadata:    metadata,
+		})
+	}
+	return pages
+}
+
+// ChunkPages applies ChunkPage to every page
+func (c *Chunker) ChunkPages(pages []*models.PageContent) []*models.PageContent {
+	chunked := make([]*models.PageContent, 0, len(pages))
+	for _, page := range pages {
+		chunked = append(chunked, c.ChunkPage(page)...)
+	}
+	return chunked
+}
+
+// split groups paragraphs into chunks of at most Size characters
+func (c *Chunker) split(paragraphs []string) [][]string {
+	chunks := make([][]string, 0)
+	current := make([]string, 0)
+	size := 0
+	fresh := false // current holds more than the carried-over overlap
+
+	for _, paragraph := range paragraphs {
+		for _, piece := range c.splitLong(paragraph) {
+			if fresh && size+len(piece) > c.Size {
+				chunks = append(chunks, current)
+
+				// Carry trailing paragraphs over as overlap
+				overlap := make([]string, 0)
+				size = 0
+				for i := len(current) - 1; i >= 0 && size+len(current[i]) <= c.Overlap; i-- {
+					overlap = append([]string{current[i]}, overlap...)
+					size += len(current[i])
+				}
+				current = overlap
+			}
+			current = append(current, piece)
+			size += len(piece)
+			fresh = true
+		}
+	}
+
+	if fresh {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// splitLong breaks a single paragraph longer than Size on word boundaries
+func (c *Chunker) splitLong(paragraph string) []string {
+	if len(paragraph) <= c.Size {
+		return []string{paragraph}
+	}
+
+	pieces := make([]string, 0)
+	var b strings.Builder
+	for _, word := range strings.Fields(paragraph) {
+		if b.Len() > 0 && b.Len()+1+len(word) > c.Size {
+			pieces = append(pieces, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+	}
+	if b.Len() > 0 {
+		pieces = append(pieces, b.String())
+	}
+	return pieces
+}
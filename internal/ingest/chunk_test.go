@@ -0,0 +1,81 @@
+package ingest
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"ollama_go/internal/models"
+)
+
+func TestChunkerKeepsShortPages(t *testing.T) {
+	page := &models.PageContent{URL: "https://go.dev/a", MainContent: []string{"short", "page"}}
+	chunks := NewChunker().ChunkPage(page)
+	if len(chunks) != 1 || chunks[0] != page {
+		t.Errorf("short page chunked into %d pieces", len(chunks))
+	}
+}
+
+func TestChunkerSplitsWithOverlap(t *testing.T) {
+	c := &Chunker{Size: 100, Overlap: 30}
+	var paragraphs []string
+	for i := range 10 {
+		paragraphs = append(paragraphs, "paragraph "+strconv.Itoa(i)+" "+strings.Repeat("x", 15))
+	}
+	page := &models.PageContent{
+		URL:         "https://go.dev/a",
+		Title:       "A",
+		MainContent: paragraphs,
+		Metadata:    map[string]string{"source": "web"},
+	}
+
+	chunks := c.ChunkPage(page)
+	if len(chunks) < 3 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	seen := make(map[string]bool)
+	for i, chunk := range chunks {
+		size := 0
+		for _, p := range chunk.MainContent {
+			size += len(p)
+			seen[p] = true
+		}
+		if size > c.Size {
+			t.Errorf("chunk %d holds %d characters, more than %d", i, size, c.Size)
+		}
+		if chunk.URL != page.URL || chunk.Title != page.Title || chunk.Metadata["source"] != "web" {
+			t.Errorf("chunk %d lost the page's attributes: %+v", i, chunk)
+		}
+		if chunk.Metadata["chunk"] != strconv.Itoa(i) {
+			t.Errorf("chunk %d has chunk metadata %q", i, chunk.Metadata["chunk"])
+		}
+		if i > 0 && chunk.MainContent[0] != chunks[i-1].MainContent[len(chunks[i-1].MainContent)-1] {
+			t.Errorf("chunk %d does not start with the last paragraph of chunk %d", i, i-1)
+		}
+	}
+	if len(seen) != len(paragraphs) {
+		t.Errorf("chunks hold %d of the %d paragraphs", len(seen), len(paragraphs))
+	}
+	if _, ok := page.Metadata["chunk"]; ok {
+		t.Error("the page's own metadata was changed")
+	}
+}
+
+func TestChunkerSplitsLongParagraphs(t *testing.T) {
+	c := &Chunker{Size: 50, Overlap: 0}
+	long := strings.TrimSpace(strings.Repeat("word ", 40))
+	chunks := c.ChunkPage(&models.PageContent{URL: "https://go.dev/a", MainContent: []string{long}})
+
+	var words int
+	for _, chunk := range chunks {
+		for _, p := range chunk.MainContent {
+			if len(p) > c.Size {
+				t.Errorf("piece of %d characters, more than %d", len(p), c.Size)
+			}
+			words += len(strings.Fields(p))
+		}
+	}
+	if words != 40 {
+		t.Errorf("chunks hold %d of the 40 words", words)
+	}
+}
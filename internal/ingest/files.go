@@ -0,0 +1,356 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ollama_go/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// fileLoader converts the contents of a local file into one or more pages
+type fileLoader func(path string, data []byte) ([]*models.PageContent, error)
+
+// fileLoaders maps supported file extensions to their loader
+var fileLoaders = map[string]fileLoader{
+	".md":       single(loadMarkdown),
+	".markdown": single(loadMarkdown),
+	".txt":      single(loadText),
+	".rst":      single(loadRST),
+	".html":     single(loadHTML),
+	".htm":      single(loadHTML),
+	".pdf":      loadPDF,
+}
+
+// LoadFiles loads every supported file named by paths, which may be files,
+// directories (walked recursively) or glob patterns. Paths matching the
+// exclude patterns, or the .gitignore/.ragignore files of a walked directory
+// and its subdirectories, are skipped. The excludes are anchored to a walked
+// directory, and to the working directory for files named directly. Files
+// that cannot be read or parsed are reported and skipped.
+func LoadFiles(paths []string, excludes []string) ([]*models.PageContent, error) {
+	pages := make([]*models.PageContent, 0)
+
+	for _, arg := range paths {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", arg, err)
+			}
+		}
+
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+			}
+
+			if info.IsDir() {
+				dirPages, err := loadDir(path, excludes)
+				if err != nil {
+					return nil, err
+				}
+				pages = append(pages, dirPages...)
+				continue
+			}
+
+			if NewIgnoreMatcher(excludes).MatchFile(relativeToWorkDir(path)) {
+				continue
+			}
+			if _, ok := fileLoaders[strings.ToLower(filepath.Ext(path))]; !ok {
+				fmt.Printf("⚠️  Skipping %s: unsupported file type\n", path)
+				continue
+			}
+			filePages, err := loadFile(path)
+			if err != nil {
+				fmt.Printf("⚠️  Skipping %v\n", err)
+				continue
+			}
+			pages = append(pages, filePages...)
+		}
+	}
+
+	// A file may be named by more than one argument
+	seen := make(map[string]bool, len(pages))
+	unique := pages[:0]
+	for _, page := range pages {
+		if !seen[page.URL] {
+			seen[page.URL] = true
+			unique = append(unique, page)
+		}
+	}
+
+	return unique, nil
+}
+
+// relativeToWorkDir returns path relative to the working directory, which the
+// exclude patterns of a file named on the command line are anchored to
+func relativeToWorkDir(path string) string {
+	rel := path
+	if abs, err := filepath.Abs(path); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if r, err := filepath.Rel(wd, abs); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+				rel = r
+			}
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(rel)), "/")
+}
+
+// ignoreFiles are read in every walked directory, like nested .gitignore files
+var ignoreFiles = []string{".gitignore", ".ragignore"}
+
+// loadDir walks a directory and loads every supported file that is not ignored
+func loadDir(root string, excludes []string) ([]*models.PageContent, error) {
+	matcher := NewIgnoreMatcher(excludes)
+	for _, name := range ignoreFiles {
+		if err := matcher.AddFile(filepath.Join(root, name)); err != nil {
+			return nil, err
+		}
+	}
+
+	pages := make([]*models.PageContent, 0)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if d.Name() == ".git" || matcher.Match(rel, true) {
+				return filepath.SkipDir
+			}
+			// Its ignore files apply to everything below it
+			for _, name := range ignoreFiles {
+				if err := matcher.AddFileIn(filepath.Join(path, name), rel); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if matcher.Match(rel, false) {
+			return nil
+		}
+		if _, ok := fileLoaders[strings.ToLower(filepath.Ext(path))]; !ok {
+			return nil
+		}
+
+		filePages, err := loadFile(path)
+		if err != nil {
+			fmt.Printf("⚠️  Skipping %v\n", err)
+			return nil
+		}
+		pages = append(pages, filePages...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+
+	return pages, nil
+}
+
+// loadFile reads a file and converts it with the loader for its extension
+func loadFile(path string) ([]*models.PageContent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	pages, err := fileLoaders[ext](path, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for _, page := range pages {
+		if page.Metadata == nil {
+			page.Metadata = make(map[string]string)
+		}
+		page.Metadata["source"] = "file"
+		page.Metadata["format"] = strings.TrimPrefix(ext, ".")
+		page.Metadata["path"] = path
+
+		if page.Title == "" {
+			page.Title = filepath.Base(path)
+		}
+	}
+
+	return pages, nil
+}
+
+// single adapts a loader that produces exactly one page
+func single(load func(path string, data []byte) (*models.PageContent, error)) fileLoader {
+	return func(path string, data []byte) ([]*models.PageContent, error) {
+		page, err := load(path, data)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.PageContent{page}, nil
+	}
+}
+
+// fileURL returns a file:// URL for a local path
+func fileURL(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return "file://" + filepath.ToSlash(abs)
+}
+
+// loadText treats blank-line separated blocks as paragraphs
+func loadText(path string, data []byte) (*models.PageContent, error) {
+	return &models.PageContent{
+		URL:         fileURL(path),
+		MainContent: paragraphs(string(data)),
+	}, nil
+}
+
+// loadMarkdown parses optional YAML ("---") or TOML ("+++") front-matter
+// into metadata and uses the first heading as the title
+func loadMarkdown(path string, data []byte) (*models.PageContent, error) {
+	metadata, body := parseFrontMatter(string(data))
+
+	page := &models.PageContent{
+		URL:         fileURL(path),
+		Title:       metadata["title"],
+		Description: metadata["description"],
+		MainContent: paragraphs(body),
+		Metadata:    metadata,
+	}
+	if page.Description == "" {
+		page.Description = metadata["summary"]
+	}
+
+	if page.Title == "" {
+		for _, p := range page.MainContent {
+			if title, ok := strings.CutPrefix(p, "# "); ok {
+				page.Title = strings.TrimSpace(strings.SplitN(title, "\n", 2)[0])
+				break
+			}
+		}
+	}
+
+	return page, nil
+}
+
+// parseFrontMatter splits simple "key: value" (YAML) or "key = value" (TOML)
+// front-matter from the body. Nested structures are kept as raw strings.
+func parseFrontMatter(text string) (map[string]string, string) {
+	metadata := make(map[string]string)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var delim, sep string
+	switch {
+	case strings.HasPrefix(text, "---\n"):
+		delim, sep = "---", ":"
+	case strings.HasPrefix(text, "+++\n"):
+		delim, sep = "+++", "="
+	default:
+		return metadata, text
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Scan() // opening delimiter
+	consumed := len(scanner.Text()) + 1
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		consumed += len(line) + 1
+		if strings.TrimSpace(line) == delim {
+			return metadata, text[min(consumed, len(text)):]
+		}
+
+		key, value, ok := strings.Cut(line, sep)
+		if !ok || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if key != "" && value != "" {
+			metadata[key] = value
+		}
+	}
+
+	// Unterminated front-matter: treat the whole file as body
+	return make(map[string]string), text
+}
+
+// loadRST uses the first underlined (or over- and underlined) line as the title
+func loadRST(path string, data []byte) (*models.PageContent, error) {
+	page := &models.PageContent{
+		URL:         fileURL(path),
+		MainContent: make([]string, 0),
+	}
+
+	for _, p := range paragraphs(string(data)) {
+		lines := strings.Split(p, "\n")
+		// Drop section adornment lines (=====, -----, ~~~~~ ...)
+		kept := make([]string, 0, len(lines))
+		for _, line := range lines {
+			if isRSTAdornment(line) {
+				continue
+			}
+			kept = append(kept, line)
+		}
+		if len(kept) == 0 {
+			continue
+		}
+
+		if page.Title == "" && len(kept) < len(lines) {
+			page.Title = strings.TrimSpace(kept[0])
+		}
+		page.MainContent = append(page.MainContent, strings.Join(kept, "\n"))
+	}
+
+	return page, nil
+}
+
+// isRSTAdornment reports whether line is a run of a single punctuation character
+func isRSTAdornment(line string) bool {
+	line = strings.TrimSpace(line)
+	if len(line) < 3 || !strings.ContainsRune("=-~`:'\"^_*+#<>", rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// loadHTML runs a local HTML file through the crawler's extractor
+func loadHTML(path string, data []byte) (*models.PageContent, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	return ExtractHTML(doc.Selection, fileURL(path)), nil
+}
+
+// paragraphs splits text on blank lines, trimming each block
+func paragraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	blocks := strings.Split(text, "\n\n")
+
+	result := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block = strings.TrimSpace(block); block != "" {
+			result = append(result, block)
+		}
+	}
+	return result
+}
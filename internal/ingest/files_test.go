@@ -0,0 +1,130 @@
+package ingest
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		metadata map[string]string
+		body     string
+	}{
+		{
+			name:     "yaml",
+			text:     "---\ntitle: \"Effective Go\"\ntags:\n  - style\nauthor: gopher\n---\n# Heading\n\nBody.",
+			metadata: map[string]string{"title": "Effective Go", "author": "gopher"},
+			body:     "# Heading\n\nBody.",
+		},
+		{
+			name:     "toml",
+			text:     "+++\r\nTitle = 'Maps'\r\ndraft = false\r\n+++\r\nBody.",
+			metadata: map[string]string{"title": "Maps", "draft": "false"},
+			body:     "Body.",
+		},
+		{
+			name:     "none",
+			text:     "# Heading\n---\nnot: front-matter",
+			metadata: map[string]string{},
+			body:     "# Heading\n---\nnot: front-matter",
+		},
+		{
+			name:     "unterminated",
+			text:     "---\ntitle: lost\nBody.",
+			metadata: map[string]string{},
+			body:     "---\ntitle: lost\nBody.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, body := parseFrontMatter(tt.text)
+			if !maps.Equal(metadata, tt.metadata) {
+				t.Errorf("metadata = %v, want %v", metadata, tt.metadata)
+			}
+			if body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+// writeFiles creates files with the given contents below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// loadedPaths returns the sorted paths, relative to dir, of the loaded files
+func loadedPaths(t *testing.T, dir string, paths, excludes []string) []string {
+	t.Helper()
+	pages, err := LoadFiles(paths, excludes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded []string
+	for _, page := range pages {
+		abs, err := filepath.Abs(page.Metadata["path"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded = append(loaded, filepath.ToSlash(rel))
+	}
+	slices.Sort(loaded)
+	return loaded
+}
+
+func TestLoadFilesReadsNestedIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":           "*.txt\n",
+		"a.md":                 "A",
+		"notes.txt":            "ignored by the root",
+		"sub/.ragignore":       "/draft.md\n!keep.txt\n",
+		"sub/b.md":             "B",
+		"sub/draft.md":         "ignored by sub",
+		"sub/keep.txt":         "kept by sub",
+		"sub/deeper/draft.md":  "not anchored there",
+		"other/draft.md":       "not below sub",
+		"other/vendor/lib.md":  "excluded",
+		"other/vendor/lib.txt": "excluded",
+	})
+
+	got := loadedPaths(t, dir, []string{dir}, []string{"vendor/"})
+	want := []string{"a.md", "other/draft.md", "sub/b.md", "sub/deeper/draft.md", "sub/keep.txt"}
+	if !slices.Equal(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+}
+
+func TestLoadFilesExcludesNamedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"docs/internal/a.md": "A",
+		"docs/public/b.md":   "B",
+		"vendor/c.md":        "C",
+	})
+	t.Chdir(dir)
+
+	paths := []string{"docs/internal/a.md", "docs/public/b.md", filepath.Join(dir, "vendor", "c.md")}
+	got := loadedPaths(t, dir, paths, []string{"/docs/internal", "vendor/"})
+	if want := []string{"docs/public/b.md"}; !slices.Equal(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+}
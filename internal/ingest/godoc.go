@@ -0,0 +1,269 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ollama_go/internal/models"
+)
+
+// LoadGoDoc walks a local module directory (or GOROOT/src) and extracts
+// documentation for every package, type, function and method it finds.
+// Each symbol becomes its own page with an "importpath#Symbol" URL so the
+// index can answer API questions without network access.
+func LoadGoDoc(root string) ([]*models.PageContent, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+
+	modulePath, err := readModulePath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]*models.PageContent, 0)
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		if path != root {
+			name := d.Name()
+			// Skip directories the go tool ignores
+			if name == "testdata" || name == "vendor" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			// Skip nested modules
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		importPath := importPathFor(modulePath, filepath.ToSlash(rel))
+
+		pkgPages, err := loadPackageDoc(path, importPath)
+		if err != nil {
+			fmt.Printf("⚠️  Skipping %s: %v\n", importPath, err)
+			return nil
+		}
+		pages = append(pages, pkgPages...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+
+	return pages, nil
+}
+
+// readModulePath returns the module path declared in root/go.mod
+func readModulePath(root string) (string, error) {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("no go.mod found in %s: %w", root, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "module"); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	return "", fmt.Errorf("no module directive in %s/go.mod", root)
+}
+
+// importPathFor joins the module path and a slash-separated relative dir.
+// The standard library ("module std") has no module prefix.
+func importPathFor(modulePath, rel string) string {
+	if rel == "." {
+		return modulePath
+	}
+	if modulePath == "std" {
+		return rel
+	}
+	return modulePath + "/" + rel
+}
+
+// loadPackageDoc parses the package in dir and converts its documentation to pages
+func loadPackageDoc(dir, importPath string) ([]*models.PageContent, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	var pkgName string
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		// Respect build constraints for the host platform
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasSuffix(name, "_test.go") {
+			pkgName = file.Name.Name
+		}
+		files = append(files, file)
+	}
+
+	// Commands and test-only directories have no importable API
+	if pkgName == "" || pkgName == "main" {
+		return nil, nil
+	}
+
+	// Keep the package itself plus its external test package (for examples)
+	pkgFiles := files[:0]
+	for _, file := range files {
+		if file.Name.Name == pkgName || file.Name.Name == pkgName+"_test" {
+			pkgFiles = append(pkgFiles, file)
+		}
+	}
+
+	pkg, err := doc.NewFromFiles(fset, pkgFiles, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return packagePages(fset, pkg), nil
+}
+
+// packagePages builds one page per package, type, function and method
+func packagePages(fset *token.FileSet, pkg *doc.Package) []*models.PageContent {
+	pages := make([]*models.PageContent, 0)
+
+	metadata := func(kind, symbol string) map[string]string {
+		return map[string]string{
+			"source":  "godoc",
+			"package": pkg.ImportPath,
+			"kind":    kind,
+			"symbol":  symbol,
+		}
+	}
+
+	// Package overview
+	overview := []string{"package " + pkg.Name, `import "` + pkg.ImportPath + `"`}
+	if pkg.Doc != "" {
+		overview = append(overview, strings.TrimSpace(pkg.Doc))
+	}
+	overview = append(overview, formatExamples(fset, pkg.Examples)...)
+	pages = append(pages, &models.PageContent{
+		URL:         pkg.ImportPath,
+		Title:       "package " + pkg.ImportPath,
+		Description: pkg.Synopsis(pkg.Doc),
+		MainContent: overview,
+		Metadata:    metadata("package", ""),
+	})
+
+	addFunc := func(fn *doc.Func, symbol string) {
+		pages = append(pages, &models.PageContent{
+			URL:         pkg.ImportPath + "#" + symbol,
+			Title:       pkg.Name + "." + symbol,
+			Description: pkg.Synopsis(fn.Doc),
+			MainContent: symbolContent(fset, fn.Decl, fn.Doc, fn.Examples),
+			Metadata:    metadata(funcKind(fn), symbol),
+		})
+	}
+
+	for _, fn := range pkg.Funcs {
+		addFunc(fn, fn.Name)
+	}
+
+	for _, typ := range pkg.Types {
+		pages = append(pages, &models.PageContent{
+			URL:         pkg.ImportPath + "#" + typ.Name,
+			Title:       pkg.Name + "." + typ.Name,
+			Description: pkg.Synopsis(typ.Doc),
+			MainContent: symbolContent(fset, typ.Decl, typ.Doc, typ.Examples),
+			Metadata:    metadata("type", typ.Name),
+		})
+
+		// Constructors are grouped with their result type by go/doc
+		for _, fn := range typ.Funcs {
+			addFunc(fn, fn.Name)
+		}
+		for _, method := range typ.Methods {
+			addFunc(method, typ.Name+"."+method.Name)
+		}
+	}
+
+	return pages
+}
+
+// funcKind reports whether a documented func is a method or a plain function
+func funcKind(fn *doc.Func) string {
+	if fn.Recv != "" {
+		return "method"
+	}
+	return "func"
+}
+
+// symbolContent renders the signature, doc comment and examples of a symbol
+func symbolContent(fset *token.FileSet, decl ast.Node, comment string, examples []*doc.Example) []string {
+	content := []string{formatNode(fset, decl)}
+	if comment != "" {
+		content = append(content, strings.TrimSpace(comment))
+	}
+	return append(content, formatExamples(fset, examples)...)
+}
+
+// formatExamples renders the code of each example
+func formatExamples(fset *token.FileSet, examples []*doc.Example) []string {
+	content := make([]string, 0, len(examples))
+	for _, ex := range examples {
+		var b strings.Builder
+		b.WriteString("Example")
+		if ex.Suffix != "" {
+			b.WriteString(" (" + ex.Suffix + ")")
+		}
+		b.WriteString(":\n")
+		// Comments are kept so the "// Output:" block stays with the code
+		b.WriteString(formatNode(fset, &printer.CommentedNode{Node: ex.Code, Comments: ex.Comments}))
+		content = append(content, b.String())
+	}
+	return content
+}
+
+// formatNode pretty-prints an AST node as Go source
+func formatNode(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ollama_go/internal/models"
+)
+
+func TestLoadGoDoc(t *testing.T) {
+	pages, err := LoadGoDoc(filepath.Join("testdata", "godoc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byURL := make(map[string]*models.PageContent, len(pages))
+	for _, page := range pages {
+		byURL[page.URL] = page
+	}
+
+	tests := []struct {
+		url, title, kind, symbol string
+		content                  []string // each must appear in the content
+	}{
+		{"example.com/shapes", "package example.com/shapes", "package", "",
+			[]string{"package shapes", `import "example.com/shapes"`, "computes areas", "Example:\n", "// Output: true"}},
+		{"example.com/shapes#Circle", "shapes.Circle", "type", "Circle",
+			[]string{"type Circle struct", "a circle of a given radius"}},
+		{"example.com/shapes#NewCircle", "shapes.NewCircle", "func", "NewCircle",
+			[]string{"func NewCircle(r float64) *Circle"}},
+		{"example.com/shapes#Circle.Area", "shapes.Circle.Area", "method", "Circle.Area",
+			[]string{"func (c *Circle) Area() float64", "Example:\n", "// Output: 12.57"}},
+		{"example.com/shapes#Sum", "shapes.Sum", "func", "Sum",
+			[]string{"func Sum(circles ...*Circle) float64", "adds up the areas"}},
+		{"example.com/shapes/geom#Point", "geom.Point", "type", "Point",
+			[]string{"type Point struct"}},
+	}
+	for _, tt := range tests {
+		page, ok := byURL[tt.url]
+		if !ok {
+			t.Errorf("no page for %s", tt.url)
+			continue
+		}
+		if page.Title != tt.title || page.Metadata["kind"] != tt.kind || page.Metadata["symbol"] != tt.symbol {
+			t.Errorf("%s: title %q, metadata %v; want %q, kind %s, symbol %q", tt.url, page.Title, page.Metadata, tt.title, tt.kind, tt.symbol)
+		}
+		content := strings.Join(page.MainContent, "\n")
+		for _, want := range tt.content {
+			if !strings.Contains(content, want) {
+				t.Errorf("%s: content lacks %q:\n%s", tt.url, want, content)
+			}
+		}
+	}
+
+	// Function bodies stay out of the signatures
+	if content := strings.Join(byURL["example.com/shapes#Circle.Area"].MainContent, "\n"); strings.Contains(content, "math.Pi") {
+		t.Errorf("method page shows its body:\n%s", content)
+	}
+
+	// Commands, nested modules and ignored directories are skipped
+	for _, page := range pages {
+		if strings.Contains(page.URL, "nested") || strings.Contains(page.URL, "cmd") || strings.Contains(page.URL, "scratch") {
+			t.Errorf("indexed %s", page.URL)
+		}
+	}
+	if len(pages) != 7 {
+		t.Errorf("got %d pages, want 7 (two packages, two types, two funcs, one method)", len(pages))
+	}
+}
+
+func TestLoadGoDocStandardLibrary(t *testing.T) {
+	// GOROOT/src declares "module std" and its packages have no prefix
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":                "module std\n",
+		"strings/strings.go":    "// Package strings manipulates strings.\npackage strings\n\n// Clone copies s.\nfunc Clone(s string) string { return s }\n",
+		"net/textproto/conn.go": "// Package textproto speaks text protocols.\npackage textproto\n",
+	})
+
+	pages, err := LoadGoDoc(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := make(map[string]bool)
+	for _, page := range pages {
+		urls[page.URL] = true
+	}
+	for _, want := range []string{"strings", "strings#Clone", "net/textproto"} {
+		if !urls[want] {
+			t.Errorf("no page for %s; got %v", want, urls)
+		}
+	}
+}
+
+func TestLoadGoDocNeedsModule(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGoDoc(dir); err == nil {
+		t.Error("loaded a directory without go.mod")
+	}
+}
@@ -0,0 +1,77 @@
+package ingest
+
+import (
+	"strings"
+
+	"ollama_go/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Selectors for the main content area and the elements extracted from it
+const (
+	mainSelector    = "main, article, .Documentation-content, .SearchResults"
+	elementSelector = "h1, h2, h3, h4, p, pre, code, li"
+	maxMainElements = 20
+)
+
+// boilerplate is generic footer text skipped during extraction
+var boilerplate = []string{
+	"Common problems companies solve",
+	"Learn and network with Go",
+	"Meet other local Go developers",
+}
+
+// ExtractHTML extracts the title, meta description and MAIN content of an
+// HTML document (skipping navigation and footer). It is shared by the
+// crawler and local file ingestion.
+func ExtractHTML(root *goquery.Selection, pageURL string) *models.PageContent {
+	pageContent := &models.PageContent{
+		URL:         pageURL,
+		Title:       strings.TrimSpace(root.Find("title").Text()),
+		MainContent: make([]string, 0),
+	}
+
+	// Meta description
+	root.Find("meta[name='description']").Each(func(_ int, s *goquery.Selection) {
+		if desc, exists := s.Attr("content"); exists {
+			pageContent.Description = desc
+		}
+	})
+
+	// Try to get main content area
+	root.Find(mainSelector).Each(func(_ int, mainEl *goquery.Selection) {
+		pageContent.MainContent = append(pageContent.MainContent, extractElements(mainEl)...)
+	})
+
+	// Local files often have no <main>; fall back to the body
+	if len(pageContent.MainContent) == 0 {
+		pageContent.MainContent = extractElements(root.Find("body"))
+	}
+
+	pageContent.LinkCount = root.Find("a[href]").Length()
+
+	return pageContent
+}
+
+// extractElements collects the text of content elements under sel
+func extractElements(sel *goquery.Selection) []string {
+	texts := make([]string, 0)
+	sel.Find(elementSelector).Each(func(_ int, el *goquery.Selection) {
+		text := strings.TrimSpace(el.Text())
+		// Skip very short text and generic footer text
+		if len(texts) < maxMainElements && len(text) > 20 && !isBoilerplate(text) {
+			texts = append(texts, text)
+		}
+	})
+	return texts
+}
+
+func isBoilerplate(text string) bool {
+	for _, b := range boilerplate {
+		if strings.Contains(text, b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// IgnoreMatcher matches slash-separated relative paths against
+// .gitignore-style patterns. Later patterns take precedence, "!" negates a
+// pattern, a trailing "/" only matches directories and a pattern containing
+// a "/" is anchored to the root being walked, or to the directory of the
+// ignore file it was read from.
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	base    string // directory the pattern applies below, "" for the root
+}
+
+// NewIgnoreMatcher creates a matcher from a list of patterns
+func NewIgnoreMatcher(patterns []string) *IgnoreMatcher {
+	m := &IgnoreMatcher{}
+	for _, p := range patterns {
+		m.Add(p)
+	}
+	return m
+}
+
+// Add adds a single pattern; blank lines and comments are ignored
+func (m *IgnoreMatcher) Add(pattern string) {
+	m.add(pattern, "")
+}
+
+func (m *IgnoreMatcher) add(pattern, base string) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr := globToRegexp(pattern)
+	if !anchored {
+		expr = "(.*/)?" + expr
+	}
+	rule.re = regexp.MustCompile("^" + expr + "$")
+
+	m.rules = append(m.rules, rule)
+}
+
+// AddFile adds every pattern in a .gitignore-style file. A missing file is not an error.
+func (m *IgnoreMatcher) AddFile(path string) error {
+	return m.AddFileIn(path, "")
+}
+
+// AddFileIn adds the patterns of an ignore file found in dir, a slash-separated
+// path relative to the root. Like a nested .gitignore, they only apply to
+// paths below dir and are anchored to it.
+func (m *IgnoreMatcher) AddFileIn(path, dir string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open ignore file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m.add(scanner.Text(), dir)
+	}
+	return scanner.Err()
+}
+
+// Match reports whether relPath should be excluded
+func (m *IgnoreMatcher) Match(relPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		path := relPath
+		if rule.base != "" {
+			var ok bool
+			if path, ok = strings.CutPrefix(relPath, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if rule.re.MatchString(path) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// MatchFile reports whether the file at relPath should be excluded, either
+// itself or because one of its directories is, as when walking to it
+func (m *IgnoreMatcher) MatchFile(relPath string) bool {
+	for i := range len(relPath) {
+		if relPath[i] == '/' && m.Match(relPath[:i], true) {
+			return true
+		}
+	}
+	return m.Match(relPath, false)
+}
+
+// globToRegexp converts a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**/") {
+				b.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(glob[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	return b.String()
+}
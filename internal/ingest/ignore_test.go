@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := NewIgnoreMatcher([]string{
+		"# comment",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/docs/draft.md",
+		"**/tmp/**",
+	})
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false}, // a file named build is not a directory
+		{"src/build", true, true},
+		{"docs/draft.md", false, true},
+		{"blog/docs/draft.md", false, false}, // anchored to the root
+		{"a/tmp/b/c.md", false, true},
+		{"README.md", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreMatcherMatchFile(t *testing.T) {
+	m := NewIgnoreMatcher([]string{"vendor/", "/docs/internal"})
+	tests := map[string]bool{
+		"vendor/lib/README.md": true,
+		"docs/internal/a.md":   true,
+		"docs/public/a.md":     false,
+		"src/docs/internal.md": false,
+	}
+	for path, want := range tests {
+		if got := m.MatchFile(path); got != want {
+			t.Errorf("MatchFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestIgnoreMatcherNestedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gitignore")
+	if err := os.WriteFile(path, []byte("*.tmp\n/generated.md\n!important.tmp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewIgnoreMatcher([]string{"*.bak"})
+	if err := m.AddFileIn(path, "sub"); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"sub/a.tmp":          true,
+		"sub/deep/a.tmp":     true,
+		"sub/important.tmp":  false,
+		"sub/generated.md":   true,
+		"sub/x/generated.md": false, // anchored to sub
+		"other/a.tmp":        false, // only applies below sub
+		"other/generated.md": false,
+		"sub/notes.bak":      true,
+		"subdirectory/a.tmp": false,
+	}
+	for p, want := range tests {
+		if got := m.Match(p, false); got != want {
+			t.Errorf("Match(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
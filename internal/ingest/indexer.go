@@ -0,0 +1,59 @@
+package ingest
+
+import (
+	"context"
+
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/models"
+	"ollama_go/internal/store"
+)
+
+// Indexer embeds a batch of already extracted pages and saves them to the
+// document store by running them through a Pipeline
+type Indexer struct {
+	embedder   embedding.Embedder
+	docStore   store.Store
+	numWorkers int
+	pageDone   func(page *models.PageContent)
+}
+
+// NewIndexer creates a new indexer with the given number of embedding workers
+func NewIndexer(embedder embedding.Embedder, docStore store.Store, numWorkers int) *Indexer {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	return &Indexer{
+		embedder:   embedder,
+		docStore:   docStore,
+		numWorkers: numWorkers,
+	}
+}
+
+// OnPageIndexed registers a callback invoked once every chunk of a page has
+// been embedded and saved
+func (ix *Indexer) OnPageIndexed(f func(page *models.PageContent)) {
+	ix.pageDone = f
+}
+
+// Index splits long pages into chunks, generates embeddings for all of them
+// in parallel batches and saves them. It returns the number of documents saved.
+func (ix *Indexer) Index(ctx context.Context, pages []*models.PageContent) int {
+	cfg := DefaultPipelineConfig()
+	cfg.EmbedWorkers = ix.numWorkers
+
+	pipeline := NewPipeline(ix.embedder, ix.docStore, cfg)
+	pipeline.OnPageIndexed(ix.pageDone)
+	pipeline.Start(ctx)
+
+	for _, page := range pages {
+		if err := pipeline.SubmitPage(ctx, page); err != nil {
+			break
+		}
+	}
+	pipeline.Close()
+	pipeline.Wait()
+
+	pipeline.PrintProgress()
+	pipeline.PrintThroughput()
+	return pipeline.Saved()
+}
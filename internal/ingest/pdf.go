@@ -0,0 +1,74 @@
+package ingest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"ollama_go/internal/models"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// loadPDF extracts text page by page; each PDF page becomes its own page with
+// a "#page=N" URL fragment, "page" metadata and a title such as
+// "spec.pdf p.14" so answers can cite it. The document title from the PDF
+// metadata, if any, becomes the description.
+// Encrypted files are opened with the empty password when possible; files
+// without any extractable text (e.g. scanned images) yield no pages.
+func loadPDF(path string, data []byte) ([]*models.PageContent, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if errors.Is(err, pdf.ErrInvalidPassword) {
+		return nil, fmt.Errorf("PDF is password-protected")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !reader.Trailer().Key("Encrypt").IsNull() {
+		fmt.Printf("⚠️  %s is encrypted; opened with the empty user password\n", path)
+	}
+
+	title := strings.TrimSpace(reader.Trailer().Key("Info").Key("Title").Text())
+	name := filepath.Base(path)
+
+	numPages := reader.NumPage()
+	pages := make([]*models.PageContent, 0, numPages)
+	for n := 1; n <= numPages; n++ {
+		page := reader.Page(n)
+		if page.V.IsNull() {
+			continue
+		}
+
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			fmt.Printf("⚠️  %s p.%d: could not extract text: %v\n", name, n, err)
+			continue
+		}
+
+		content := paragraphs(text)
+		if len(content) == 0 {
+			continue
+		}
+
+		pages = append(pages, &models.PageContent{
+			URL:         fileURL(path) + "#page=" + strconv.Itoa(n),
+			Title:       fmt.Sprintf("%s p.%d", name, n),
+			Description: title,
+			MainContent: content,
+			Metadata: map[string]string{
+				"page":  strconv.Itoa(n),
+				"pages": strconv.Itoa(numPages),
+			},
+		})
+	}
+
+	if len(pages) == 0 {
+		fmt.Printf("⚠️  %s has no extractable text (%d pages); it may be scanned images and needs OCR\n", path, numPages)
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,94 @@
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a minimal PDF with one page per text; an empty text makes
+// a page without any text, like a scanned image
+func buildPDF(title string, texts ...string) []byte {
+	var objects []string
+	kids := make([]string, len(texts))
+	for i := range texts {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(texts)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i, text := range texts {
+		var stream string
+		if text != "" {
+			stream = fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		}
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+	info := len(objects) + 1
+	objects = append(objects, fmt.Sprintf("<< /Title (%s) >>", title))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, info, xref)
+	return b.Bytes()
+}
+
+func TestLoadPDFSplitsPages(t *testing.T) {
+	data := buildPDF("Design Spec", "Goroutines are cheap.", "", "Channels synchronize.")
+	pages, err := loadPDF("docs/spec.pdf", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The page without text is skipped, the others keep their number
+	if len(pages) != 2 {
+		t.Fatalf("extracted %d pages, want 2", len(pages))
+	}
+	for i, want := range []struct{ page, text string }{{"1", "Goroutines are cheap."}, {"3", "Channels synchronize."}} {
+		page := pages[i]
+		if page.Metadata["page"] != want.page || page.Metadata["pages"] != "3" {
+			t.Errorf("page %d has metadata %v, want page %s of 3", i, page.Metadata, want.page)
+		}
+		if !strings.HasSuffix(page.URL, "/docs/spec.pdf#page="+want.page) {
+			t.Errorf("page %d has URL %s", i, page.URL)
+		}
+		if page.Title != "spec.pdf p."+want.page || page.Description != "Design Spec" {
+			t.Errorf("page %d has title %q and description %q, want spec.pdf p.%s and the PDF title", i, page.Title, page.Description, want.page)
+		}
+		if got := strings.Join(page.MainContent, " "); !strings.Contains(got, want.text) {
+			t.Errorf("page %d has content %q, want %q", i, got, want.text)
+		}
+	}
+}
+
+func TestLoadPDFWithoutText(t *testing.T) {
+	pages, err := loadPDF("scan.pdf", buildPDF("Scan", "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 0 {
+		t.Errorf("extracted %d pages from a PDF without text", len(pages))
+	}
+}
+
+func TestLoadPDFRejectsGarbage(t *testing.T) {
+	if _, err := loadPDF("broken.pdf", []byte("not a pdf")); err == nil {
+		t.Error("loaded a file that is not a PDF")
+	}
+}
@@ -0,0 +1,494 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/models"
+	"ollama_go/internal/resilience"
+	"ollama_go/internal/store"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+)
+
+// PipelineConfig sets the worker count of each stage and the capacity of the
+// bounded channels between them. A full channel blocks the stage before it,
+// so a slow embedder throttles extraction and, in turn, fetching.
+type PipelineConfig struct {
+	ExtractWorkers   int
+	ChunkWorkers     int
+	EmbedWorkers     int
+	StoreWorkers     int
+	Buffer           int
+	MaxBatch         int           // upper bound of the adaptive embedding batch size
+	TargetLatency    time.Duration // batches slower than this shrink the batch size
+	BatchLinger      time.Duration // how long to wait for a batch to fill up
+	ProgressInterval time.Duration // 0 disables periodic progress output
+}
+
+// DefaultPipelineConfig returns the worker counts used by the crawler
+func DefaultPipelineConfig() PipelineConfig {
+	return PipelineConfig{
+		ExtractWorkers:   2,
+		ChunkWorkers:     1,
+		EmbedWorkers:     3,
+		StoreWorkers:     1,
+		Buffer:           16,
+		MaxBatch:         32,
+		TargetLatency:    5 * time.Second,
+		BatchLinger:      100 * time.Millisecond,
+		ProgressInterval: 10 * time.Second,
+	}
+}
+
+// StageStats is a snapshot of one stage's progress counters
+type StageStats struct {
+	Name   string
+	In     int64 // items received
+	Out    int64 // items passed downstream (or stored)
+	Failed int64 // items dropped because of an error
+	Queued int   // items waiting in the stage's input channel
+}
+
+// stage holds the counters of one pipeline stage
+type stage struct {
+	name   string
+	in     atomic.Int64
+	out    atomic.Int64
+	failed atomic.Int64
+	queued func() int
+}
+
+func (s *stage) stats() StageStats {
+	return StageStats{
+		Name:   s.name,
+		In:     s.in.Load(),
+		Out:    s.out.Load(),
+		Failed: s.failed.Load(),
+		Queued: s.queued(),
+	}
+}
+
+// htmlJob is a fetched HTML page waiting for extraction
+type htmlJob struct {
+	url string
+	dom *goquery.Selection
+}
+
+// chunkJob is one chunk of a source page
+type chunkJob struct {
+	source *models.PageContent
+	chunk  *models.PageContent
+}
+
+// embeddedJob is a chunk turned into a document with its embedding, or with
+// a nil doc a chunk whose embedding failed
+type embeddedJob struct {
+	source *models.PageContent
+	doc    *models.Document
+}
+
+// pageProgress collects the embedded chunks of a source page until every
+// chunk has arrived, so the page is saved in one batch
+type pageProgress struct {
+	remaining int
+	docs      []*models.Document
+	failed    bool
+}
+
+// Pipeline streams pages through extract → chunk → embed → store stages
+// connected by bounded channels. Fetching happens upstream (e.g. in the
+// crawler), which hands pages over with SubmitHTML or SubmitPage.
+type Pipeline struct {
+	cfg        PipelineConfig
+	chunker    *Chunker
+	embedder   embedding.Embedder
+	docStore   store.Store
+	sizer      *batchSizer
+	throughput *throughput
+
+	htmlIn   chan htmlJob
+	pagesIn  chan *models.PageContent
+	chunks   chan chunkJob
+	embedded chan embeddedJob
+
+	extract, chunk, embed, save *stage
+
+	// pages tracks the chunks of each source page still being embedded
+	pagesMux sync.Mutex
+	pages    map[*models.PageContent]*pageProgress
+
+	pageExtracted func(page *models.PageContent)
+	pageDone      func(page *models.PageContent)
+
+	extractWG *sync.WaitGroup
+	done      chan struct{}
+}
+
+// NewPipeline creates a pipeline; call Start before submitting pages
+func NewPipeline(embedder embedding.Embedder, docStore store.Store, cfg PipelineConfig) *Pipeline {
+	cfg.ExtractWorkers = max(cfg.ExtractWorkers, 1)
+	cfg.ChunkWorkers = max(cfg.ChunkWorkers, 1)
+	cfg.EmbedWorkers = max(cfg.EmbedWorkers, 1)
+	cfg.StoreWorkers = max(cfg.StoreWorkers, 1)
+
+	p := &Pipeline{
+		cfg:        cfg,
+		chunker:    NewChunker(),
+		embedder:   embedder,
+		docStore:   docStore,
+		sizer:      newBatchSizer(cfg.MaxBatch, cfg.TargetLatency),
+		throughput: &throughput{},
+		htmlIn:     make(chan htmlJob, cfg.Buffer),
+		pagesIn:    make(chan *models.PageContent, cfg.Buffer),
+		chunks:     make(chan chunkJob, cfg.Buffer),
+		embedded:   make(chan embeddedJob, cfg.Buffer),
+		pages:      make(map[*models.PageContent]*pageProgress),
+		done:       make(chan struct{}),
+	}
+
+	p.extract = &stage{name: "extract", queued: func() int { return len(p.htmlIn) }}
+	p.chunk = &stage{name: "chunk", queued: func() int { return len(p.pagesIn) }}
+	p.embed = &stage{name: "embed", queued: func() int { return len(p.chunks) }}
+	p.save = &stage{name: "store", queued: func() int { return len(p.embedded) }}
+
+	return p
+}
+
+// OnPageExtracted registers a callback invoked for every page extracted from HTML
+func (p *Pipeline) OnPageExtracted(f func(page *models.PageContent)) {
+	p.pageExtracted = f
+}
+
+// OnPageIndexed registers a callback invoked once every chunk of a page has
+// been embedded and saved
+func (p *Pipeline) OnPageIndexed(f func(page *models.PageContent)) {
+	p.pageDone = f
+}
+
+// Start launches the stage workers. Cancelling ctx stops every stage; pages
+// still in flight are simply never reported as indexed.
+func (p *Pipeline) Start(ctx context.Context) {
+	// extract: HTML → page
+	p.extractWG = runStage(p.cfg.ExtractWorkers, func(int) {
+		for job := range receive(ctx, p.htmlIn) {
+			p.extract.in.Add(1)
+			page := ExtractHTML(job.dom, job.url)
+			if p.pageExtracted != nil {
+				p.pageExtracted(page)
+			}
+			if !send(ctx, p.pagesIn, page) {
+				return
+			}
+			p.extract.out.Add(1)
+		}
+	})
+
+	// chunk: page → chunks
+	chunkWG := runStage(p.cfg.ChunkWorkers, func(int) {
+		for page := range receive(ctx, p.pagesIn) {
+			p.chunk.in.Add(1)
+			pieces := p.chunker.ChunkPage(page)
+
+			p.pagesMux.Lock()
+			p.pages[page] = &pageProgress{remaining: len(pieces)}
+			p.pagesMux.Unlock()
+
+			for _, piece := range pieces {
+				if !send(ctx, p.chunks, chunkJob{source: page, chunk: piece}) {
+					return
+				}
+				p.chunk.out.Add(1)
+			}
+		}
+	})
+
+	// embed: chunks → documents with embeddings, in adaptively sized batches
+	embedWG := runStage(p.cfg.EmbedWorkers, func(workerID int) {
+		jobs := receive(ctx, p.chunks)
+		for {
+			batch, ok := collectBatch(jobs, p.sizer.Size(), p.cfg.BatchLinger)
+			if !ok {
+				return
+			}
+			p.embed.in.Add(int64(len(batch)))
+
+			texts := make([]string, len(batch))
+			for i, job := range batch {
+				texts[i] = p.embeddingText(job.chunk)
+			}
+
+			fmt.Printf("\n📊 [Worker %d] Generating %d embeddings for: %s\n", workerID, len(batch), batch[0].chunk.Title)
+
+			start := time.Now()
+			results := embedBatch(ctx, p.embedder, p.sizer, texts)
+			embedded := make([]string, 0, len(texts))
+			for i, r := range results {
+				if r.err == nil {
+					embedded = append(embedded, texts[i])
+				}
+			}
+			p.throughput.Record(embedded, time.Since(start))
+
+			hinted := false
+			for i, job := range batch {
+				pageContent := job.chunk
+				if err := results[i].err; err != nil {
+					if ctx.Err() == nil {
+						log.Printf("⚠️  Error generating embedding for %s: %v\n", pageContent.URL, err)
+						if hint := resilience.Hint(err); hint != "" && !hinted {
+							log.Printf("💡 %s\n", hint)
+							hinted = true
+						}
+					}
+					p.embed.failed.Add(1)
+					if !send(ctx, p.embedded, embeddedJob{source: job.source}) {
+						return
+					}
+					continue
+				}
+
+				doc := &models.Document{
+					ID:          DocumentID(pageContent.URL, pageContent.Metadata["chunk"]),
+					URL:         pageContent.URL,
+					Title:       pageContent.Title,
+					Description: pageContent.Description,
+					Content:     strings.Join(pageContent.MainContent, "\n"),
+					Embedding:   results[i].vector,
+					CreatedAt:   time.Now(),
+					Metadata:    pageContent.Metadata,
+				}
+				if !send(ctx, p.embedded, embeddedJob{source: job.source, doc: doc}) {
+					return
+				}
+				p.embed.out.Add(1)
+			}
+		}
+	})
+
+	// store: documents → DocumentStore, one batch per source page
+	storeWG := runStage(p.cfg.StoreWorkers, func(workerID int) {
+		for job := range receive(ctx, p.embedded) {
+			if job.doc != nil {
+				p.save.in.Add(1)
+			}
+			docs, complete, failed := p.collectPage(job)
+			if !complete || len(docs) == 0 {
+				continue
+			}
+
+			if err := p.docStore.ReplaceURL(job.source.URL, docs); err != nil {
+				log.Printf("⚠️  Error saving documents of %s: %v\n", job.source.URL, err)
+				p.save.failed.Add(int64(len(docs)))
+				continue
+			}
+			p.save.out.Add(int64(len(docs)))
+
+			fmt.Printf("✅ [Worker %d] Embeddings generated (dim: %d) and %d documents saved\n",
+				workerID, len(docs[0].Embedding), len(docs))
+
+			// A page with a failed chunk is not indexed, so it is retried
+			if !failed && p.pageDone != nil {
+				p.pageDone(job.source)
+			}
+		}
+	})
+
+	// Close each channel once the stage feeding it has finished; pagesIn is
+	// closed by Close since SubmitPage feeds it as well as the extract stage
+	go func() {
+		chunkWG.Wait()
+		close(p.chunks)
+	}()
+	go func() {
+		embedWG.Wait()
+		close(p.embedded)
+	}()
+
+	go func() {
+		storeWG.Wait()
+		close(p.done)
+	}()
+
+	if p.cfg.ProgressInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(p.cfg.ProgressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					p.PrintProgress()
+				case <-p.done:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// DocumentID derives the ID of a chunk from its page URL and its "chunk"
+// metadata, empty for a page of one chunk, so indexing a page again replaces
+// its documents instead of adding new ones
+func DocumentID(url, chunk string) string {
+	if chunk == "" {
+		chunk = "0"
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(url+"#chunk="+chunk)).String()
+}
+
+// collectPage adds a chunk to its source page. Once every chunk of the page
+// has arrived it returns the embedded ones, and whether any failed.
+func (p *Pipeline) collectPage(job embeddedJob) (docs []*models.Document, complete, failed bool) {
+	p.pagesMux.Lock()
+	defer p.pagesMux.Unlock()
+
+	page := p.pages[job.source]
+	if job.doc != nil {
+		page.docs = append(page.docs, job.doc)
+	} else {
+		page.failed = true
+	}
+	page.remaining--
+	if page.remaining > 0 {
+		return nil, false, false
+	}
+	delete(p.pages, job.source)
+	return page.docs, true, page.failed
+}
+
+// SubmitHTML hands a fetched HTML page to the extract stage. It blocks while
+// the stage is full and returns an error once ctx is cancelled.
+func (p *Pipeline) SubmitHTML(ctx context.Context, url string, dom *goquery.Selection) error {
+	if !send(ctx, p.htmlIn, htmlJob{url: url, dom: dom}) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// SubmitPage hands already extracted page content to the chunk stage
+func (p *Pipeline) SubmitPage(ctx context.Context, page *models.PageContent) error {
+	if !send(ctx, p.pagesIn, page) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Close signals that no more pages will be submitted
+func (p *Pipeline) Close() {
+	close(p.htmlIn)
+	go func() {
+		p.extractWG.Wait()
+		close(p.pagesIn)
+	}()
+}
+
+// Wait blocks until every stage has drained (or stopped after cancellation)
+func (p *Pipeline) Wait() {
+	<-p.done
+}
+
+// Progress returns a snapshot of every stage's counters
+func (p *Pipeline) Progress() []StageStats {
+	return []StageStats{p.extract.stats(), p.chunk.stats(), p.embed.stats(), p.save.stats()}
+}
+
+// Saved returns the number of documents written to the store
+func (p *Pipeline) Saved() int {
+	return int(p.save.out.Load())
+}
+
+// PrintThroughput writes the embedding throughput of the run
+func (p *Pipeline) PrintThroughput() {
+	p.throughput.Print()
+}
+
+// PrintProgress writes one line with the counters of every stage
+func (p *Pipeline) PrintProgress() {
+	parts := make([]string, 0, 4)
+	for _, s := range p.Progress() {
+		part := fmt.Sprintf("%s %d/%d", s.Name, s.Out, s.In)
+		if s.Failed > 0 {
+			part += fmt.Sprintf(" (%d failed)", s.Failed)
+		}
+		if s.Queued > 0 {
+			part += fmt.Sprintf(" [%d queued]", s.Queued)
+		}
+		parts = append(parts, part)
+	}
+	fmt.Printf("\n📈 Pipeline: %s\n", strings.Join(parts, " | "))
+}
+
+// embeddingText combines title, description, and content for embedding
+func (p *Pipeline) embeddingText(pageContent *models.PageContent) string {
+	return EmbeddingText(pageContent.Title, pageContent.Description, pageContent.MainContent)
+}
+
+// maxEmbeddingChars truncates the text sent to the embedding model
+const maxEmbeddingChars = 2000
+
+// EmbeddingText is the text embedded for a chunk, so a document can be
+// re-embedded exactly as the pipeline embedded it
+func EmbeddingText(title, description string, paragraphs []string) string {
+	combinedText := fmt.Sprintf("%s. %s. %s", title, description, strings.Join(paragraphs, " "))
+
+	// Truncate if too long
+	if len(combinedText) > maxEmbeddingChars {
+		combinedText = combinedText[:maxEmbeddingChars]
+	}
+	return combinedText
+}
+
+// runStage starts n workers and returns a WaitGroup that completes when all
+// of them return; the caller closes the stage's output channel afterwards
+func runStage(n int, work func(workerID int)) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			work(workerID)
+		}(w)
+	}
+	return wg
+}
+
+// receive yields items from in until it is closed or ctx is cancelled
+func receive[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// send delivers item unless ctx is cancelled first
+func send[T any](ctx context.Context, out chan<- T, item T) bool {
+	select {
+	case out <- item:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,262 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/models"
+	"ollama_go/internal/store"
+)
+
+// countingStore records the batches saved to a store
+type countingStore struct {
+	store.Store
+	mu      sync.Mutex
+	batches map[string][]int // sizes of the batches saved, by URL
+}
+
+func (s *countingStore) ReplaceURL(url string, docs []*models.Document) error {
+	s.mu.Lock()
+	s.batches[url] = append(s.batches[url], len(docs))
+	s.mu.Unlock()
+	return s.Store.ReplaceURL(url, docs)
+}
+
+// failingEmbedder fails every batch holding a text containing "poison"
+type failingEmbedder struct {
+	*embedding.Hash
+}
+
+func (e failingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	for _, text := range texts {
+		if strings.Contains(text, "poison") {
+			return nil, fmt.Errorf("invalid input")
+		}
+	}
+	return e.Hash.EmbedBatch(ctx, texts)
+}
+
+// longPage returns a page that the default chunker splits into several chunks
+func longPage(url string, poisoned bool) *models.PageContent {
+	var paragraphs []string
+	for i := range 8 {
+		paragraphs = append(paragraphs, fmt.Sprintf("Paragraph %d of %s. %s", i, url, strings.Repeat("words ", 80)))
+	}
+	if poisoned {
+		paragraphs[7] = "poison " + paragraphs[7]
+	}
+	return &models.PageContent{URL: url, Title: url, MainContent: paragraphs}
+}
+
+func TestPipelineSavesEachPageOnce(t *testing.T) {
+	docStore := &countingStore{Store: store.NewDocumentStoreAt(t.TempDir()), batches: make(map[string][]int)}
+	cfg := DefaultPipelineConfig()
+	cfg.EmbedWorkers, cfg.StoreWorkers, cfg.ProgressInterval = 3, 2, 0
+	p := NewPipeline(failingEmbedder{embedding.NewHash(16)}, docStore, cfg)
+
+	var mu sync.Mutex
+	indexed := make(map[string]bool)
+	p.OnPageIndexed(func(page *models.PageContent) {
+		mu.Lock()
+		indexed[page.URL] = true
+		mu.Unlock()
+	})
+
+	ctx := context.Background()
+	p.Start(ctx)
+	pages := map[string]*models.PageContent{
+		"https://go.dev/a":      longPage("https://go.dev/a", false),
+		"https://go.dev/b":      longPage("https://go.dev/b", false),
+		"https://go.dev/broken": longPage("https://go.dev/broken", true),
+	}
+	for _, page := range pages {
+		if err := p.SubmitPage(ctx, page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Close()
+	p.Wait()
+
+	chunker := NewChunker()
+	for url, page := range pages {
+		chunks := len(chunker.ChunkPage(page))
+		if chunks < 2 {
+			t.Fatalf("%s has %d chunks, want several", url, chunks)
+		}
+		batches := docStore.batches[url]
+		if url == "https://go.dev/broken" {
+			if indexed[url] {
+				t.Errorf("page with a failed chunk reported as indexed")
+			}
+			if len(batches) != 1 || batches[0] != chunks-1 {
+				t.Errorf("%s saved in batches %v, want its %d good chunks at once", url, batches, chunks-1)
+			}
+			continue
+		}
+		if !indexed[url] {
+			t.Errorf("%s not reported as indexed", url)
+		}
+		if len(batches) != 1 || batches[0] != chunks {
+			t.Errorf("%s saved in batches %v, want all %d chunks at once", url, batches, chunks)
+		}
+	}
+	if saved := p.Saved(); saved != len(docStore.GetAllDocuments()) {
+		t.Errorf("Saved() = %d, store holds %d", saved, len(docStore.GetAllDocuments()))
+	}
+}
+
+func TestPipelineReplacesReindexedPages(t *testing.T) {
+	docStore := store.NewDocumentStoreAt(t.TempDir())
+	cfg := DefaultPipelineConfig()
+	cfg.ProgressInterval = 0
+	index := func(page *models.PageContent) {
+		p := NewPipeline(embedding.NewHash(16), docStore, cfg)
+		p.Start(context.Background())
+		if err := p.SubmitPage(context.Background(), page); err != nil {
+			t.Fatal(err)
+		}
+		p.Close()
+		p.Wait()
+	}
+
+	long := longPage("https://go.dev/a", false)
+	index(long)
+	if n := len(docStore.GetAllDocuments()); n != len(NewChunker().ChunkPage(long)) {
+		t.Fatalf("stored %d documents for the long page", n)
+	}
+
+	// The page shrank to one chunk
+	index(&models.PageContent{URL: "https://go.dev/a", Title: "a", MainContent: []string{"Short now."}})
+	docs := docStore.GetAllDocuments()
+	if len(docs) != 1 || docs[0].Content != "Short now." {
+		t.Fatalf("after indexing the page again the store holds %d documents", len(docs))
+	}
+	if docs[0].ID != DocumentID("https://go.dev/a", "") || docs[0].Version != 2 {
+		t.Errorf("reindexed chunk has ID %s, version %d; want the ID of chunk 0 at version 2", docs[0].ID, docs[0].Version)
+	}
+}
+
+// blockingEmbedder holds every batch until release is closed or the context
+// is cancelled
+type blockingEmbedder struct {
+	*embedding.Hash
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e blockingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	select {
+	case e.started <- struct{}{}:
+	default:
+	}
+	select {
+	case <-e.release:
+		return e.Hash.EmbedBatch(ctx, texts)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func newBlockingEmbedder() blockingEmbedder {
+	return blockingEmbedder{Hash: embedding.NewHash(16), started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+// shortPage returns a page of one chunk
+func shortPage(i int) *models.PageContent {
+	url := fmt.Sprintf("https://go.dev/%d", i)
+	return &models.PageContent{URL: url, Title: url, MainContent: []string{"Page " + url}}
+}
+
+func TestPipelineBackpressure(t *testing.T) {
+	docStore := store.NewDocumentStoreAt(t.TempDir())
+	cfg := PipelineConfig{Buffer: 1, MaxBatch: 1, TargetLatency: time.Minute}
+	embedder := newBlockingEmbedder()
+	p := NewPipeline(embedder, docStore, cfg)
+	ctx := context.Background()
+	p.Start(ctx)
+
+	const pages = 50
+	var submitted atomic.Int64
+	go func() {
+		for i := range pages {
+			if err := p.SubmitPage(ctx, shortPage(i)); err != nil {
+				t.Error(err)
+				return
+			}
+			submitted.Add(1)
+		}
+		p.Close()
+	}()
+
+	// While the embedder is stuck, the full channels block the submitter
+	<-embedder.started
+	time.Sleep(50 * time.Millisecond)
+	if n := submitted.Load(); n >= pages/2 {
+		t.Errorf("submitted %d of %d pages while the embedder was blocked", n, pages)
+	}
+
+	close(embedder.release)
+	p.Wait()
+	if n := len(docStore.GetAllDocuments()); n != pages {
+		t.Errorf("stored %d of %d pages once the embedder was released", n, pages)
+	}
+}
+
+func TestPipelineStopsOnCancel(t *testing.T) {
+	docStore := store.NewDocumentStoreAt(t.TempDir())
+	cfg := DefaultPipelineConfig()
+	cfg.Buffer, cfg.ProgressInterval = 1, 0
+	embedder := newBlockingEmbedder()
+	p := NewPipeline(embedder, docStore, cfg)
+
+	var indexed atomic.Int64
+	p.OnPageIndexed(func(*models.PageContent) { indexed.Add(1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	submitErr := make(chan error, 1)
+	go func() {
+		for i := 0; ; i++ {
+			if err := p.SubmitPage(ctx, shortPage(i)); err != nil {
+				submitErr <- err
+				return
+			}
+		}
+	}()
+
+	<-embedder.started
+	cancel()
+	select {
+	case err := <-submitErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("blocked submit returned %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("submit still blocked after cancellation")
+	}
+
+	p.Close()
+	waited := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline did not stop after cancellation")
+	}
+	if n := indexed.Load(); n != 0 {
+		t.Errorf("%d pages reported as indexed although no embedding finished", n)
+	}
+	if n := len(docStore.GetAllDocuments()); n != 0 {
+		t.Errorf("stored %d documents after cancellation", n)
+	}
+}
@@ -0,0 +1,9 @@
+// Command shapes prints an area.
+package main
+
+import "fmt"
+
+// Unexported commands have no API worth indexing.
+func main() {
+	fmt.Println(3.14)
+}
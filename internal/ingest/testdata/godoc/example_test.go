@@ -0,0 +1,17 @@
+package shapes_test
+
+import (
+	"fmt"
+
+	"example.com/shapes"
+)
+
+func Example() {
+	fmt.Println(shapes.Sum(shapes.NewCircle(1)) > 3)
+	// Output: true
+}
+
+func ExampleCircle_Area() {
+	fmt.Printf("%.2f\n", shapes.NewCircle(2).Area())
+	// Output: 12.57
+}
@@ -0,0 +1,7 @@
+// Package geom holds points in the plane.
+package geom
+
+// Point is a position in the plane.
+type Point struct {
+	X, Y float64
+}
@@ -0,0 +1,2 @@
+// Package scratch is ignored by the go tool.
+package scratch
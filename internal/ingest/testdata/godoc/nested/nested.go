@@ -0,0 +1,5 @@
+// Package nested is a separate module and must not be indexed.
+package nested
+
+// Hidden is documented by its own module.
+func Hidden() {}
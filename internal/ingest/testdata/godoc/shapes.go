@@ -0,0 +1,28 @@
+// Package shapes computes areas of plane figures.
+package shapes
+
+import "math"
+
+// Circle is a circle of a given radius.
+type Circle struct {
+	Radius float64
+}
+
+// NewCircle returns a circle of radius r.
+func NewCircle(r float64) *Circle {
+	return &Circle{Radius: r}
+}
+
+// Area returns the area of the circle.
+func (c *Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+// Sum adds up the areas of circles.
+func Sum(circles ...*Circle) float64 {
+	var total float64
+	for _, c := range circles {
+		total += c.Area()
+	}
+	return total
+}
@@ -11,14 +11,18 @@ type PageContent struct {
 	Description string   `json:"description"`
 	Title       string   `json:"title"`
 	URL         string   `json:"url"`
+	// Metadata carries source-specific attributes (e.g. package, symbol kind)
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type Document struct {
-	ID          string    `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	Embedding   []float32 `json:"embedding"`
-	Content     string    `json:"content"`
-	Description string    `json:"description"`
-	Title       string    `json:"title"`
-	URL         string    `json:"url"`
-}
\ No newline at end of file
+	ID          string            `json:"id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Embedding   []float32         `json:"embedding"`
+	Content     string            `json:"content"`
+	Description string            `json:"description"`
+	Title       string            `json:"title"`
+	URL         string            `json:"url"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Version     int64             `json:"version,omitempty"` // number of saves, starting at 1
+}
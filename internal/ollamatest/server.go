@@ -0,0 +1,305 @@
+// Package ollamatest provides a fake Ollama server for hermetic tests. It
+// speaks the /api/embed, /api/embeddings, /api/generate and /api/chat
+// endpoints (including streaming NDJSON), returns deterministic embeddings
+// and replies with scripted answers.
+package ollamatest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ollama_go/internal/embedding"
+)
+
+// DefaultReply is returned by generate and chat once the script is exhausted
+const DefaultReply = "I don't know."
+
+// Request is a request received by the server
+type Request struct {
+	Path   string
+	Model  string
+	Prompt string   // generate prompt, or the last chat message
+	Input  []string // embedding inputs
+}
+
+// failure is a scripted error response
+type failure struct {
+	status  int
+	message string
+}
+
+// Server is a fake Ollama server backed by httptest
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	embedder *embedding.Hash
+	models   map[string]bool // nil accepts every model
+	replies  []string
+	failures []failure
+	requests []Request
+}
+
+// New starts a fake server and points OLLAMA_HOST at it for the rest of the
+// test. Embeddings are produced by a hashing embedder of the given dimension,
+// so texts sharing words are similar and retrieval behaves sensibly.
+func New(t testing.TB, dimension int) *Server {
+	t.Helper()
+
+	s := &Server{embedder: embedding.NewHash(dimension)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/embed", s.handleEmbed)
+	mux.HandleFunc("POST /api/embeddings", s.handleEmbeddings)
+	mux.HandleFunc("POST /api/generate", s.handleGenerate)
+	mux.HandleFunc("POST /api/chat", s.handleChat)
+	s.Server = httptest.NewServer(mux)
+
+	t.Cleanup(s.Close)
+	t.Setenv("OLLAMA_HOST", s.URL)
+	return s
+}
+
+// SetModels restricts the server to the given models; requests for any other
+// model fail with Ollama's "model not found" error
+func (s *Server) SetModels(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = make(map[string]bool, len(names))
+	for _, name := range names {
+		s.models[name] = true
+	}
+}
+
+// Script queues replies for subsequent generate and chat requests, in order
+func (s *Server) Script(replies ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// Fail makes the next n requests (of any kind) fail with status and message
+func (s *Server) Fail(n, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, failure{status: status, message: message})
+	}
+}
+
+// Requests returns the requests received on path ("" for all paths)
+func (s *Server) Requests(path string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]Request, 0, len(s.requests))
+	for _, r := range s.requests {
+		if path == "" || r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// Embedding returns the vector the server produces for text
+func (s *Server) Embedding(text string) []float32 {
+	vec, _ := s.embedder.Embed(context.Background(), text)
+	return vec
+}
+
+// begin records a request and reports whether it should be answered; a
+// scripted failure or unknown model has already been written otherwise
+func (s *Server) begin(w http.ResponseWriter, req Request) bool {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	var fail *failure
+	if len(s.failures) > 0 {
+		fail = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	known := s.models == nil || s.models[req.Model]
+	s.mu.Unlock()
+
+	switch {
+	case fail != nil:
+		writeError(w, fail.status, fail.message)
+		return false
+	case !known:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found, try pulling it first", req.Model))
+		return false
+	}
+	return true
+}
+
+// nextReply pops the next scripted reply
+func (s *Server) nextReply() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.replies) == 0 {
+		return DefaultReply
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return reply
+}
+
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model string          `json:"model"`
+		Input json.RawMessage `json:"input"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	// input is either a string or a list of strings
+	var inputs []string
+	if err := json.Unmarshal(body.Input, &inputs); err != nil {
+		var single string
+		if err := json.Unmarshal(body.Input, &single); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid input")
+			return
+		}
+		inputs = []string{single}
+	}
+
+	if !s.begin(w, Request{Path: "/api/embed", Model: body.Model, Input: inputs}) {
+		return
+	}
+
+	embeddings := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		embeddings[i] = s.Embedding(input)
+	}
+	writeJSON(w, map[string]any{"model": body.Model, "embeddings": embeddings})
+}
+
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if !s.begin(w, Request{Path: "/api/embeddings", Model: body.Model, Input: []string{body.Prompt}}) {
+		return
+	}
+	writeJSON(w, map[string]any{"embedding": s.Embedding(body.Prompt)})
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+		Stream *bool  `json:"stream"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if !s.begin(w, Request{Path: "/api/generate", Model: body.Model, Prompt: body.Prompt}) {
+		return
+	}
+
+	s.respond(w, body.Stream, func(chunk string, done bool) any {
+		return map[string]any{
+			"model":      body.Model,
+			"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+			"response":   chunk,
+			"done":       done,
+		}
+	})
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		Stream *bool `json:"stream"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	var prompt string
+	if n := len(body.Messages); n > 0 {
+		prompt = body.Messages[n-1].Content
+	}
+	if !s.begin(w, Request{Path: "/api/chat", Model: body.Model, Prompt: prompt}) {
+		return
+	}
+
+	s.respond(w, body.Stream, func(chunk string, done bool) any {
+		return map[string]any{
+			"model":      body.Model,
+			"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+			"message":    map[string]string{"role": "assistant", "content": chunk},
+			"done":       done,
+		}
+	})
+}
+
+// respond writes the next reply, either as one object or (the default) as
+// NDJSON with one object per word followed by a final "done" object
+func (s *Server) respond(w http.ResponseWriter, stream *bool, object func(chunk string, done bool) any) {
+	reply := s.nextReply()
+
+	if stream != nil && !*stream {
+		writeJSON(w, object(reply, true))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for _, chunk := range splitWords(reply) {
+		enc.Encode(object(chunk, false))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	enc.Encode(object("", true))
+}
+
+// splitWords splits text into chunks that concatenate back to text
+func splitWords(text string) []string {
+	chunks := make([]string, 0)
+	for text != "" {
+		i := strings.IndexByte(text[1:], ' ')
+		if i < 0 {
+			chunks = append(chunks, text)
+			break
+		}
+		chunks = append(chunks, text[:i+1])
+		text = text[i+1:]
+	}
+	return chunks
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -3,10 +3,12 @@ package internal
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"ollama_go/internal/embedding"
 	"ollama_go/internal/models"
+	"ollama_go/internal/resilience"
 	"ollama_go/internal/store"
 
 	"github.com/tmc/langchaingo/llms"
@@ -15,59 +17,81 @@ import (
 
 // RAGService handles retrieval-augmented generation
 type RAGService struct {
-	llm        *ollama.LLM
-	embService *embedding.Service
-	docStore   *store.DocumentStore
-	topK       int
+	llm     *ollama.LLM
+	client  *resilience.Client
+	sources []Source
+	topK    int
 }
 
-// NewRAGService creates a new RAG service
-func NewRAGService(modelName string, docStore *store.DocumentStore, topK int) (*RAGService, error) {
-	llm, err := ollama.New(ollama.WithModel(modelName))
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize LLM: %w", err)
+// Source is a collection to retrieve from, with the embedder matching the
+// model it was indexed with. With Embedders set, the embedder is instead
+// picked by the model the store records at query time, so a live store that
+// was re-embedded and reloaded keeps working.
+type Source struct {
+	Name      string
+	Store     store.Store
+	Embedder  embedding.Embedder
+	Embedders *embedding.Set
+}
+
+// embedder returns the embedder for queries against the source
+func (s Source) embedder() (embedding.Embedder, error) {
+	if s.Embedders == nil {
+		return s.Embedder, nil
 	}
+	return s.Embedders.For(s.Store.Metadata().Model)
+}
 
-	embService, err := embedding.NewService(modelName)
+// NewRAGService creates a new RAG service. Queries are embedded with
+// embedder, which must match the embedder used to index docStore.
+func NewRAGService(modelName string, embedder embedding.Embedder, docStore store.Store, topK int) (*RAGService, error) {
+	return NewMultiRAGService(modelName, []Source{{Name: store.DefaultCollection, Store: docStore, Embedder: embedder}}, topK)
+}
+
+// NewMultiRAGService creates a RAG service that retrieves the topK best
+// documents across several collections
+func NewMultiRAGService(modelName string, sources []Source, topK int) (*RAGService, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no collections to query")
+	}
+	llm, err := ollama.New(ollama.WithModel(modelName))
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize embedding service: %w", err)
+		return nil, fmt.Errorf("failed to initialize LLM: %w", err)
 	}
 
 	return &RAGService{
-		llm:        llm,
-		embService: embService,
-		docStore:   docStore,
-		topK:       topK,
+		llm:     llm,
+		client:  resilience.NewClient(resilience.GeneratePolicy()),
+		sources: sources,
+		topK:    topK,
 	}, nil
 }
 
 // Query performs RAG: retrieves relevant documents and generates response
 func (r *RAGService) Query(ctx context.Context, query string, streamFunc func(string)) (string, error) {
-	// Generate embedding for the query
-	queryEmbedding, err := r.embService.GenerateEmbedding(ctx, query)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate query embedding: %w", err)
-	}
+	response, _, err := r.QueryWithSources(ctx, query, streamFunc)
+	return response, err
+}
 
+// QueryWithSources is Query that also returns the retrieved documents the
+// answer was generated from, in the order they were numbered in the prompt
+func (r *RAGService) QueryWithSources(ctx context.Context, query string, streamFunc func(string)) (string, []*models.Document, error) {
 	// Retrieve similar documents
-	similarDocs := r.docStore.SearchBySimilarity(queryEmbedding, r.topK)
+	similarDocs, err := r.GetRetrievedDocuments(ctx, query)
+	if err != nil {
+		return "", nil, err
+	}
 
 	if len(similarDocs) == 0 {
-		return "", fmt.Errorf("no relevant documents found")
+		return "", nil, fmt.Errorf("no relevant documents found")
 	}
 
 	// Build context from retrieved documents
-	contextParts := make([]string, 0, len(similarDocs))
-	for i, doc := range similarDocs {
-		contextParts = append(contextParts, fmt.Sprintf(
-			"[Document %d]\nTitle: %s\nURL: %s\nContent: %s\n",
-			i+1, doc.Title, doc.URL, doc.Content,
-		))
-	}
-	context1 := strings.Join(contextParts, "\n---\n\n")
+	context1 := BuildContext(similarDocs)
 
 	// Create augmented prompt
 	augmentedPrompt := fmt.Sprintf(`Based on the following context, answer the question.
+Cite the documents you use as [Document N].
 
 Context:
 %s
@@ -78,38 +102,123 @@ Question: %s
 
 Answer:`, context1, query)
 
-	// Generate response with streaming
+	// Generate response with streaming. Failed attempts are retried only
+	// until the first chunk has been streamed to the caller.
 	var responseBuilder strings.Builder
-	response, err := llms.GenerateFromSinglePrompt(
-		ctx,
-		r.llm,
-		augmentedPrompt,
-		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
-			text := string(chunk)
-			responseBuilder.WriteString(text)
-			if streamFunc != nil {
-				streamFunc(text)
-			}
-			return nil
-		}),
-	)
+	var response string
+	err = r.client.Do(ctx, "generate", func(ctx context.Context) error {
+		var err error
+		response, err = llms.GenerateFromSinglePrompt(
+			ctx,
+			r.llm,
+			augmentedPrompt,
+			llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+				text := string(chunk)
+				responseBuilder.WriteString(text)
+				if streamFunc != nil {
+					streamFunc(text)
+				}
+				return nil
+			}),
+		)
+		if err != nil && responseBuilder.Len() > 0 {
+			return resilience.Permanent(err)
+		}
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to generate response: %w", err)
+		return "", nil, fmt.Errorf("failed to generate response: %w", err)
 	}
 
 	if response == "" {
-		return responseBuilder.String(), nil
+		return responseBuilder.String(), similarDocs, nil
 	}
 
-	return response, nil
+	return response, similarDocs, nil
+}
+
+// BuildContext formats retrieved documents for a prompt, numbered from 1 so
+// answers can cite them as [Document N]
+func BuildContext(docs []*models.Document) string {
+	contextParts := make([]string, 0, len(docs))
+	for i, doc := range docs {
+		contextParts = append(contextParts, fmt.Sprintf(
+			"[Document %d]\nTitle: %s\nURL: %s\nContent: %s\n",
+			i+1, doc.Title, doc.URL, doc.Content,
+		))
+	}
+	return strings.Join(contextParts, "\n---\n\n")
 }
 
 // GetRetrievedDocuments returns the documents that would be retrieved for a query
 func (r *RAGService) GetRetrievedDocuments(ctx context.Context, query string) ([]*models.Document, error) {
-	queryEmbedding, err := r.embService.GenerateEmbedding(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	// Embed the query once per model, however many collections share it
+	embeddings := make(map[string][]float32)
+	results := make([][]store.ScoredDocument, len(r.sources))
+	modelIDs := make([]string, len(r.sources))
+	for i, source := range r.sources {
+		embedder, err := source.embedder()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize the embedder of %s: %w", source.Name, err)
+		}
+		modelIDs[i] = embedder.ModelID()
+		queryEmbedding, ok := embeddings[modelIDs[i]]
+		if !ok {
+			queryEmbedding, err = embedder.Embed(ctx, query)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+			}
+			embeddings[modelIDs[i]] = queryEmbedding
+		}
+		results[i] = source.Store.SearchWithScores(queryEmbedding, r.topK)
 	}
 
-	return r.docStore.SearchBySimilarity(queryEmbedding, r.topK), nil
+	return r.merge(results, modelIDs), nil
+}
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion
+const rrfK = 60
+
+// merge combines per-collection results into the overall topK. Scores are
+// only comparable when every collection uses the same model and metric;
+// otherwise the results are fused by rank (reciprocal rank fusion). modelIDs
+// holds the embedding model each collection was queried with.
+func (r *RAGService) merge(results [][]store.ScoredDocument, modelIDs []string) []*models.Document {
+	if len(results) == 1 {
+		docs := make([]*models.Document, len(results[0]))
+		for i, hit := range results[0] {
+			docs[i] = hit.Document
+		}
+		return docs
+	}
+
+	type candidate struct {
+		doc   *models.Document
+		score float64
+	}
+	var candidates []candidate
+	first := r.sources[0]
+	metric := first.Store.Metadata().Metric
+	comparable := true
+	for i, source := range r.sources[1:] {
+		comparable = comparable && source.Store.Metadata().Metric == metric && modelIDs[i+1] == modelIDs[0]
+	}
+	for _, hits := range results {
+		for rank, hit := range hits {
+			score := 1 / float64(rrfK+rank+1)
+			if comparable {
+				score = float64(metric.Rank(hit.Score))
+			}
+			candidates = append(candidates, candidate{doc: hit.Document, score: score})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	docs := make([]*models.Document, 0, r.topK)
+	for _, c := range candidates[:min(r.topK, len(candidates))] {
+		docs = append(docs, c.doc)
+	}
+	return docs
 }
@@ -0,0 +1,220 @@
+// Package resilience wraps calls to a model server with per-call timeouts,
+// jittered exponential backoff retries and a circuit breaker.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Policy configures timeouts and retries of a Client
+type Policy struct {
+	Timeout    time.Duration // per attempt; 0 means no timeout
+	MaxRetries int           // retries after the first attempt
+	BaseDelay  time.Duration // wait before the first retry
+	MaxDelay   time.Duration // upper bound for any single wait
+
+	// BreakerThreshold consecutive server failures open the circuit for
+	// BreakerCooldown; 0 disables the breaker
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultPolicy suits embedding requests
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout:          time.Minute,
+		MaxRetries:       3,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         10 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// GeneratePolicy suits text generation, which may legitimately take minutes
+func GeneratePolicy() Policy {
+	p := DefaultPolicy()
+	p.Timeout = 5 * time.Minute
+	p.MaxRetries = 2
+	return p
+}
+
+// Client runs operations under a Policy; all calls share one circuit breaker
+type Client struct {
+	policy  Policy
+	breaker *Breaker
+}
+
+// NewClient creates a client with its own circuit breaker
+func NewClient(policy Policy) *Client {
+	return &Client{
+		policy:  policy,
+		breaker: NewBreaker(policy.BreakerThreshold, policy.BreakerCooldown),
+	}
+}
+
+// Do runs fn, retrying retryable failures with backoff. Errors are returned
+// as *Error classified by kind unless ctx itself was cancelled, in which case
+// ctx.Err() is returned.
+func (c *Client) Do(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	var last *Error
+	for attempt := 0; ; attempt++ {
+		probe, err := c.breaker.allow()
+		if err != nil {
+			if last != nil {
+				// The breaker opened during our own retries; report why
+				return last
+			}
+			return &Error{Op: op, Kind: ErrCircuitOpen, Err: err}
+		}
+
+		err = c.attempt(ctx, fn)
+		if ctx.Err() != nil {
+			// A cancelled call says nothing about the server; let the next
+			// call probe it instead
+			if probe {
+				c.breaker.release()
+			}
+			return ctx.Err()
+		}
+
+		kind := Classify(err)
+		switch kind {
+		case nil:
+			c.breaker.Success()
+			return nil
+		case ErrUnreachable, ErrTimeout, ErrOverloaded:
+			c.breaker.Failure()
+		case ErrUnknown:
+			// Nothing is known about the server either way
+			if probe {
+				c.breaker.release()
+			}
+		default:
+			// The server answered; it is healthy even if the request was bad
+			c.breaker.Success()
+		}
+
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return &Error{Op: op, Kind: kind, Err: permanent.err}
+		}
+		last = &Error{Op: op, Kind: kind, Err: err}
+		if !Retryable(err) || attempt >= c.policy.MaxRetries {
+			return last
+		}
+
+		select {
+		case <-time.After(c.backoff(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// attempt runs fn once under the per-call timeout
+func (c *Client) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if c.policy.Timeout <= 0 {
+		return fn(ctx)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, c.policy.Timeout)
+	defer cancel()
+	return fn(callCtx)
+}
+
+// backoff returns the jittered wait before retry number attempt (starting at 0)
+func (c *Client) backoff(attempt int) time.Duration {
+	return Backoff(attempt, c.policy.BaseDelay, c.policy.MaxDelay)
+}
+
+// Backoff returns the wait before retry number attempt (starting at 0): base
+// doubled for every attempt and capped at max, with equal jitter. The wait
+// is drawn uniformly from its upper half, so retries of many clients spread
+// out yet none comes back sooner than half the backoff.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base << attempt
+	if d <= 0 || d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Breaker is a consecutive-failure circuit breaker. After threshold failures
+// it opens and rejects calls for cooldown, then lets a single probe through
+// (half-open); the probe's outcome closes or re-opens it.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewBreaker creates a breaker; threshold <= 0 disables it
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// errBreakerOpen is the underlying error of rejected calls
+var errBreakerOpen = errors.New("too many consecutive failures, not retrying until the cooldown ends")
+
+// Allow returns an error if the circuit is open. A call allowed through a
+// half-open circuit is the probe and must end with Success or Failure.
+func (b *Breaker) Allow() error {
+	_, err := b.allow()
+	return err
+}
+
+// allow is Allow that also reports whether the call is the half-open probe
+func (b *Breaker) allow() (probe bool, err error) {
+	if b.threshold <= 0 {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, nil
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false, errBreakerOpen
+	}
+	b.probing = true
+	return true, nil
+}
+
+// release ends a probe that finished without an outcome, so the next call
+// can probe again
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Success closes the circuit
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a server failure, opening the circuit at the threshold
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,154 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := NewBreaker(2, cooldown)
+
+	b.Failure()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("open after one failure: %v", err)
+	}
+	b.Failure()
+	if err := b.Allow(); err == nil {
+		t.Fatal("closed after reaching the threshold")
+	}
+
+	// Half-open: one probe, which fails and re-opens the circuit
+	time.Sleep(cooldown)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("no probe after the cooldown: %v", err)
+	}
+	if err := b.Allow(); err == nil {
+		t.Fatal("a second call got through while probing")
+	}
+	b.Failure()
+	if err := b.Allow(); err == nil {
+		t.Fatal("closed after a failed probe")
+	}
+
+	// A successful probe closes it
+	time.Sleep(cooldown)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("no probe after the cooldown: %v", err)
+	}
+	b.Success()
+	for range 3 {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("open after a successful probe: %v", err)
+		}
+	}
+}
+
+func TestDisabledBreaker(t *testing.T) {
+	b := NewBreaker(0, time.Hour)
+	for range 5 {
+		b.Failure()
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("disabled breaker rejected a call: %v", err)
+	}
+}
+
+func testPolicy() Policy {
+	return Policy{MaxRetries: 0, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BreakerThreshold: 1, BreakerCooldown: 10 * time.Millisecond}
+}
+
+func TestCancelledProbeReleasesBreaker(t *testing.T) {
+	c := NewClient(testPolicy())
+	overloaded := func(context.Context) error { return &StatusError{StatusCode: 503} }
+	if err := c.Do(context.Background(), "embed", overloaded); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Do = %v, want ErrOverloaded", err)
+	}
+	if err := c.Do(context.Background(), "embed", overloaded); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Do = %v, want ErrCircuitOpen", err)
+	}
+
+	// The probe is cancelled, e.g. by Ctrl-C
+	time.Sleep(testPolicy().BreakerCooldown)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := c.Do(ctx, "embed", func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled probe = %v, want context.Canceled", err)
+	}
+
+	// The next call probes again instead of finding the breaker stuck
+	called := false
+	err = c.Do(context.Background(), "embed", func(context.Context) error {
+		called = true
+		return nil
+	})
+	if err != nil || !called {
+		t.Fatalf("call after a cancelled probe = %v (called: %v)", err, called)
+	}
+}
+
+func TestOnlyTransportErrorsAreRetriedAndCounted(t *testing.T) {
+	policy := testPolicy()
+	policy.MaxRetries = 3
+	policy.BreakerThreshold = 2
+	c := NewClient(policy)
+
+	for _, fail := range []error{
+		errors.New("something odd happened"),
+		Invalid(errors.New("text cannot be empty")),
+		fmt.Errorf("embed: %w", Invalid(errors.New("expected 2 embeddings, got 1"))),
+	} {
+		calls := 0
+		err := c.Do(context.Background(), "embed", func(context.Context) error {
+			calls++
+			return fail
+		})
+		if err == nil || calls != 1 {
+			t.Errorf("Do(%v) = %v after %d calls, want a failure after 1", fail, err, calls)
+		}
+	}
+	if err := c.breaker.Allow(); err != nil {
+		t.Errorf("local and unknown errors opened the breaker: %v", err)
+	}
+}
+
+func TestRejectedRequestsAreNotRetried(t *testing.T) {
+	policy := testPolicy()
+	policy.MaxRetries = 3
+	c := NewClient(policy)
+
+	calls := 0
+	err := c.Do(context.Background(), "embed", func(context.Context) error {
+		calls++
+		return &StatusError{StatusCode: 400}
+	})
+	if !errors.Is(err, ErrRequest) || calls != 1 {
+		t.Fatalf("Do = %v after %d calls, want ErrRequest after 1", err, calls)
+	}
+	if err := c.breaker.Allow(); err != nil {
+		t.Errorf("a rejected request opened the breaker: %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	const base, limit = 100 * time.Millisecond, time.Second
+	for attempt, ceiling := range []time.Duration{base, 2 * base, 4 * base, 8 * base, limit, limit} {
+		for range 20 {
+			if d := Backoff(attempt, base, limit); d < ceiling/2 || d > ceiling {
+				t.Fatalf("attempt %d waits %v, want between %v and %v", attempt, d, ceiling/2, ceiling)
+			}
+		}
+	}
+	if d := Backoff(200, base, limit); d < limit/2 || d > limit {
+		t.Errorf("a shift overflow waits %v, want at most %v", d, limit)
+	}
+	if d := Backoff(3, 0, 0); d != 0 {
+		t.Errorf("a zero policy waits %v", d)
+	}
+}
@@ -0,0 +1,220 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Error kinds returned (wrapped in *Error) by Client.Do. Use errors.Is to test
+// for them.
+var (
+	// ErrModelNotFound means the server is up but the model is not pulled
+	ErrModelNotFound = errors.New("model not found")
+	// ErrUnreachable means the server could not be reached at all
+	ErrUnreachable = errors.New("server unreachable")
+	// ErrTimeout means a single call exceeded its timeout
+	ErrTimeout = errors.New("request timed out")
+	// ErrOverloaded means the server answered 429 or 5xx
+	ErrOverloaded = errors.New("server overloaded")
+	// ErrCircuitOpen means recent calls failed and the server is not tried
+	ErrCircuitOpen = errors.New("circuit breaker open")
+	// ErrRequest means the request itself is at fault: the server rejected
+	// it, or it or the answer failed validation (see Invalid)
+	ErrRequest = errors.New("request rejected")
+	// ErrUnknown means the call failed in a way not recognized above. Only
+	// transport failures are retried, so it is not, and it does not count
+	// against the server.
+	ErrUnknown = errors.New("request failed")
+)
+
+// Error is a classified failure of an operation against a model server
+type Error struct {
+	Op   string // e.g. "embed" or "generate"
+	Kind error  // one of the Err* kinds above
+	Err  error  // the underlying error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %v", e.Op, e.Kind)
+	}
+	return fmt.Sprintf("%s: %v: %v", e.Op, e.Kind, e.Err)
+}
+
+// Unwrap exposes both the kind and the underlying error to errors.Is/As
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// StatusError is an HTTP error response; backends with their own HTTP client
+// return it so failures can be classified by status code
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("request failed (status %d)", e.StatusCode)
+	}
+	return fmt.Sprintf("request failed (status %d): %s", e.StatusCode, e.Message)
+}
+
+// statusLine matches an HTTP status line inside an error message, as the
+// langchaingo Ollama client produces ("503 Service Unavailable: ...")
+var statusLine = regexp.MustCompile(`(?:^|: )([1-5]\d\d) [A-Z]`)
+
+// Classify maps err to one of the error kinds. It returns nil for a nil error.
+func Classify(err error) error {
+	var classified *Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &classified):
+		return classified.Kind
+	case errors.As(err, new(invalidError)):
+		return ErrRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	}
+
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "model") && strings.Contains(message, "not found") {
+		return ErrModelNotFound
+	}
+
+	status := statusCode(err)
+	switch {
+	case status == http.StatusNotFound && strings.Contains(message, "model"):
+		return ErrModelNotFound
+	case status == http.StatusTooManyRequests || status >= 500:
+		return ErrOverloaded
+	case status >= 400:
+		return ErrRequest
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrTimeout
+		}
+		return ErrUnreachable
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		strings.Contains(message, "connection refused") || strings.Contains(message, "connection reset") {
+		return ErrUnreachable
+	}
+
+	return ErrUnknown
+}
+
+// statusCode finds the HTTP status of err. Besides StatusError it recognizes
+// any error struct with a StatusCode field, such as the langchaingo Ollama
+// client's unexported error type, and status lines inside the message.
+func statusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		v := reflect.ValueOf(e)
+		if v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		if f := v.FieldByName("StatusCode"); f.IsValid() && f.CanInt() && f.Int() != 0 {
+			return int(f.Int())
+		}
+	}
+
+	if m := statusLine.FindStringSubmatch(err.Error()); m != nil {
+		status, _ := strconv.Atoi(m[1])
+		return status
+	}
+	return 0
+}
+
+// Retryable reports whether retrying err may succeed: only failures to
+// reach the server or get an answer in time are worth another try
+func Retryable(err error) bool {
+	switch Classify(err) {
+	case ErrUnreachable, ErrTimeout, ErrOverloaded:
+		return true
+	}
+	return false
+}
+
+// ServerDown reports whether err is about the server rather than the input,
+// so retrying with different input (e.g. a smaller batch) will not help
+func ServerDown(err error) bool {
+	switch Classify(err) {
+	case ErrUnreachable, ErrTimeout, ErrOverloaded, ErrCircuitOpen, ErrModelNotFound:
+		return true
+	}
+	return false
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Client.Do returns it without retrying, e.g. after a
+// streaming call has already delivered part of its output
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// invalidError marks an error in the request or its answer, found before or
+// after the server was asked
+type invalidError struct {
+	err error
+}
+
+func (e invalidError) Error() string { return e.err.Error() }
+func (e invalidError) Unwrap() error { return e.err }
+
+// Invalid wraps a validation error, such as an empty text or an answer with
+// missing embeddings, so it is classified as ErrRequest: not retried and not
+// counted against the server
+func Invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return invalidError{err: err}
+}
+
+// Hint suggests a fix for err, or returns "" when there is nothing to suggest
+func Hint(err error) string {
+	switch Classify(err) {
+	case ErrModelNotFound:
+		return "the model is not available; pull it first (e.g. 'ollama pull llama3')"
+	case ErrUnreachable:
+		return "the model server is not reachable; is 'ollama serve' running (check OLLAMA_HOST)?"
+	case ErrCircuitOpen:
+		return "the model server failed repeatedly; waiting before trying again"
+	case ErrTimeout:
+		return "the model server is too slow; try a smaller model or a longer timeout"
+	}
+	return ""
+}
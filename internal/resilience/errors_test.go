@@ -0,0 +1,90 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+// ollamaError mimics the langchaingo Ollama client's unexported error type
+type ollamaError struct {
+	StatusCode   int
+	ErrorMessage string
+}
+
+func (e ollamaError) Error() string { return e.ErrorMessage }
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"nil", nil, nil},
+		{"already classified", &Error{Op: "embed", Kind: ErrOverloaded}, ErrOverloaded},
+		{"deadline", fmt.Errorf("embed: %w", context.DeadlineExceeded), ErrTimeout},
+		{"model missing message", errors.New(`model "llama3" not found, try pulling it first`), ErrModelNotFound},
+		{"model missing status", &StatusError{StatusCode: 404, Message: "no such model"}, ErrModelNotFound},
+		{"rate limited", &StatusError{StatusCode: 429}, ErrOverloaded},
+		{"server error", &StatusError{StatusCode: 503}, ErrOverloaded},
+		{"bad request", &StatusError{StatusCode: 400, Message: "input too long"}, ErrRequest},
+		{"status field", fmt.Errorf("generate: %w", ollamaError{StatusCode: 500, ErrorMessage: "boom"}), ErrOverloaded},
+		{"status line", errors.New("embed: 502 Bad Gateway: upstream"), ErrOverloaded},
+		{"net timeout", timeoutError{}, ErrTimeout},
+		{"connection refused", refused, ErrUnreachable},
+		{"connection reset", errors.New("read tcp: connection reset by peer"), ErrUnreachable},
+		{"eof", fmt.Errorf("decode: %w", io.ErrUnexpectedEOF), ErrUnreachable},
+		{"unknown", errors.New("something odd happened"), ErrUnknown},
+		{"invalid", fmt.Errorf("embed: %w", Invalid(errors.New("duplicate embedding for index 0"))), ErrRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	if Retryable(errors.New("something odd happened")) {
+		t.Error("unknown errors are retried")
+	}
+	if !Retryable(&StatusError{StatusCode: 503}) {
+		t.Error("overloaded servers are not retried")
+	}
+	if Retryable(&StatusError{StatusCode: 400}) {
+		t.Error("rejected requests are retried")
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&StatusError{StatusCode: 418}, 418},
+		{fmt.Errorf("wrapped: %w", &StatusError{StatusCode: 429}), 429},
+		{ollamaError{StatusCode: 500}, 500},
+		{&ollamaError{StatusCode: 404}, 404},
+		{errors.New("503 Service Unavailable"), 503},
+		{errors.New("embed: 400 Bad Request: too long"), 400},
+		{errors.New("listening on port 8080 Now"), 0},
+		{errors.New("no status here"), 0},
+	}
+	for _, tt := range tests {
+		if got := statusCode(tt.err); got != tt.want {
+			t.Errorf("statusCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
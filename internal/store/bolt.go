@@ -0,0 +1,497 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of a bolt store. Documents are stored as JSON without their
+// embedding; vectors are raw little-endian float32 values in their own bucket,
+// which searches scan in place.
+var (
+	documentsBucket = []byte("documents") // id → document JSON
+	vectorsBucket   = []byte("vectors")   // id → embedding
+	urlsBucket      = []byte("urls")      // url \x00 id → nothing
+	hashesBucket    = []byte("hashes")    // SHA-256 of the content, id → nothing
+)
+
+// BoltStore is a DocumentStore saved in an embedded bbolt key-value file
+// (documents.bolt). Each operation is one bolt transaction applied to memory
+// under the store's write lock, so a search running during a crawl sees a
+// batch either entirely or not at all. The documents are held in memory but
+// the vectors are not: searches read them from the file's memory map. The
+// process keeps the file open while it uses it, and closes it when idle so
+// other processes, such as a crawl and a chat following the collection, can
+// take turns.
+type BoltStore struct {
+	*DocumentStore
+	kv *boltBackend
+}
+
+// boltTimeout bounds the wait for another process to close the file
+const boltTimeout = 30 * time.Second
+
+// OpenBolt opens or creates the bolt store in dir and loads its documents
+func OpenBolt(dir string) (*BoltStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	kv, err := openBoltBackend(filepath.Join(dir, "documents.bolt"))
+	if err != nil {
+		return nil, err
+	}
+	err = kv.update(func(tx *bolt.Tx) error {
+		// Files written while the urls bucket was dropped lack it
+		rebuild := tx.Bucket(urlsBucket) == nil
+		for _, name := range [][]byte{documentsBucket, vectorsBucket, urlsBucket, hashesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if !rebuild {
+			return nil
+		}
+		urls := tx.Bucket(urlsBucket)
+		return tx.Bucket(documentsBucket).ForEach(func(id, data []byte) error {
+			var doc models.Document
+			if err := json.Unmarshal(data, &doc); err != nil {
+				return fmt.Errorf("failed to unmarshal document %s: %w", id, err)
+			}
+			return urls.Put(urlKey(doc.URL, string(id)), []byte{})
+		})
+	})
+	if err != nil {
+		kv.close()
+		return nil, fmt.Errorf("failed to create the buckets of %s: %w", kv.path, err)
+	}
+
+	s := &BoltStore{DocumentStore: newDocumentStore(dir, kv), kv: kv}
+	s.keywords = newKeywordIndex()
+	if err := s.LoadFromDisk(); err != nil {
+		kv.close()
+		return nil, err
+	}
+	s.meta.Backend = BackendBolt
+	return s, nil
+}
+
+// ReplaceURL saves docs as the chunks of the page at url and removes the
+// other chunks the urls bucket lists for it
+func (s *BoltStore) ReplaceURL(url string, docs []*models.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.kv.urlIDs(url, false)
+	if err != nil {
+		return err
+	}
+	return s.replaceURL(url, docs, existing)
+}
+
+// DeleteByURL removes every chunk of the page at url and returns how many
+// documents were deleted
+func (s *BoltStore) DeleteByURL(url string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, err := s.kv.urlIDs(url, false)
+	if err != nil {
+		return 0, err
+	}
+	return s.deleteIDs(ids)
+}
+
+// DeleteByURLPrefix removes every document whose URL starts with prefix and
+// returns how many were deleted
+func (s *BoltStore) DeleteByURLPrefix(prefix string) (int, error) {
+	if prefix == "" {
+		return 0, fmt.Errorf("refusing to delete with an empty URL prefix")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, err := s.kv.urlIDs(prefix, true)
+	if err != nil {
+		return 0, err
+	}
+	return s.deleteIDs(ids)
+}
+
+// GetDocumentsByContentHash returns the documents whose content is exactly
+// content, e.g. the same page crawled under two URLs
+func (s *BoltStore) GetDocumentsByContentHash(content string) ([]*models.Document, error) {
+	prefix := contentHash(content)
+	var ids []string
+	err := s.kv.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(hashesBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			ids = append(ids, string(k[len(prefix):]))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up content hash: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs := make([]*models.Document, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := s.documents[id]; ok {
+			docs = append(docs, copyDocument(doc))
+		}
+	}
+	return docs, nil
+}
+
+func urlKey(url, id string) []byte {
+	return []byte(url + "\x00" + id)
+}
+
+// urlIDs returns the IDs of the documents at url, or of those whose URL
+// starts with it if isPrefix is set
+func (b *boltBackend) urlIDs(url string, isPrefix bool) ([]string, error) {
+	prefix := []byte(url)
+	if !isPrefix {
+		prefix = urlKey(url, "")
+	}
+	var ids []string
+	err := b.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(urlsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if i := bytes.IndexByte(k, 0); i >= 0 {
+				ids = append(ids, string(k[i+1:]))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up URL %s: %w", url, err)
+	}
+	return ids, nil
+}
+
+func contentHash(content string) []byte {
+	sum := sha256.Sum256([]byte(content))
+	return sum[:]
+}
+
+// boltBackend saves each operation of a DocumentStore in one transaction and
+// serves the vectors from the file, so they are read through bolt's memory
+// map instead of being held in memory
+type boltBackend struct {
+	path string
+	file *boltFile
+}
+
+// boltFile is the one handle a process keeps on a bolt file, shared by every
+// store of the file in the process. bolt locks the file while it is open, so
+// the handle is closed once no transaction used it for boltIdle; another
+// process waits for that, up to boltTimeout.
+type boltFile struct {
+	path  string
+	users int // stores of the file, guarded by boltFiles
+
+	mu      sync.Mutex
+	db      *bolt.DB
+	running int         // transactions using db
+	idle    *time.Timer // closes db once running stays 0 for boltIdle
+	closed  bool        // set once the last store closed the file
+}
+
+// boltIdle is how long a handle stays open after its last transaction, so
+// bursts of transactions, such as a crawl's, share one bolt.Open
+const boltIdle = 100 * time.Millisecond
+
+// boltFiles holds the open bolt files of the process by path
+var boltFiles = struct {
+	sync.Mutex
+	open map[string]*boltFile
+}{open: make(map[string]*boltFile)}
+
+// openBoltBackend returns the backend of the bolt file at path, sharing the
+// handle of other stores of the file
+func openBoltBackend(path string) (*boltBackend, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	boltFiles.Lock()
+	defer boltFiles.Unlock()
+	f, ok := boltFiles.open[abs]
+	if !ok {
+		f = &boltFile{path: abs}
+		boltFiles.open[abs] = f
+	}
+	f.users++
+	return &boltBackend{path: path, file: f}, nil
+}
+
+// acquire returns the open handle, opening the file if needed and waiting
+// while another process has it open
+func (f *boltFile) acquire() (*bolt.DB, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, fmt.Errorf("%s is closed", f.path)
+	}
+	if f.idle != nil {
+		f.idle.Stop()
+		f.idle = nil
+	}
+	if f.db == nil {
+		db, err := bolt.Open(f.path, 0644, &bolt.Options{Timeout: boltTimeout})
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, fmt.Errorf("%s is in use by another process", f.path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.path, err)
+		}
+		f.db = db
+	}
+	f.running++
+	return f.db, nil
+}
+
+// release ends a transaction and closes the handle if no other one starts
+// within boltIdle
+func (f *boltFile) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running--
+	if f.running == 0 {
+		f.idle = time.AfterFunc(boltIdle, f.closeIdle)
+	}
+}
+
+func (f *boltFile) closeIdle() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.running > 0 || f.db == nil {
+		return
+	}
+	if err := f.db.Close(); err != nil {
+		log.Printf("⚠️  Failed to close %s: %v", f.path, err)
+	}
+	f.db = nil
+}
+
+// view runs fn in a read-only transaction
+func (b *boltBackend) view(fn func(*bolt.Tx) error) error {
+	f, db, err := b.acquire()
+	if err != nil {
+		return err
+	}
+	defer f.release()
+	return db.View(fn)
+}
+
+// update runs fn in a read-write transaction
+func (b *boltBackend) update(fn func(*bolt.Tx) error) error {
+	f, db, err := b.acquire()
+	if err != nil {
+		return err
+	}
+	defer f.release()
+	return db.Update(fn)
+}
+
+func (b *boltBackend) acquire() (*boltFile, *bolt.DB, error) {
+	boltFiles.Lock()
+	f := b.file
+	boltFiles.Unlock()
+	if f == nil {
+		return nil, nil, fmt.Errorf("%s is closed", b.path)
+	}
+	db, err := f.acquire()
+	return f, db, err
+}
+
+// load returns the documents without their embeddings, which stay in the
+// file
+func (b *boltBackend) load() ([]*models.Document, error) {
+	var docs []*models.Document
+	err := b.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(documentsBucket).ForEach(func(id, data []byte) error {
+			doc := &models.Document{}
+			if err := json.Unmarshal(data, doc); err != nil {
+				return fmt.Errorf("failed to unmarshal document %s: %w", id, err)
+			}
+			docs = append(docs, doc)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+	return docs, nil
+}
+
+// vectors reads the embeddings of the documents with the given IDs
+func (b *boltBackend) vectors(ids []string) ([][]float32, error) {
+	vectors := make([][]float32, len(ids))
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(vectorsBucket)
+		for i, id := range ids {
+			data := bucket.Get([]byte(id))
+			if data == nil {
+				return fmt.Errorf("document %s has no vector", id)
+			}
+			var err error
+			if vectors[i], err = decodeVector(data); err != nil {
+				return fmt.Errorf("document %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vectors: %w", err)
+	}
+	return vectors, nil
+}
+
+// search scans the vectors in one read transaction for the k best matching
+// query. Only the documents in positions are ranked, at their position;
+// vectors are decoded from the memory map into one buffer.
+func (b *boltBackend) search(query []float32, k int, metric vector.Metric, positions map[string]int) ([]vector.Result, error) {
+	scanner := vector.NewScanner(query, k, metric)
+	buf := make([]float32, len(query))
+	err := b.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(vectorsBucket).ForEach(func(id, data []byte) error {
+			pos, ok := positions[string(id)]
+			if !ok || len(data) != 4*len(buf) {
+				return nil
+			}
+			for i := range buf {
+				buf[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+			}
+			scanner.Offer(pos, buf)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search vectors: %w", err)
+	}
+	return scanner.Results(), nil
+}
+
+func (b *boltBackend) save(_ func() ([]*models.Document, error), changed []*models.Document, removed []string) error {
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	err := b.update(func(tx *bolt.Tx) error {
+		for _, id := range removed {
+			if err := deleteKeys(tx, id); err != nil {
+				return err
+			}
+		}
+		for _, doc := range changed {
+			if err := deleteKeys(tx, doc.ID); err != nil {
+				return err
+			}
+			if err := putKeys(tx, doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit documents: %w", err)
+	}
+	return nil
+}
+
+// snapshot copies the database file from a read transaction
+func (b *boltBackend) snapshot(dir string) error {
+	return b.view(func(tx *bolt.Tx) error {
+		return tx.CopyFile(filepath.Join(dir, "documents.bolt"), 0644)
+	})
+}
+
+// close gives up this store's share of the handle; the last store of the
+// file closes it
+func (b *boltBackend) close() error {
+	boltFiles.Lock()
+	defer boltFiles.Unlock()
+	f := b.file
+	if f == nil {
+		return nil
+	}
+	b.file = nil
+	if f.users--; f.users > 0 {
+		return nil
+	}
+	delete(boltFiles.open, f.path)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	if f.idle != nil {
+		f.idle.Stop()
+		f.idle = nil
+	}
+	if f.db == nil {
+		return nil
+	}
+	err := f.db.Close()
+	f.db = nil
+	return err
+}
+
+// deleteKeys removes a document, its vector and its index entries
+func deleteKeys(tx *bolt.Tx, id string) error {
+	documents := tx.Bucket(documentsBucket)
+	data := documents.Get([]byte(id))
+	if data == nil {
+		return nil
+	}
+	var old models.Document
+	if err := json.Unmarshal(data, &old); err != nil {
+		return fmt.Errorf("failed to unmarshal document %s: %w", id, err)
+	}
+
+	if err := documents.Delete([]byte(id)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(vectorsBucket).Delete([]byte(id)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(urlsBucket).Delete(urlKey(old.URL, id)); err != nil {
+		return err
+	}
+	return tx.Bucket(hashesBucket).Delete(append(contentHash(old.Content), id...))
+}
+
+func putKeys(tx *bolt.Tx, doc *models.Document) error {
+	stored := *doc
+	stored.Embedding = nil
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document %s: %w", doc.ID, err)
+	}
+
+	id := []byte(doc.ID)
+	if err := tx.Bucket(documentsBucket).Put(id, data); err != nil {
+		return err
+	}
+	if err := tx.Bucket(vectorsBucket).Put(id, encodeVector(doc.Embedding)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(urlsBucket).Put(urlKey(doc.URL, doc.ID), []byte{}); err != nil {
+		return err
+	}
+	return tx.Bucket(hashesBucket).Put(append(contentHash(doc.Content), id...), []byte{})
+}
@@ -0,0 +1,310 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCollections(dir)
+	if err := c.Create("kv", vector.Cosine, "", "", BackendBolt); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := c.Open("kv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := opened.(*BoltStore)
+	if !ok {
+		t.Fatalf("Open returned a %T, want the bolt backend recorded in the metadata", opened)
+	}
+
+	original := testDoc("original", "https://go.dev/doc/faq", 1, 0, 0)
+	mirror := testDoc("mirror", "https://mirror.example/doc/faq", 0, 1, 0)
+	mirror.Content = original.Content
+	mirror.Metadata = map[string]string{"chunk": "0"}
+	other := testDoc("other", "https://go.dev/blog/maps", 0, 0, 1)
+	if err := s.SaveDocuments([]*models.Document{original, mirror, other}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("other"); err != nil {
+		t.Fatal(err)
+	}
+	if second, err := OpenBolt(s.Dir()); err != nil {
+		t.Errorf("the file stays locked while the store is open: %v", err)
+	} else if n := len(second.GetAllDocuments()); n != 2 {
+		t.Errorf("second store loaded %d documents, want 2", n)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := c.Open("kv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	s = reopened.(*BoltStore)
+	checkConsistent(t, s.DocumentStore)
+
+	dupes, err := s.GetDocumentsByContentHash(original.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, doc := range dupes {
+		ids = append(ids, doc.ID)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"mirror", "original"}) {
+		t.Errorf("documents with the same content: %v, want mirror and original", ids)
+	}
+	if got, _ := s.GetDocument("mirror"); got.Metadata["chunk"] != "0" || len(got.Embedding) != 3 || got.Version != 1 {
+		t.Errorf("reloaded %+v", got)
+	}
+	if _, err := s.GetDocument("other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted document reloaded: %v", err)
+	}
+	if got, _ := s.GetDocumentsByContentHash(other.Content); len(got) != 0 {
+		t.Errorf("hash index still holds the deleted document: %v", got)
+	}
+}
+
+func TestBoltStoreBatchesAreAtomicForReaders(t *testing.T) {
+	s, err := OpenBolt(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	const batches, size = 20, 5
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for b := range batches {
+			var docs []*models.Document
+			for i := range size {
+				docs = append(docs, testDoc(fmt.Sprintf("b%d-%d", b, i), fmt.Sprintf("https://go.dev/batch/%d", b), float32(b+1), float32(i), 1))
+			}
+			if err := s.SaveDocuments(docs); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-finished:
+			if n := len(s.GetAllDocuments()); n != batches*size {
+				t.Errorf("stored %d documents, want %d", n, batches*size)
+			}
+			return
+		default:
+		}
+		for b := range batches {
+			if n := len(s.GetDocumentsByURL(fmt.Sprintf("https://go.dev/batch/%d", b))); n != 0 && n != size {
+				t.Fatalf("reader saw %d of the %d documents of batch %d", n, size, b)
+			}
+		}
+		if hits := s.SearchWithScores([]float32{1, 1, 1}, batches*size); len(hits)%size != 0 {
+			t.Fatalf("search saw %d documents, part of a batch", len(hits))
+		}
+	}
+}
+
+func TestBoltURLBucket(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenBolt(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	docs := []*models.Document{
+		testDoc("faq-0", "https://go.dev/doc/faq", 1, 0, 0),
+		testDoc("faq-1", "https://go.dev/doc/faq", 0, 1, 0),
+		testDoc("spec-0", "https://go.dev/ref/spec", 0, 0, 1),
+		testDoc("maps-0", "https://go.dev/blog/maps", 1, 1, 0),
+	}
+	if err := s.SaveDocuments(docs); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReplaceURL("https://go.dev/doc/faq", []*models.Document{testDoc("faq-2", "https://go.dev/doc/faq", 1, 0, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	checkURLBucket(t, s, []string{"https://go.dev/blog/maps\x00maps-0", "https://go.dev/doc/faq\x00faq-2", "https://go.dev/ref/spec\x00spec-0"})
+	if page := s.GetDocumentsByURL("https://go.dev/doc/faq"); len(page) != 1 || page[0].ID != "faq-2" {
+		t.Errorf("after ReplaceURL the page has %d documents, want only faq-2", len(page))
+	}
+
+	if n, err := s.DeleteByURL("https://go.dev/ref/spec"); err != nil || n != 1 {
+		t.Fatalf("DeleteByURL = %d, %v; want 1", n, err)
+	}
+	if n, err := s.DeleteByURLPrefix("https://go.dev/doc"); err != nil || n != 1 {
+		t.Fatalf("DeleteByURLPrefix = %d, %v; want 1", n, err)
+	}
+	checkURLBucket(t, s, []string{"https://go.dev/blog/maps\x00maps-0"})
+	if s.Len() != 1 {
+		t.Errorf("%d documents left, want 1", s.Len())
+	}
+
+	// A file without the bucket gets it rebuilt from the documents
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err := bolt.Open(s.kv.path, 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket(urlsBucket) })
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenBolt(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	checkURLBucket(t, reopened, []string{"https://go.dev/blog/maps\x00maps-0"})
+	if n, err := reopened.DeleteByURL("https://go.dev/blog/maps"); err != nil || n != 1 {
+		t.Fatalf("DeleteByURL after the rebuild = %d, %v; want 1", n, err)
+	}
+}
+
+func checkURLBucket(t *testing.T, s *BoltStore, want []string) {
+	t.Helper()
+	var keys []string
+	err := s.kv.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(urlsBucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, want) {
+		t.Errorf("urls bucket holds %q, want %q", keys, want)
+	}
+}
+
+func TestBoltSearchesVectorsInFile(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenBolt(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	docs := make([]*models.Document, 300)
+	for i := range docs {
+		embedding := make([]float32, 16)
+		for j := range embedding {
+			embedding[j] = rng.Float32()*2 - 1
+		}
+		docs[i] = testDoc(fmt.Sprintf("doc-%d", i), fmt.Sprintf("https://go.dev/%d", i), embedding...)
+	}
+	if err := s.SaveDocuments(docs); err != nil {
+		t.Fatal(err)
+	}
+	if s.index != nil {
+		t.Fatal("the bolt store keeps the vectors in memory")
+	}
+	search := func(name string) {
+		t.Helper()
+		for _, i := range []int{0, 42, 299} {
+			hits := s.SearchWithScores(docs[i].Embedding, 3)
+			if len(hits) != 3 || hits[0].Document.ID != docs[i].ID || math.Abs(float64(hits[0].Score-1)) > 1e-5 {
+				t.Fatalf("%s search for %s returned %+v", name, docs[i].ID, hits)
+			}
+		}
+	}
+	search("exact")
+
+	if err := s.SetQuantization(vector.QuantizeInt8); err != nil {
+		t.Fatal(err)
+	}
+	if s.quantized == nil || s.quantized.Len() != len(docs) {
+		t.Fatal("the vectors in the file were not quantized")
+	}
+	search("quantized")
+
+	if err := s.SetQuantization(""); err != nil {
+		t.Fatal(err)
+	}
+	if s.quantized != nil || s.index != nil {
+		t.Fatal("unquantizing did not leave the vectors to the file")
+	}
+	search("unquantized")
+}
+
+func TestBoltFileIsSharedAndReleased(t *testing.T) {
+	dir := t.TempDir()
+	first, err := OpenBolt(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.SaveDocument(testDoc("a", "https://go.dev/doc/a", 1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenBolt(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.kv.file != second.kv.file {
+		t.Fatal("two stores of one file opened it twice")
+	}
+	if _, err := second.GetDocumentsByContentHash("content of a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once idle, the file is closed for other processes
+	f := first.kv.file
+	deadline := time.Now().Add(10 * boltIdle)
+	for {
+		f.mu.Lock()
+		closed := f.db == nil
+		f.mu.Unlock()
+		if closed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the idle file was kept open")
+		}
+		time.Sleep(boltIdle / 10)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if hits := second.SearchBySimilarity([]float32{1, 0, 0}, 1); len(hits) != 1 || hits[0].ID != "a" {
+		t.Errorf("search after the other store closed returned %v", hits)
+	}
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Close(); err != nil {
+		t.Errorf("closing twice: %v", err)
+	}
+	boltFiles.Lock()
+	defer boltFiles.Unlock()
+	if _, ok := boltFiles.open[f.path]; ok || f.db != nil {
+		t.Error("the file stayed open after its stores closed")
+	}
+}
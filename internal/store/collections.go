@@ -0,0 +1,171 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"ollama_go/internal/vector"
+)
+
+// DefaultCollection is the collection kept directly in the root directory,
+// where indexes lived before collections existed
+const DefaultCollection = "default"
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Collections manages named indexes under one root directory. Each
+// collection is a DocumentStore with its own metric, dimension and
+// embedding model, kept in root/collections/<name>.
+type Collections struct {
+	root string
+}
+
+// CollectionInfo describes a collection
+type CollectionInfo struct {
+	Name      string
+	Dir       string
+	Metadata  IndexMetadata
+	Documents int
+}
+
+// NewCollections manages the collections under root
+func NewCollections(root string) *Collections {
+	return &Collections{root: root}
+}
+
+// Dir returns the directory of the named collection
+func (c *Collections) Dir(name string) (string, error) {
+	if name == "" || name == DefaultCollection {
+		return c.root, nil
+	}
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid collection name %q: use lowercase letters, digits, - and _", name)
+	}
+	return filepath.Join(c.root, "collections", name), nil
+}
+
+// Exists reports whether the named collection has been created. The default
+// collection always exists.
+func (c *Collections) Exists(name string) bool {
+	dir, err := c.Dir(name)
+	if err != nil {
+		return false
+	}
+	if dir == c.root {
+		return true
+	}
+	_, err = os.Stat(filepath.Join(dir, "index.json"))
+	return err == nil
+}
+
+// Create creates an empty collection saved by backend (BackendJSON,
+// BackendSQLite or BackendBolt). model may be empty, in which case the embedding model is
+// recorded when the first documents are indexed.
+func (c *Collections) Create(name string, metric vector.Metric, quantization vector.Quantization, model, backend string) error {
+	dir, err := c.Dir(name)
+	if err != nil {
+		return err
+	}
+	if dir == c.root || c.Exists(name) {
+		return fmt.Errorf("collection %s already exists", name)
+	}
+
+	ds, err := openBackend(dir, backend)
+	if err != nil {
+		return err
+	}
+	defer ds.Close()
+
+	if err := ds.SetMetric(metric); err != nil {
+		return err
+	}
+	if err := ds.SetQuantization(quantization); err != nil {
+		return err
+	}
+	if err := ds.SetModel(model); err != nil {
+		return err
+	}
+	return ds.Persist()
+}
+
+// Open loads the named collection
+func (c *Collections) Open(name string) (Store, error) {
+	if !c.Exists(name) {
+		return nil, fmt.Errorf("%w: collection %s (create it with: collection create %s)", ErrNotFound, name, name)
+	}
+	dir, err := c.Dir(name)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := Open(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load collection %s: %w", name, err)
+	}
+	return ds, nil
+}
+
+// Drop deletes the named collection and its documents
+func (c *Collections) Drop(name string) error {
+	dir, err := c.Dir(name)
+	if err != nil {
+		return err
+	}
+	if dir == c.root {
+		return fmt.Errorf("the default collection cannot be dropped; delete its documents instead")
+	}
+	if !c.Exists(name) {
+		return fmt.Errorf("%w: collection %s", ErrNotFound, name)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to drop collection %s: %w", name, err)
+	}
+	return nil
+}
+
+// Names returns the names of all collections, the default one first
+func (c *Collections) Names() ([]string, error) {
+	names := []string{DefaultCollection}
+	entries, err := os.ReadDir(filepath.Join(c.root, "collections"))
+	if os.IsNotExist(err) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	var named []string
+	for _, entry := range entries {
+		if entry.IsDir() && c.Exists(entry.Name()) {
+			named = append(named, entry.Name())
+		}
+	}
+	sort.Strings(named)
+	return append(names, named...), nil
+}
+
+// List loads every collection and describes it
+func (c *Collections) List() ([]CollectionInfo, error) {
+	names, err := c.Names()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]CollectionInfo, 0, len(names))
+	for _, name := range names {
+		ds, err := c.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, CollectionInfo{
+			Name:      name,
+			Dir:       ds.Dir(),
+			Metadata:  ds.Metadata(),
+			Documents: ds.Len(),
+		})
+		ds.Close()
+	}
+	return infos, nil
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+func TestCollections(t *testing.T) {
+	c := NewCollections(t.TempDir())
+
+	if err := c.Create("notes", vector.L2, "", "hash/fnv-64", BackendJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("notes", vector.Cosine, "", "", BackendJSON); err == nil {
+		t.Error("created a collection twice")
+	}
+	for _, name := range []string{DefaultCollection, "Bad Name", "../escape"} {
+		if err := c.Create(name, vector.Cosine, "", "", BackendJSON); err == nil {
+			t.Errorf("created collection %q", name)
+		}
+	}
+	if _, err := c.Open("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("opening a missing collection: got %v, want ErrNotFound", err)
+	}
+
+	notes, err := c.Open("notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta := notes.Metadata(); meta.Metric != vector.L2 || meta.Model != "hash/fnv-64" {
+		t.Errorf("notes has metadata %+v, want l2 and hash/fnv-64", meta)
+	}
+	if err := notes.SaveDocuments([]*models.Document{testDoc("alpha", "https://go.dev/a", 1, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := notes.SetModel("ollama/llama3:latest"); err == nil {
+		t.Error("switched the model of a non-empty collection")
+	}
+
+	names, err := c.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{DefaultCollection, "notes"}) {
+		t.Errorf("names %v, want default and notes", names)
+	}
+	infos, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infos[1].Documents != 1 || infos[1].Metadata.Dimension != 2 {
+		t.Errorf("notes listed as %+v, want 1 document of dimension 2", infos[1])
+	}
+
+	if err := c.Drop(DefaultCollection); err == nil {
+		t.Error("dropped the default collection")
+	}
+	if err := c.Drop("notes"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Exists("notes") {
+		t.Error("notes still exists after being dropped")
+	}
+}
+
+func TestQuantizedCollection(t *testing.T) {
+	c := NewCollections(t.TempDir())
+	if err := c.Create("notes", vector.L2, vector.QuantizeInt8, "", BackendJSON); err == nil {
+		t.Error("created a quantized l2 collection")
+	}
+	if err := c.Create("notes", vector.Cosine, vector.QuantizeInt8, "", BackendJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	docs := make([]*models.Document, 200)
+	for i := range docs {
+		embedding := make([]float32, 16)
+		for j := range embedding {
+			embedding[j] = rng.Float32()*2 - 1
+		}
+		docs[i] = testDoc(fmt.Sprintf("doc-%d", i), fmt.Sprintf("https://go.dev/%d", i), embedding...)
+	}
+	notes, err := c.Open("notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notes.SaveDocuments(docs); err != nil {
+		t.Fatal(err)
+	}
+	notes.Close()
+
+	notes, err = c.Open("notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notes.Close()
+	if q := notes.Metadata().Quantization; q != vector.QuantizeInt8 {
+		t.Fatalf("reopened with quantization %q, want int8", q)
+	}
+	if err := notes.SetMetric(vector.Dot); err == nil {
+		t.Error("switched a quantized collection to the dot metric")
+	}
+	ds := notes.(*DocumentStore)
+	if ds.index != nil || ds.quantized == nil || ds.quantized.Len() != len(docs) {
+		t.Fatal("the quantized collection keeps its full-precision vectors in memory")
+	}
+
+	// Re-ranking makes the scores exact cosine similarities
+	query := docs[42].Embedding
+	hits := notes.SearchWithScores(query, 5)
+	if len(hits) != 5 || hits[0].Document.ID != "doc-42" {
+		t.Fatalf("search returned %d hits starting with %v, want doc-42 first", len(hits), hits)
+	}
+	for _, hit := range hits {
+		doc, err := notes.GetDocument(hit.Document.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := vector.CosineSimilarity(query, doc.Embedding); math.Abs(float64(hit.Score-want)) > 1e-5 {
+			t.Errorf("%s scored %f, want %f", hit.Document.ID, hit.Score, want)
+		}
+	}
+
+	// Writes update the codes in place
+	quantized := ds.quantized
+	added := testDoc("added", "https://go.dev/added", slices.Clone(docs[7].Embedding)...)
+	added.Embedding[0] += 0.001
+	if _, err := notes.DeleteByURL(docs[7].URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := notes.SaveDocument(added); err != nil {
+		t.Fatal(err)
+	}
+	if hits := notes.SearchBySimilarity(added.Embedding, 1); len(hits) != 1 || hits[0].ID != "added" {
+		t.Errorf("search after a write returned %v, want the added document", hits)
+	}
+	if ds.quantized != quantized || ds.quantized.Len() != len(docs) {
+		t.Error("a write rebuilt the quantized index")
+	}
+
+	// Without quantization the vectors come back into memory
+	if err := notes.SetQuantization(""); err != nil {
+		t.Fatal(err)
+	}
+	if ds.quantized != nil || ds.index.Len() != len(docs) {
+		t.Fatal("the exact index was not restored")
+	}
+	if hits := notes.SearchBySimilarity(added.Embedding, 1); len(hits) != 1 || hits[0].ID != "added" {
+		t.Errorf("exact search returned %v, want the added document", hits)
+	}
+}
@@ -0,0 +1,393 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+var (
+	// ErrNotFound is returned for operations on a document that does not exist
+	ErrNotFound = errors.New("document not found")
+	// ErrVersionConflict is returned by Upsert when the stored version
+	// differs from the expected one
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrReplaced is returned by saves to a store another process replaced
+	// since it was opened
+	ErrReplaced = errors.New("store was replaced")
+)
+
+// AnyVersion makes Upsert skip the version check
+const AnyVersion int64 = -1
+
+// SaveDocuments saves a batch of documents and persists once. The batch is
+// validated first, so either every document is saved or none is.
+func (ds *DocumentStore) SaveDocuments(docs []*models.Document) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.checkDimensions(docs); err != nil {
+		return err
+	}
+	return ds.commit(docs, nil, false)
+}
+
+// CopyDocuments saves a batch of documents taken from another index, keeping
+// their versions, e.g. when the index is rebuilt with another model
+func (ds *DocumentStore) CopyDocuments(docs []*models.Document) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.checkDimensions(docs); err != nil {
+		return err
+	}
+	return ds.commit(docs, nil, true)
+}
+
+// ReplaceURL saves docs as the chunks of the page at url and removes its
+// other chunks in the same operation, so a page crawled again never shows
+// stale chunks, nor none at all, to searches
+func (ds *DocumentStore) ReplaceURL(url string, docs []*models.Document) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.replaceURL(url, docs, ds.byURL[url])
+}
+
+// replaceURL saves docs and removes the IDs in existing that are not among
+// them
+func (ds *DocumentStore) replaceURL(url string, docs []*models.Document, existing []string) error {
+	keep := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if doc.URL != url {
+			return fmt.Errorf("document %s has the URL %s, not %s", doc.ID, doc.URL, url)
+		}
+		keep[doc.ID] = true
+	}
+	if err := ds.checkDimensions(docs); err != nil {
+		return err
+	}
+	var removed []string
+	for _, id := range existing {
+		if _, ok := ds.documents[id]; ok && !keep[id] {
+			removed = append(removed, id)
+		}
+	}
+	return ds.commit(docs, removed, false)
+}
+
+// Upsert saves doc if the stored document with its ID is at expectedVersion.
+// A document that does not exist yet is at version 0; AnyVersion skips the
+// check. On success doc.Version holds the new version.
+func (ds *DocumentStore) Upsert(doc *models.Document, expectedVersion int64) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var current int64
+	if existing, ok := ds.documents[doc.ID]; ok {
+		current = existing.Version
+	}
+	if expectedVersion != AnyVersion && current != expectedVersion {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, doc.ID, current, expectedVersion)
+	}
+
+	if err := ds.checkDimensions([]*models.Document{doc}); err != nil {
+		return err
+	}
+	return ds.commit([]*models.Document{doc}, nil, false)
+}
+
+// Delete removes the document with the given ID
+func (ds *DocumentStore) Delete(id string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if _, ok := ds.documents[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return ds.commit(nil, []string{id}, false)
+}
+
+// DeleteByURL removes every chunk of the page at url and returns how many
+// documents were deleted
+func (ds *DocumentStore) DeleteByURL(url string) (int, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.deleteIDs(ds.byURL[url])
+}
+
+// DeleteByURLPrefix removes every document whose URL starts with prefix and
+// returns how many were deleted
+func (ds *DocumentStore) DeleteByURLPrefix(prefix string) (int, error) {
+	if prefix == "" {
+		return 0, fmt.Errorf("refusing to delete with an empty URL prefix")
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var ids []string
+	for url, urlIDs := range ds.byURL {
+		if strings.HasPrefix(url, prefix) {
+			ids = append(ids, urlIDs...)
+		}
+	}
+	return ds.deleteIDs(ids)
+}
+
+// deleteIDs removes the stored documents among ids and returns how many
+// there were
+func (ds *DocumentStore) deleteIDs(ids []string) (int, error) {
+	var removed []string
+	for _, id := range ids {
+		if _, ok := ds.documents[id]; ok {
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) == 0 {
+		return 0, nil
+	}
+	if err := ds.commit(nil, removed, false); err != nil {
+		return 0, err
+	}
+	return len(removed), nil
+}
+
+// Clear removes every document. The metric and embedding model are kept, so
+// the store can be refilled, e.g. by a fresh crawl.
+func (ds *DocumentStore) Clear() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	removed := make([]string, 0, len(ds.documents))
+	for id := range ds.documents {
+		removed = append(removed, id)
+	}
+	return ds.locked(func() error {
+		if err := ds.saveBackend(nil, removed); err != nil {
+			return err
+		}
+		ds.documents = make(map[string]*models.Document)
+		ds.resetIndexes()
+		ds.meta.Dimension = 0
+		return ds.saveMetadata()
+	})
+}
+
+// GetDocumentsByURL returns the chunks of the page at url in chunk order,
+// without their embeddings
+func (ds *DocumentStore) GetDocumentsByURL(url string) []*models.Document {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	docs := ds.documentsByURL(url)
+	for i, doc := range docs {
+		docs[i] = copyDocument(doc)
+	}
+	return docs
+}
+
+func (ds *DocumentStore) documentsByURL(url string) []*models.Document {
+	docs := make([]*models.Document, 0, len(ds.byURL[url]))
+	for _, id := range ds.byURL[url] {
+		docs = append(docs, ds.documents[id])
+	}
+	slices.SortFunc(docs, func(a, b *models.Document) int {
+		ca, _ := strconv.Atoi(a.Metadata["chunk"])
+		cb, _ := strconv.Atoi(b.Metadata["chunk"])
+		if ca != cb {
+			return ca - cb
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return docs
+}
+
+// URLs returns the distinct document URLs starting with prefix, sorted
+func (ds *DocumentStore) URLs(prefix string) []string {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.urls(prefix)
+}
+
+func (ds *DocumentStore) urls(prefix string) []string {
+	urls := make([]string, 0)
+	for url := range ds.byURL {
+		if strings.HasPrefix(url, prefix) {
+			urls = append(urls, url)
+		}
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+// Filter returns the documents matching f, ordered by URL and chunk. They
+// carry their embeddings only if f.Embeddings is set.
+func (ds *DocumentStore) Filter(f Filter) []*models.Document {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	docs := make([]*models.Document, 0)
+	for _, url := range ds.urls(f.URLPrefix) {
+		for _, doc := range ds.documentsByURL(url) {
+			if !matchesMetadata(doc, f.Metadata) {
+				continue
+			}
+			copied := copyDocument(doc)
+			if f.Embeddings {
+				// A document left without its embedding fails the export
+				withVector, err := ds.withEmbedding(doc)
+				if err != nil {
+					log.Printf("⚠️  %v", err)
+				} else {
+					copied = withVector
+				}
+			}
+			docs = append(docs, copied)
+			if len(docs) == f.Limit {
+				return docs
+			}
+		}
+	}
+	return docs
+}
+
+func matchesMetadata(doc *models.Document, want map[string]string) bool {
+	for key, value := range want {
+		if got, ok := doc.Metadata[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchByKeyword returns the topK documents best matching the words of
+// query, ranked by BM25 over title, description and content
+func (ds *DocumentStore) SearchByKeyword(query string, topK int) ([]*models.Document, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	if ds.keywords == nil {
+		return nil, fmt.Errorf("keyword search is not supported by this backend")
+	}
+	hits := ds.keywords.search(query, topK)
+	results := make([]*models.Document, len(hits))
+	for i, hit := range hits {
+		results[i] = copyDocument(ds.documents[hit.id])
+	}
+	return results, nil
+}
+
+// checkDimensions verifies that docs match the index dimension, or each
+// other if the index is empty
+func (ds *DocumentStore) checkDimensions(docs []*models.Document) error {
+	dim := ds.meta.Dimension
+	for _, doc := range docs {
+		if dim == 0 {
+			dim = len(doc.Embedding)
+		}
+		if len(doc.Embedding) == 0 {
+			return fmt.Errorf("document %s has no embedding", doc.ID)
+		}
+		if len(doc.Embedding) != dim {
+			return fmt.Errorf("embedding dimension %d does not match the index dimension %d", len(doc.Embedding), dim)
+		}
+	}
+	return nil
+}
+
+// commit saves docs and deletes the documents with the removed IDs. The
+// backend is written first and memory only changed once it succeeded, so a
+// failed save leaves the store, and the versions and embeddings of docs, as
+// they were. Dimensions must have been checked.
+func (ds *DocumentStore) commit(docs []*models.Document, removed []string, keepVersions bool) error {
+	type fields struct {
+		version   int64
+		embedding []float32
+	}
+	before := make([]fields, len(docs))
+	staged := make(map[string]*models.Document, len(docs))
+	for i, doc := range docs {
+		before[i] = fields{doc.Version, doc.Embedding}
+		if ds.meta.Normalized {
+			doc.Embedding = vector.Normalize(doc.Embedding)
+		}
+		if !keepVersions {
+			existing, ok := staged[doc.ID]
+			if !ok {
+				existing, ok = ds.documents[doc.ID]
+			}
+			doc.Version = 1
+			if ok {
+				doc.Version = existing.Version + 1
+			}
+		}
+		staged[doc.ID] = doc
+	}
+
+	committed := false
+	err := ds.locked(func() error {
+		if err := ds.saveBackend(docs, removed); err != nil {
+			return err
+		}
+		committed = true
+		for _, id := range removed {
+			if doc, ok := ds.documents[id]; ok {
+				if err := ds.remove(doc); err != nil {
+					return err
+				}
+			}
+		}
+		for _, doc := range docs {
+			if err := ds.put(doc); err != nil {
+				return err
+			}
+		}
+		return ds.saveMetadata()
+	})
+	if err == nil {
+		// The codes are maintained here so searches never train
+		if err := ds.updateQuantized(); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+	if !committed {
+		for i, doc := range docs {
+			doc.Version, doc.Embedding = before[i].version, before[i].embedding
+		}
+	}
+	return err
+}
+
+// put inserts or replaces a saved doc in memory. The stored copy drops the
+// embedding once it is in the search index.
+func (ds *DocumentStore) put(doc *models.Document) error {
+	if ds.meta.Dimension == 0 {
+		ds.meta.Dimension = len(doc.Embedding)
+	}
+	if existing, ok := ds.documents[doc.ID]; ok {
+		if err := ds.unindexDocument(existing, true); err != nil {
+			return err
+		}
+	}
+	stored := *doc
+	ds.documents[doc.ID] = &stored
+	if err := ds.indexDocument(&stored); err != nil {
+		return err
+	}
+	stored.Embedding = nil
+	return nil
+}
+
+// remove deletes doc from the documents and every index
+func (ds *DocumentStore) remove(doc *models.Document) error {
+	if err := ds.unindexDocument(doc, false); err != nil {
+		return err
+	}
+	delete(ds.documents, doc.ID)
+	return nil
+}
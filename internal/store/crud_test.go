@@ -0,0 +1,245 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"ollama_go/internal/models"
+)
+
+func testDoc(id, url string, embedding ...float32) *models.Document {
+	return &models.Document{ID: id, URL: url, Title: id, Content: "content of " + id, Embedding: embedding}
+}
+
+// checkConsistent verifies that every index agrees with ds.documents
+func checkConsistent(t *testing.T, ds *DocumentStore) {
+	t.Helper()
+
+	vectors := len(ds.ids)
+	switch {
+	case ds.diskVectors == nil:
+		vectors = ds.index.Len()
+	case ds.index != nil:
+		t.Fatal("a store searching the vectors in its file holds an exact index")
+	}
+	if vectors != len(ds.documents) || len(ds.ids) != len(ds.documents) || len(ds.positions) != len(ds.documents) {
+		t.Fatalf("vector index holds %d vectors, %d ids, %d positions for %d documents",
+			vectors, len(ds.ids), len(ds.positions), len(ds.documents))
+	}
+	if ds.Len() != len(ds.documents) {
+		t.Errorf("Len() = %d for %d documents", ds.Len(), len(ds.documents))
+	}
+	for _, doc := range ds.GetAllDocuments() {
+		if doc.Embedding != nil {
+			t.Errorf("listing copied the embedding of %s", doc.ID)
+		}
+	}
+	for id, doc := range ds.documents {
+		if ds.ids[ds.positions[id]] != id {
+			t.Errorf("position of %s points at %s", id, ds.ids[ds.positions[id]])
+		}
+		if doc.Embedding != nil {
+			t.Errorf("stored %s keeps a copy of its embedding", id)
+		}
+		embedding, err := ds.vectorAt(ds.positions[id])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ds.GetDocument(id); err != nil || !slices.Equal(got.Embedding, embedding) {
+			t.Errorf("%s was returned without its embedding: %v", id, err)
+		}
+		if got := ds.SearchBySimilarity(embedding, 1); len(got) != 1 || got[0].ID != id {
+			t.Errorf("searching for the embedding of %s found %v", id, got)
+		}
+		if got, err := ds.SearchByKeyword(id, 1); err != nil || len(got) != 1 || got[0].ID != id {
+			t.Errorf("keyword search for %s found %v", id, got)
+		}
+	}
+	urls := 0
+	for _, ids := range ds.byURL {
+		urls += len(ids)
+	}
+	if urls != len(ds.documents) || len(ds.keywords.lengths) != len(ds.documents) {
+		t.Errorf("URL index holds %d and keyword index %d of %d documents", urls, len(ds.keywords.lengths), len(ds.documents))
+	}
+}
+
+func TestCRUDKeepsIndexesConsistent(t *testing.T) {
+	dir := t.TempDir()
+	ds := NewDocumentStoreAt(dir)
+
+	var docs []*models.Document
+	for i := range 6 {
+		url := fmt.Sprintf("https://go.dev/doc/page%d", i%3)
+		docs = append(docs, testDoc(fmt.Sprintf("doc%d", i), url, float32(i+1), float32(i%2), 1))
+	}
+	docs = append(docs, testDoc("blog", "https://go.dev/blog/maps", 0, 0, 1))
+	if err := ds.SaveDocuments(docs); err != nil {
+		t.Fatal(err)
+	}
+	checkConsistent(t, ds)
+
+	if err := ds.Delete("doc0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Delete("doc0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting twice: got %v, want ErrNotFound", err)
+	}
+	if n, err := ds.DeleteByURL("https://go.dev/doc/page1"); err != nil || n != 2 {
+		t.Errorf("DeleteByURL removed %d (%v), want 2", n, err)
+	}
+	checkConsistent(t, ds)
+
+	if n, err := ds.DeleteByURLPrefix("https://go.dev/doc/"); err != nil || n != 3 {
+		t.Errorf("DeleteByURLPrefix removed %d (%v), want 3", n, err)
+	}
+	checkConsistent(t, ds)
+
+	reloaded := NewDocumentStoreAt(dir)
+	if err := reloaded.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+	}
+	if all := reloaded.GetAllDocuments(); len(all) != 1 || all[0].ID != "blog" {
+		t.Errorf("after reload got %v, want only the blog post", all)
+	}
+	checkConsistent(t, reloaded)
+}
+
+func TestUpsertVersionCheck(t *testing.T) {
+	ds := NewDocumentStoreAt(t.TempDir())
+
+	doc := testDoc("alpha", "https://go.dev/a", 1, 0)
+	if err := ds.Upsert(doc, 0); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != 1 {
+		t.Errorf("new document has version %d, want 1", doc.Version)
+	}
+
+	stale := testDoc("alpha", "https://go.dev/b", 0, 1)
+	if err := ds.Upsert(stale, 0); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("stale upsert: got %v, want ErrVersionConflict", err)
+	}
+
+	update := testDoc("alpha", "https://go.dev/b", 0, 1)
+	if err := ds.Upsert(update, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := ds.GetDocumentsByURL("https://go.dev/a"); len(got) != 0 {
+		t.Errorf("old URL still indexed: %v", got)
+	}
+	if got, _ := ds.GetDocument("alpha"); got.Version != 2 || got.URL != "https://go.dev/b" {
+		t.Errorf("stored %+v, want version 2 at the new URL", got)
+	}
+	checkConsistent(t, ds)
+
+	if err := ds.SaveDocuments([]*models.Document{testDoc("b", "x", 1, 2), testDoc("c", "y", 1, 2, 3)}); err == nil {
+		t.Error("batch with a wrong dimension was saved")
+	}
+	if _, err := ds.GetDocument("b"); !errors.Is(err, ErrNotFound) {
+		t.Error("part of a rejected batch was saved")
+	}
+}
+
+// failingBackend wraps a backend and fails its saves while fail is set
+type failingBackend struct {
+	backend
+	fail bool
+}
+
+func (b *failingBackend) save(all func() ([]*models.Document, error), changed []*models.Document, removed []string) error {
+	if b.fail {
+		return errors.New("disk full")
+	}
+	return b.backend.save(all, changed, removed)
+}
+
+func TestFailedSaveLeavesStoreUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	ds := NewDocumentStoreAt(dir)
+	failing := &failingBackend{backend: ds.backend}
+	ds.backend = failing
+
+	kept := testDoc("kept", "https://go.dev/a", 1, 0)
+	if err := ds.SaveDocuments([]*models.Document{kept, testDoc("other", "https://go.dev/b", 0, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	meta := ds.Metadata()
+
+	failing.fail = true
+	update := testDoc("kept", "https://go.dev/c", 3, 4)
+	if err := ds.SaveDocuments([]*models.Document{update, testDoc("new", "https://go.dev/d", 0, 1)}); err == nil {
+		t.Fatal("save succeeded on a failing backend")
+	}
+	if update.Version != 0 || update.Embedding[0] != 3 {
+		t.Errorf("failed save changed the caller's document to version %d, embedding %v", update.Version, update.Embedding)
+	}
+	if err := ds.Upsert(testDoc("kept", "https://go.dev/c", 3, 4), 1); err == nil {
+		t.Fatal("upsert succeeded on a failing backend")
+	}
+	if err := ds.Delete("other"); err == nil {
+		t.Fatal("delete succeeded on a failing backend")
+	}
+	if _, err := ds.DeleteByURLPrefix("https://go.dev/"); err == nil {
+		t.Fatal("bulk delete succeeded on a failing backend")
+	}
+	if err := ds.Clear(); err == nil {
+		t.Fatal("clear succeeded on a failing backend")
+	}
+
+	if got := ds.Metadata(); got != meta {
+		t.Errorf("metadata after failed saves = %+v, want %+v", got, meta)
+	}
+	if _, err := ds.GetDocument("new"); !errors.Is(err, ErrNotFound) {
+		t.Error("document of a failed save is stored")
+	}
+	if got, err := ds.GetDocument("kept"); err != nil || got.URL != kept.URL || got.Version != 1 {
+		t.Errorf("kept = %+v, %v; want the first saved version", got, err)
+	}
+	if got := ds.GetDocumentsByURL("https://go.dev/b"); len(got) != 1 {
+		t.Errorf("deleted document is gone from memory: %v", got)
+	}
+	if hits := ds.SearchWithScores([]float32{1, 0}, 1); len(hits) != 1 || hits[0].Document.ID != "kept" {
+		t.Errorf("search after failed saves = %+v, want kept", hits)
+	}
+	checkConsistent(t, ds)
+
+	// Memory still matches the disk
+	failing.fail = false
+	reloaded := NewDocumentStoreAt(dir)
+	if err := reloaded.LoadFromDisk(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(reloaded.GetAllDocuments()); n != 2 {
+		t.Errorf("reloaded %d documents, want 2", n)
+	}
+}
+
+func TestReplaceURL(t *testing.T) {
+	ds := NewDocumentStoreAt(t.TempDir())
+	page := []*models.Document{
+		testDoc("p0", "https://go.dev/p", 1, 0),
+		testDoc("p1", "https://go.dev/p", 0, 1),
+		testDoc("p2", "https://go.dev/p", 1, 1),
+	}
+	other := testDoc("q0", "https://go.dev/q", 1, 0)
+	if err := ds.SaveDocuments(append(page, other)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.ReplaceURL("https://go.dev/p", []*models.Document{testDoc("p0", "https://go.dev/p", 0, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ds.GetDocumentsByURL("https://go.dev/p"); len(got) != 1 || got[0].ID != "p0" || got[0].Version != 2 {
+		t.Errorf("page after replacing = %v, want only p0 at version 2", got)
+	}
+	if _, err := ds.GetDocument("q0"); err != nil {
+		t.Errorf("other page removed: %v", err)
+	}
+	if err := ds.ReplaceURL("https://go.dev/p", []*models.Document{testDoc("x", "https://go.dev/x", 0, 1)}); err == nil {
+		t.Error("replaced a page with a document of another URL")
+	}
+	checkConsistent(t, ds)
+}
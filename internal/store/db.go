@@ -6,35 +6,198 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 
 	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
 )
 
-// DocumentStore handles storage of documents with embeddings
+// IndexMetadata describes how the stored embeddings are compared. It is
+// saved next to the documents so a query uses the metric the index was built
+// with.
+type IndexMetadata struct {
+	Metric     vector.Metric `json:"metric"`
+	Normalized bool          `json:"normalized"` // embeddings are stored unit-length
+	Dimension  int           `json:"dimension,omitempty"`
+	Model      string        `json:"model,omitempty"`      // embedding model ID, e.g. ollama/llama3:latest
+	Backend    string        `json:"backend,omitempty"`    // BackendJSON (default), BackendSQLite or BackendBolt
+	Generation uint64        `json:"generation,omitempty"` // bumped on every save, so readers can tell the index changed
+
+	// Quantization, if set, makes searches scan compressed vectors and
+	// re-rank the best candidates exactly; cosine indexes only
+	Quantization vector.Quantization `json:"quantization,omitempty"`
+}
+
+// DocumentStore handles storage of documents with embeddings. Documents
+// are kept in memory and saved by a backend, a JSON file by default.
 type DocumentStore struct {
 	mu        sync.RWMutex
 	documents map[string]*models.Document
-	filePath  string
+	backend   backend
+	dir       string
+	metaPath  string
+	meta      IndexMetadata
+
+	// savedModel is the model in index.json as this store last read or wrote
+	// it; another one there means another process replaced the store
+	savedModel string
+
+	// written is the index.json this store last wrote, which Live adopts so
+	// its own saves do not cause a reload
+	written stamp
+
+	// index holds the embeddings contiguously for search; ids maps index
+	// positions to document IDs and positions the reverse
+	index     *vector.Flat
+	ids       []string
+	positions map[string]int
+
+	// quantized replaces index when the metadata asks for quantization. It
+	// keeps compressed vectors in memory and the full-precision ones, at the
+	// same positions, in a file under dir; rawPath is that file if it still
+	// has to be removed once the index is closed.
+	quantized *vector.QuantizedIndex
+	rawPath   string
+
+	// diskVectors replaces index when the backend serves the vectors from
+	// its file; positions are then only bookkeeping for ids
+	diskVectors vectorBackend
+
+	// Secondary indexes: document IDs by URL, and terms for keyword search
+	// unless the backend searches keywords itself
+	byURL    map[string][]string
+	keywords *keywordIndex
 }
 
-// NewDocumentStore creates a new document store
+// DefaultDir is the directory holding the documents and index metadata
+const DefaultDir = "data"
+
+// NewDocumentStore creates a new document store in DefaultDir ranking by
+// cosine similarity
 func NewDocumentStore() *DocumentStore {
+	return NewDocumentStoreAt(DefaultDir)
+}
+
+// NewDocumentStoreAt creates a new document store kept in dir
+func NewDocumentStoreAt(dir string) *DocumentStore {
+	ds := newDocumentStore(dir, &jsonBackend{path: filepath.Join(dir, "documents.json")})
+	ds.keywords = newKeywordIndex()
+	return ds
+}
+
+func newDocumentStore(dir string, b backend) *DocumentStore {
+	diskVectors, _ := b.(vectorBackend)
 	return &DocumentStore{
 		documents: make(map[string]*models.Document),
-		filePath:  "data/documents.json",
+		backend:   b,
+		dir:       dir,
+		metaPath:  filepath.Join(dir, "index.json"),
+		meta:      metadataFor(vector.Cosine),
+		positions: make(map[string]int),
+		byURL:     make(map[string][]string),
+
+		diskVectors: diskVectors,
 	}
 }
 
-// SaveDocument saves a document with its embedding
-func (ds *DocumentStore) SaveDocument(doc *models.Document) error {
+// metadataFor returns the metadata of a new index. Cosine indexes store
+// normalized vectors so that scoring is a plain dot product.
+func metadataFor(metric vector.Metric) IndexMetadata {
+	return IndexMetadata{Metric: metric, Normalized: metric == vector.Cosine}
+}
+
+// Dir returns the directory the store is kept in
+func (ds *DocumentStore) Dir() string {
+	return ds.dir
+}
+
+// Metadata returns the index metadata
+func (ds *DocumentStore) Metadata() IndexMetadata {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.meta
+}
+
+// writtenStamp returns the index.json the store last wrote
+func (ds *DocumentStore) writtenStamp() stamp {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.written
+}
+
+// SetMetric selects the distance metric. It can only change while the store
+// is empty, since cosine indexes store normalized embeddings.
+func (ds *DocumentStore) SetMetric(metric vector.Metric) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if metric == ds.meta.Metric {
+		return nil
+	}
+	if len(ds.documents) > 0 {
+		return fmt.Errorf("index was built with the %s metric; re-index to use %s", ds.meta.Metric, metric)
+	}
+	if ds.meta.Quantization != "" && metric != vector.Cosine {
+		return fmt.Errorf("%s quantization needs the cosine metric, not %s", ds.meta.Quantization, metric)
+	}
+	meta := metadataFor(metric)
+	meta.Model, meta.Backend, meta.Generation = ds.meta.Model, ds.meta.Backend, ds.meta.Generation
+	meta.Quantization = ds.meta.Quantization
+	ds.meta = meta
+	return nil
+}
+
+// SetQuantization selects the compression used for search, or none if
+// kind is empty. Only cosine indexes can be quantized.
+func (ds *DocumentStore) SetQuantization(kind vector.Quantization) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if kind == ds.meta.Quantization {
+		return nil
+	}
+	if kind != "" && ds.meta.Metric != vector.Cosine {
+		return fmt.Errorf("%s quantization needs the cosine metric, not %s", kind, ds.meta.Metric)
+	}
+	ds.meta.Quantization = kind
+	return ds.updateQuantized()
+}
+
+// SetModel records the embedding model of the index. Once documents are
+// stored it can no longer change, since their embeddings would not be
+// comparable with queries embedded by another model.
+func (ds *DocumentStore) SetModel(modelID string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if modelID == ds.meta.Model {
+		return nil
+	}
+	if ds.meta.Model != "" && len(ds.documents) > 0 {
+		return fmt.Errorf("index was built with the embedding model %s, not %s; run reembed to switch models", ds.meta.Model, modelID)
+	}
+	ds.meta.Model = modelID
+	return nil
+}
+
+// Persist writes the documents and index metadata to disk
+func (ds *DocumentStore) Persist() error {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
+	return ds.persist()
+}
 
-	ds.documents[doc.ID] = doc
+// Close releases the backend; the store must not be used afterwards
+func (ds *DocumentStore) Close() error {
+	ds.closeQuantized()
+	return ds.backend.close()
+}
 
-	// Persist to disk
-	return ds.persistToDisk()
+// SaveDocument saves a document with its embedding, replacing any document
+// with the same ID
+func (ds *DocumentStore) SaveDocument(doc *models.Document) error {
+	return ds.SaveDocuments([]*models.Document{doc})
 }
 
 // GetDocument retrieves a document by ID
@@ -44,67 +207,190 @@ func (ds *DocumentStore) GetDocument(id string) (*models.Document, error) {
 
 	doc, exists := ds.documents[id]
 	if !exists {
-		return nil, fmt.Errorf("document not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 
-	return doc, nil
+	return ds.withEmbedding(doc)
 }
 
-// GetAllDocuments returns all stored documents
+// GetAllDocuments returns all stored documents without their embeddings
 func (ds *DocumentStore) GetAllDocuments() []*models.Document {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
 
 	docs := make([]*models.Document, 0, len(ds.documents))
 	for _, doc := range ds.documents {
-		docs = append(docs, doc)
+		docs = append(docs, copyDocument(doc))
 	}
 
 	return docs
 }
 
+// Len returns the number of stored documents
+func (ds *DocumentStore) Len() int {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return len(ds.documents)
+}
+
 // LoadFromDisk loads documents from disk
 func (ds *DocumentStore) LoadFromDisk() error {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 
-	// Check if file exists
-	if _, err := os.Stat(ds.filePath); os.IsNotExist(err) {
-		log.Println("No existing documents file found, starting fresh")
-		return nil
+	docs, err := ds.backend.load()
+	if err != nil {
+		return err
 	}
 
-	data, err := os.ReadFile(ds.filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read documents file: %w", err)
+	ds.documents = make(map[string]*models.Document)
+	for _, doc := range docs {
+		ds.documents[doc.ID] = doc
 	}
 
-	var docs []*models.Document
-	if err := json.Unmarshal(data, &docs); err != nil {
-		return fmt.Errorf("failed to unmarshal documents: %w", err)
+	if err := ds.loadMetadata(docs); err != nil {
+		return err
 	}
 
-	ds.documents = make(map[string]*models.Document)
+	ds.resetIndexes()
 	for _, doc := range docs {
-		ds.documents[doc.ID] = doc
+		if err := ds.indexDocument(doc); err != nil {
+			return fmt.Errorf("failed to index document %s: %w", doc.ID, err)
+		}
+		doc.Embedding = nil
+	}
+	if err := ds.updateQuantized(); err != nil {
+		log.Printf("⚠️  Searching exactly: %v", err)
 	}
 
 	log.Printf("Loaded %d documents from disk\n", len(docs))
 	return nil
 }
 
-// persistToDisk saves all documents to disk
-func (ds *DocumentStore) persistToDisk() error {
+// resetIndexes empties the search and secondary indexes
+func (ds *DocumentStore) resetIndexes() {
+	ds.closeQuantized()
+	ds.index, ds.ids, ds.positions = nil, nil, make(map[string]int)
+	ds.byURL = make(map[string][]string)
+	if ds.keywords != nil {
+		ds.keywords = newKeywordIndex()
+	}
+}
+
+// persist saves every document and the index metadata
+func (ds *DocumentStore) persist() error {
+	return ds.locked(func() error {
+		if err := ds.saveBackend(nil, nil); err != nil {
+			return err
+		}
+		return ds.saveMetadata()
+	})
+}
+
+// locked runs save holding the shared lock of the store directory, once it
+// checked that no other process replaced the store, e.g. by re-embedding it,
+// since the documents in memory would overwrite the new ones
+func (ds *DocumentStore) locked(save func() error) error {
 	// Create directory if it doesn't exist
-	dir := filepath.Dir(ds.filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := os.MkdirAll(ds.dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
+	lock, err := lockShared(ds.dir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
 
-	// Convert map to slice
-	docs := make([]*models.Document, 0, len(ds.documents))
-	for _, doc := range ds.documents {
-		docs = append(docs, doc)
+	onDisk, ok, err := readMetadata(ds.metaPath)
+	if err != nil {
+		return err
+	}
+	if ok && onDisk.Model != ds.savedModel {
+		return fmt.Errorf("%w: %s was re-embedded with %s; open it again", ErrReplaced, ds.dir, onDisk.Model)
+	}
+	return save()
+}
+
+// saveBackend saves the documents changed and removed by an operation before
+// it is applied in memory
+func (ds *DocumentStore) saveBackend(changed []*models.Document, removed []string) error {
+	// The documents once the operation is applied; later changes of one ID win
+	next := func() ([]*models.Document, error) {
+		replaced := make(map[string]bool, len(changed)+len(removed))
+		for _, id := range removed {
+			replaced[id] = true
+		}
+		docs := make([]*models.Document, 0, len(ds.documents)+len(changed))
+		for i := len(changed) - 1; i >= 0; i-- {
+			if !replaced[changed[i].ID] {
+				replaced[changed[i].ID] = true
+				docs = append(docs, changed[i])
+			}
+		}
+		for id, doc := range ds.documents {
+			if !replaced[id] {
+				// The save runs under ds.mu, so the copy can share the
+				// index's vector
+				vector, err := ds.vectorAt(ds.positions[id])
+				if err != nil {
+					return nil, err
+				}
+				withVector := *doc
+				withVector.Embedding = vector
+				docs = append(docs, &withVector)
+			}
+		}
+		return docs, nil
+	}
+	return ds.backend.save(next, changed, removed)
+}
+
+// saveMetadata writes index.json with a new generation. It is written after
+// the documents: once it changes, they are saved.
+func (ds *DocumentStore) saveMetadata() error {
+	ds.meta.Generation++
+	meta, err := json.MarshalIndent(ds.meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index metadata: %w", err)
+	}
+	info, err := writeFileAtomic(ds.metaPath, meta)
+	if err != nil {
+		return fmt.Errorf("failed to write index metadata: %w", err)
+	}
+	ds.savedModel = ds.meta.Model
+	ds.written = stamp{info: info, generation: ds.meta.Generation}
+
+	return nil
+}
+
+// jsonBackend keeps every document in one JSON file, rewritten on each save
+type jsonBackend struct {
+	path string
+}
+
+func (b *jsonBackend) load() ([]*models.Document, error) {
+	// Check if file exists
+	if _, err := os.Stat(b.path); os.IsNotExist(err) {
+		log.Println("No existing documents file found, starting fresh")
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read documents file: %w", err)
+	}
+
+	var docs []*models.Document
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal documents: %w", err)
+	}
+	return docs, nil
+}
+
+func (b *jsonBackend) save(all func() ([]*models.Document, error), _ []*models.Document, _ []string) error {
+	docs, err := all()
+	if err != nil {
+		return err
 	}
 
 	// Marshal to JSON
@@ -113,82 +399,177 @@ func (ds *DocumentStore) persistToDisk() error {
 		return fmt.Errorf("failed to marshal documents: %w", err)
 	}
 
-	// Write to file
-	if err := os.WriteFile(ds.filePath, data, 0644); err != nil {
+	// Replace the file so hard-linked snapshots keep the old one
+	if _, err := writeFileAtomic(b.path, data); err != nil {
 		return fmt.Errorf("failed to write documents file: %w", err)
 	}
-
 	return nil
 }
 
-// SearchBySimilarity finds documents similar to the query embedding
-// This is a simple implementation - for production, use a vector database
-func (ds *DocumentStore) SearchBySimilarity(queryEmbedding []float32, topK int) []*models.Document {
-	ds.mu.RLock()
-	defer ds.mu.RUnlock()
-
-	type scoredDoc struct {
-		doc   *models.Document
-		score float32
+// snapshot hard-links the documents file, which saves only ever replace
+func (b *jsonBackend) snapshot(dir string) error {
+	err := linkOrCopy(b.path, filepath.Join(dir, filepath.Base(b.path)), true)
+	if os.IsNotExist(err) {
+		return nil
 	}
+	return err
+}
 
-	scores := make([]scoredDoc, 0, len(ds.documents))
+func (b *jsonBackend) close() error { return nil }
 
-	for _, doc := range ds.documents {
-		similarity := cosineSimilarity(queryEmbedding, doc.Embedding)
-		scores = append(scores, scoredDoc{doc: doc, score: similarity})
+// loadMetadata reads the index metadata. A new index ranks by cosine
+// similarity; indexes written before metadata existed used cosine similarity
+// on unnormalized vectors.
+func (ds *DocumentStore) loadMetadata(docs []*models.Document) error {
+	meta, ok, err := readMetadata(ds.metaPath)
+	if err != nil {
+		return err
 	}
+	if !ok && len(docs) == 0 {
+		ds.meta = metadataFor(vector.Cosine)
+		return nil
+	}
+	if !ok {
+		ds.meta = IndexMetadata{Metric: vector.Cosine, Dimension: len(docs[0].Embedding)}
+		return nil
+	}
+	ds.meta, ds.savedModel = meta, meta.Model
+	return nil
+}
 
-	// Sort by similarity (descending)
-	for i := 0; i < len(scores); i++ {
-		for j := i + 1; j < len(scores); j++ {
-			if scores[j].score > scores[i].score {
-				scores[i], scores[j] = scores[j], scores[i]
-			}
-		}
+// readMetadata reads an index metadata file; ok is false if it does not exist
+func readMetadata(path string) (meta IndexMetadata, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return meta, false, nil
+	}
+	if err != nil {
+		return meta, false, fmt.Errorf("failed to read index metadata: %w", err)
 	}
 
-	// Return top K results
-	if topK > len(scores) {
-		topK = len(scores)
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, false, fmt.Errorf("failed to unmarshal index metadata: %w", err)
 	}
+	if meta.Metric, err = vector.ParseMetric(string(meta.Metric)); err != nil {
+		return meta, false, err
+	}
+	return meta, true, nil
+}
 
-	results := make([]*models.Document, topK)
-	for i := 0; i < topK; i++ {
-		results[i] = scores[i].doc
+// withEmbedding returns a copy of a stored document carrying its embedding.
+// Stored documents leave their embedding to the search index, so each
+// vector is held in memory once; it is only copied out on request.
+func (ds *DocumentStore) withEmbedding(doc *models.Document) (*models.Document, error) {
+	vector, err := ds.vectorAt(ds.positions[doc.ID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the embedding of %s: %w", doc.ID, err)
 	}
+	withVector := *doc
+	withVector.Embedding = slices.Clone(vector)
+	return &withVector, nil
+}
 
-	return results
+// copyDocument returns a copy of a stored document, which has no embedding,
+// so callers cannot change the store's copy
+func copyDocument(doc *models.Document) *models.Document {
+	c := *doc
+	return &c
 }
 
-// cosineSimilarity calculates cosine similarity between two vectors
-func cosineSimilarity(a, b []float32) float32 {
-	if len(a) != len(b) {
-		return 0
+// indexDocument adds doc, which must already be in ds.documents, to the
+// search and secondary indexes, replacing its embedding if it is indexed
+func (ds *DocumentStore) indexDocument(doc *models.Document) error {
+	ds.byURL[doc.URL] = append(ds.byURL[doc.URL], doc.ID)
+	if ds.keywords != nil {
+		ds.keywords.add(doc)
 	}
 
-	var dotProduct, normA, normB float32
-	for i := range a {
-		dotProduct += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
+	if pos, ok := ds.positions[doc.ID]; ok {
+		return ds.setVector(pos, doc.Embedding)
+	}
+	pos, err := ds.addVector(doc.Embedding)
+	if err != nil {
+		return err
+	}
+	ds.ids = append(ds.ids, doc.ID)
+	ds.positions[doc.ID] = pos
+	return nil
+}
+
+// unindexDocument removes doc from the secondary indexes and, unless
+// keepVector is set because its embedding is about to be replaced, from the
+// search index
+func (ds *DocumentStore) unindexDocument(doc *models.Document, keepVector bool) error {
+	ids := slices.DeleteFunc(ds.byURL[doc.URL], func(id string) bool { return id == doc.ID })
+	if len(ids) == 0 {
+		delete(ds.byURL, doc.URL)
+	} else {
+		ds.byURL[doc.URL] = ids
+	}
+	if ds.keywords != nil {
+		ds.keywords.remove(doc)
+	}
+	if keepVector {
+		return nil
 	}
 
-	if normA == 0 || normB == 0 {
-		return 0
+	// The index swaps the last vector into the freed position
+	pos := ds.positions[doc.ID]
+	moved, err := ds.removeVector(pos)
+	if err != nil {
+		return err
 	}
+	if moved >= 0 {
+		movedID := ds.ids[moved]
+		ds.ids[pos] = movedID
+		ds.positions[movedID] = pos
+	}
+	ds.ids = ds.ids[:len(ds.ids)-1]
+	delete(ds.positions, doc.ID)
+	return nil
+}
+
+// ScoredDocument is a search result with its score under the index metric:
+// a similarity for cosine and dot, a distance for L2
+type ScoredDocument struct {
+	Document *models.Document
+	Score    float32
+}
 
-	return dotProduct / (sqrt(normA) * sqrt(normB))
+// SearchBySimilarity finds the documents closest to the query embedding
+// under the index metric, best first. It is an exact search over all
+// embeddings, parallelized across CPUs.
+func (ds *DocumentStore) SearchBySimilarity(queryEmbedding []float32, topK int) []*models.Document {
+	hits := ds.SearchWithScores(queryEmbedding, topK)
+	results := make([]*models.Document, len(hits))
+	for i, hit := range hits {
+		results[i] = hit.Document
+	}
+	return results
 }
 
-// sqrt is a simple square root implementation for float32
-func sqrt(x float32) float32 {
-	if x == 0 {
-		return 0
+// SearchWithScores is SearchBySimilarity returning the scores as well
+func (ds *DocumentStore) SearchWithScores(queryEmbedding []float32, topK int) []ScoredDocument {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	if len(ds.ids) == 0 {
+		return []ScoredDocument{}
+	}
+
+	var hits []vector.Result
+	switch {
+	case ds.quantized != nil:
+		hits = ds.searchQuantized(queryEmbedding, topK)
+	case ds.diskVectors != nil:
+		hits = ds.searchDisk(queryEmbedding, topK)
+	default:
+		hits = ds.index.Search(queryEmbedding, topK)
 	}
-	z := x
-	for i := 0; i < 10; i++ {
-		z = (z + x/z) / 2
+	results := make([]ScoredDocument, len(hits))
+	for i, hit := range hits {
+		results[i] = ScoredDocument{Document: copyDocument(ds.documents[ds.ids[hit.Index]]), Score: hit.Score}
 	}
-	return z
+
+	return results
 }
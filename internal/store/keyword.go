@@ -0,0 +1,140 @@
+package store
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ollama_go/internal/models"
+)
+
+// BM25 parameters: term frequency saturation and length normalization
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stopwords are too common to help keyword ranking
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "how": true, "in": true,
+	"is": true, "it": true, "of": true, "on": true, "or": true, "that": true,
+	"the": true, "this": true, "to": true, "what": true, "when": true,
+	"with": true, "do": true, "does": true, "i": true, "can": true,
+}
+
+// keywordIndex is an inverted index over document titles, descriptions and
+// content, ranked with BM25
+type keywordIndex struct {
+	postings map[string]map[string]int // term → document ID → term frequency
+	lengths  map[string]int            // document ID → number of terms
+	total    int                       // sum of lengths
+}
+
+func newKeywordIndex() *keywordIndex {
+	return &keywordIndex{
+		postings: make(map[string]map[string]int),
+		lengths:  make(map[string]int),
+	}
+}
+
+// tokenize lowercases text and splits it into terms, dropping stopwords
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	terms := fields[:0]
+	for _, f := range fields {
+		if len(f) > 1 && !stopwords[f] {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+func documentTerms(doc *models.Document) []string {
+	return tokenize(doc.Title + " " + doc.Description + " " + doc.Content)
+}
+
+func (k *keywordIndex) add(doc *models.Document) {
+	terms := documentTerms(doc)
+	for _, term := range terms {
+		docs, ok := k.postings[term]
+		if !ok {
+			docs = make(map[string]int)
+			k.postings[term] = docs
+		}
+		docs[doc.ID]++
+	}
+	k.lengths[doc.ID] = len(terms)
+	k.total += len(terms)
+}
+
+// remove drops doc; it must be the same content that was added
+func (k *keywordIndex) remove(doc *models.Document) {
+	for _, term := range documentTerms(doc) {
+		docs := k.postings[term]
+		delete(docs, doc.ID)
+		if len(docs) == 0 {
+			delete(k.postings, term)
+		}
+	}
+	k.total -= k.lengths[doc.ID]
+	delete(k.lengths, doc.ID)
+}
+
+// keywordHit is a document ID with its BM25 score
+type keywordHit struct {
+	id    string
+	score float64
+}
+
+// search returns the IDs of the topK documents best matching query
+func (k *keywordIndex) search(query string, topK int) []keywordHit {
+	n := len(k.lengths)
+	if n == 0 || topK <= 0 {
+		return nil
+	}
+	avgLength := float64(k.total) / float64(n)
+
+	scores := make(map[string]float64)
+	for _, term := range uniqueTerms(tokenize(query)) {
+		docs := k.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+		idf := math.Log(1 + (float64(n)-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+		for id, tf := range docs {
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(k.lengths[id])/avgLength)
+			scores[id] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + norm)
+		}
+	}
+
+	hits := make([]keywordHit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, keywordHit{id: id, score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].id < hits[j].id
+	})
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := terms[:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			unique = append(unique, t)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,319 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+// Live is a Store that follows its files on disk, so a long-running process
+// such as the chat sees the documents a crawl in another process saves.
+// Every save rewrites index.json last with a new generation; when
+// ReloadIfChanged finds it changed, the store is opened again and swapped in.
+// Searches running on the old state finish on it, and the old store is
+// closed once they have.
+type Live struct {
+	dir string
+
+	mu  sync.RWMutex // guards cur, held only to read or swap it
+	cur *liveState
+
+	reloadMu sync.Mutex // serializes reloads and writes
+}
+
+// liveState is one opened version of the store and the index.json it was
+// opened at
+type liveState struct {
+	Store
+	stamp stamp
+	users sync.WaitGroup
+}
+
+// stamp identifies a version of index.json. The file is replaced on every
+// save, so a new file or generation means the store changed.
+type stamp struct {
+	info       os.FileInfo
+	generation uint64
+}
+
+func (s stamp) equal(o stamp) bool {
+	if s.info == nil || o.info == nil {
+		return s.info == o.info
+	}
+	return os.SameFile(s.info, o.info) && s.info.ModTime().Equal(o.info.ModTime()) && s.generation == o.generation
+}
+
+func readStamp(dir string) (stamp, error) {
+	path := filepath.Join(dir, "index.json")
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return stamp{}, nil
+	}
+	if err != nil {
+		return stamp{}, fmt.Errorf("failed to read index metadata: %w", err)
+	}
+	meta, _, err := readMetadata(path)
+	if err != nil {
+		return stamp{}, err
+	}
+	return stamp{info: info, generation: meta.Generation}, nil
+}
+
+// NewLive makes s follow its files on disk. The Live store owns s and closes
+// it when it is replaced or closed.
+func NewLive(s Store) (*Live, error) {
+	st, err := readStamp(s.Dir())
+	if err != nil {
+		return nil, err
+	}
+	return &Live{dir: s.Dir(), cur: &liveState{Store: s, stamp: st}}, nil
+}
+
+// ReloadIfChanged opens the store again if its files changed since it was
+// opened or last reloaded, and reports whether it did
+func (l *Live) ReloadIfChanged() (bool, error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	st, err := readStamp(l.dir)
+	if err != nil {
+		return false, err
+	}
+	l.mu.RLock()
+	unchanged := st.equal(l.cur.stamp)
+	l.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	return true, l.reload(st)
+}
+
+// Reload opens the store again whether or not its files changed
+func (l *Live) Reload() error {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	st, err := readStamp(l.dir)
+	if err != nil {
+		return err
+	}
+	return l.reload(st)
+}
+
+// reload opens the store at st and swaps it in. The stamp is read first, so
+// a save in between at worst causes one more reload.
+func (l *Live) reload(st stamp) error {
+	s, err := Open(l.dir)
+	if err != nil {
+		return fmt.Errorf("failed to reload %s: %w", l.dir, err)
+	}
+	next := &liveState{Store: s, stamp: st}
+
+	l.mu.Lock()
+	old := l.cur
+	l.cur = next
+	l.mu.Unlock()
+
+	old.users.Wait()
+	return old.Close()
+}
+
+// Watch calls ReloadIfChanged every interval until ctx is done. notify, if
+// not nil, is called after every reload with its error.
+func (l *Live) Watch(ctx context.Context, interval time.Duration, notify func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := l.ReloadIfChanged()
+			if (reloaded || err != nil) && notify != nil {
+				notify(err)
+			}
+		}
+	}
+}
+
+// acquire returns the current state, which stays open until released
+func (l *Live) acquire() *liveState {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	l.cur.users.Add(1)
+	return l.cur
+}
+
+func (st *liveState) release() { st.users.Done() }
+
+// write runs a change on the current state and records the index.json it
+// wrote, so a process's own saves do not cause a reload. The file on disk is
+// not read back: another process may already have replaced it, and that
+// change must still be reloaded.
+func (l *Live) write(fn func(Store) error) error {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	st := l.acquire()
+	defer st.release()
+
+	w, ok := st.Store.(interface{ writtenStamp() stamp })
+	if !ok {
+		return fn(st.Store)
+	}
+	before := w.writtenStamp()
+	err := fn(st.Store)
+	if after := w.writtenStamp(); after.info != nil && !after.equal(before) {
+		l.mu.Lock()
+		st.stamp = after
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// The Store methods run on the current state; writes are serialized with
+// reloads so they cannot be lost to a swap.
+
+func (l *Live) SaveDocuments(docs []*models.Document) error {
+	return l.write(func(s Store) error { return s.SaveDocuments(docs) })
+}
+
+func (l *Live) CopyDocuments(docs []*models.Document) error {
+	return l.write(func(s Store) error { return s.CopyDocuments(docs) })
+}
+
+func (l *Live) SaveDocument(doc *models.Document) error {
+	return l.write(func(s Store) error { return s.SaveDocument(doc) })
+}
+
+func (l *Live) ReplaceURL(url string, docs []*models.Document) error {
+	return l.write(func(s Store) error { return s.ReplaceURL(url, docs) })
+}
+
+func (l *Live) Upsert(doc *models.Document, expectedVersion int64) error {
+	return l.write(func(s Store) error { return s.Upsert(doc, expectedVersion) })
+}
+
+func (l *Live) Delete(id string) error {
+	return l.write(func(s Store) error { return s.Delete(id) })
+}
+
+func (l *Live) DeleteByURL(url string) (n int, err error) {
+	err = l.write(func(s Store) error {
+		n, err = s.DeleteByURL(url)
+		return err
+	})
+	return n, err
+}
+
+func (l *Live) DeleteByURLPrefix(prefix string) (n int, err error) {
+	err = l.write(func(s Store) error {
+		n, err = s.DeleteByURLPrefix(prefix)
+		return err
+	})
+	return n, err
+}
+
+func (l *Live) Clear() error {
+	return l.write(func(s Store) error { return s.Clear() })
+}
+
+func (l *Live) SetMetric(metric vector.Metric) error {
+	return l.write(func(s Store) error { return s.SetMetric(metric) })
+}
+
+func (l *Live) SetQuantization(kind vector.Quantization) error {
+	return l.write(func(s Store) error { return s.SetQuantization(kind) })
+}
+
+func (l *Live) SetModel(modelID string) error {
+	return l.write(func(s Store) error { return s.SetModel(modelID) })
+}
+
+func (l *Live) Persist() error {
+	return l.write(func(s Store) error { return s.Persist() })
+}
+
+func (l *Live) GetDocument(id string) (*models.Document, error) {
+	st := l.acquire()
+	defer st.release()
+	return st.GetDocument(id)
+}
+
+func (l *Live) GetAllDocuments() []*models.Document {
+	st := l.acquire()
+	defer st.release()
+	return st.GetAllDocuments()
+}
+
+func (l *Live) Len() int {
+	st := l.acquire()
+	defer st.release()
+	return st.Len()
+}
+
+func (l *Live) GetDocumentsByURL(url string) []*models.Document {
+	st := l.acquire()
+	defer st.release()
+	return st.GetDocumentsByURL(url)
+}
+
+func (l *Live) URLs(prefix string) []string {
+	st := l.acquire()
+	defer st.release()
+	return st.URLs(prefix)
+}
+
+func (l *Live) Filter(f Filter) []*models.Document {
+	st := l.acquire()
+	defer st.release()
+	return st.Filter(f)
+}
+
+func (l *Live) SearchBySimilarity(queryEmbedding []float32, topK int) []*models.Document {
+	st := l.acquire()
+	defer st.release()
+	return st.SearchBySimilarity(queryEmbedding, topK)
+}
+
+func (l *Live) SearchWithScores(queryEmbedding []float32, topK int) []ScoredDocument {
+	st := l.acquire()
+	defer st.release()
+	return st.SearchWithScores(queryEmbedding, topK)
+}
+
+func (l *Live) SearchByKeyword(query string, topK int) ([]*models.Document, error) {
+	st := l.acquire()
+	defer st.release()
+	return st.SearchByKeyword(query, topK)
+}
+
+func (l *Live) Metadata() IndexMetadata {
+	st := l.acquire()
+	defer st.release()
+	return st.Metadata()
+}
+
+func (l *Live) Dir() string { return l.dir }
+
+func (l *Live) Snapshot(name, reason string, auto bool) (SnapshotInfo, error) {
+	st := l.acquire()
+	defer st.release()
+	return st.Snapshot(name, reason, auto)
+}
+
+// Close closes the current store once the calls running on it return
+func (l *Live) Close() error {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	l.mu.RLock()
+	st := l.cur
+	l.mu.RUnlock()
+	st.users.Wait()
+	return st.Close()
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+func TestLiveReloadsChangesOfOtherWriters(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite, BackendBolt} {
+		t.Run(backend, func(t *testing.T) {
+			c := NewCollections(t.TempDir())
+			if err := c.Create("docs", vector.Cosine, "", "hash/fnv-256", backend); err != nil {
+				t.Fatal(err)
+			}
+			reader, err := c.Open("docs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			live, err := NewLive(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer live.Close()
+
+			// Another process, e.g. a crawl, saves documents
+			writer, err := c.Open("docs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.SaveDocument(testDoc("a", "https://go.dev/doc/a", 1, 0, 0)); err != nil {
+				t.Fatal(err)
+			}
+			if n := len(live.GetAllDocuments()); n != 0 {
+				t.Fatalf("live store saw %d documents before reloading", n)
+			}
+			reloaded, err := live.ReloadIfChanged()
+			if err != nil || !reloaded {
+				t.Fatalf("ReloadIfChanged = %v, %v; want a reload", reloaded, err)
+			}
+			if hits := live.SearchWithScores([]float32{1, 0, 0}, 1); len(hits) != 1 || hits[0].Document.ID != "a" {
+				t.Errorf("search after reload = %+v, want a", hits)
+			}
+			if reloaded, err := live.ReloadIfChanged(); err != nil || reloaded {
+				t.Errorf("reloaded an unchanged store: %v, %v", reloaded, err)
+			}
+
+			// Its own saves do not make the live store reload
+			if err := live.SaveDocument(testDoc("b", "https://go.dev/doc/b", 0, 1, 0)); err != nil {
+				t.Fatal(err)
+			}
+			if reloaded, err := live.ReloadIfChanged(); err != nil || reloaded {
+				t.Errorf("reloaded after its own save: %v, %v", reloaded, err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// A migration swaps in an index of another model
+			shadow, err := OpenShadow(live, "hash/fnv-64", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := shadow.CopyDocuments([]*models.Document{testDoc("a", "https://go.dev/doc/a", 0, 0, 0, 1)}); err != nil {
+				t.Fatal(err)
+			}
+			shadow.Close()
+			if err := SwapShadow(live.Dir()); err != nil {
+				t.Fatal(err)
+			}
+			if reloaded, err := live.ReloadIfChanged(); err != nil || !reloaded {
+				t.Fatalf("ReloadIfChanged after the swap = %v, %v", reloaded, err)
+			}
+			if meta := live.Metadata(); meta.Model != "hash/fnv-64" || meta.Dimension != 4 {
+				t.Errorf("metadata after the swap = %+v", meta)
+			}
+		})
+	}
+}
+
+func TestLiveReloadsSaveRacingItsOwn(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite, BackendBolt} {
+		t.Run(backend, func(t *testing.T) {
+			c := NewCollections(t.TempDir())
+			if err := c.Create("docs", vector.Cosine, "", "hash/fnv-256", backend); err != nil {
+				t.Fatal(err)
+			}
+			opened, err := c.Open("docs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			live, err := NewLive(opened)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer live.Close()
+			other, err := c.Open("docs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer other.Close()
+
+			// Another process saves right after this one, before the write
+			// returns, and writes the same generation
+			err = live.write(func(s Store) error {
+				if err := s.SaveDocument(testDoc("a", "https://go.dev/doc/a", 1, 0, 0)); err != nil {
+					return err
+				}
+				return other.SaveDocument(testDoc("b", "https://go.dev/doc/b", 0, 1, 0))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if live.Metadata().Generation != other.Metadata().Generation {
+				t.Fatalf("generations %d and %d differ", live.Metadata().Generation, other.Metadata().Generation)
+			}
+			reloaded, err := live.ReloadIfChanged()
+			if err != nil || !reloaded {
+				t.Fatalf("ReloadIfChanged = %v, %v; want a reload", reloaded, err)
+			}
+			if _, err := live.GetDocument("b"); err != nil {
+				t.Errorf("the other save is missing after the reload: %v", err)
+			}
+		})
+	}
+}
+
+// TestWriterProcess is run by TestLiveFollowsWriterProcess as another process
+// saving to the store in $STORE_TEST_WRITER_DIR
+func TestWriterProcess(t *testing.T) {
+	dir := os.Getenv("STORE_TEST_WRITER_DIR")
+	if dir == "" {
+		t.Skip("run by TestLiveFollowsWriterProcess")
+	}
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveDocument(testDoc("crawled", "https://go.dev/doc/crawled", 0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLiveFollowsWriterProcess(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite, BackendBolt} {
+		t.Run(backend, func(t *testing.T) {
+			c := NewCollections(t.TempDir())
+			if err := c.Create("docs", vector.Cosine, "", "hash/fnv-256", backend); err != nil {
+				t.Fatal(err)
+			}
+			opened, err := c.Open("docs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			live, err := NewLive(opened)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer live.Close()
+			if err := live.SaveDocument(testDoc("a", "https://go.dev/doc/a", 1, 0, 0)); err != nil {
+				t.Fatal(err)
+			}
+
+			// A crawl in another process saves while the store is open here
+			cmd := exec.Command(os.Args[0], "-test.run=^TestWriterProcess$")
+			cmd.Env = append(os.Environ(), "STORE_TEST_WRITER_DIR="+live.Dir())
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("writer process failed: %v\n%s", err, out)
+			}
+			reloaded, err := live.ReloadIfChanged()
+			if err != nil || !reloaded {
+				t.Fatalf("ReloadIfChanged = %v, %v; want a reload", reloaded, err)
+			}
+			if hits := live.SearchWithScores([]float32{0, 0, 1}, 1); len(hits) != 1 || hits[0].Document.ID != "crawled" {
+				t.Errorf("search after reload = %+v, want the document of the other process", hits)
+			}
+			if n := len(live.GetAllDocuments()); n != 2 {
+				t.Errorf("reloaded %d documents, want 2", n)
+			}
+		})
+	}
+}
+
+func TestLiveReloadWaitsForRunningSearches(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSQLite(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveDocument(testDoc("old", "https://go.dev/doc/old", 1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	live, err := NewLive(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer live.Close()
+
+	// A search holds the old state while the store is reloaded
+	running := live.acquire()
+	reloaded := make(chan error)
+	go func() { reloaded <- live.Reload() }()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		live.mu.RLock()
+		swapped := live.cur != running
+		live.mu.RUnlock()
+		if swapped {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the reloaded store was not swapped in")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// New searches use the new state without waiting for the old one
+	if docs := live.GetAllDocuments(); len(docs) != 1 {
+		t.Errorf("search on the reloaded store found %d documents", len(docs))
+	}
+	select {
+	case err := <-reloaded:
+		t.Fatalf("Reload returned (%v) before the running search finished", err)
+	default:
+	}
+	if docs, err := running.SearchByKeyword("old", 1); err != nil || len(docs) != 1 {
+		t.Errorf("running search on the old state = %v, %v", docs, err)
+	}
+
+	running.release()
+	if err := <-reloaded; err != nil {
+		t.Fatal(err)
+	}
+}
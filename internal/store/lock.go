@@ -0,0 +1,97 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// lockFile is the file in a store directory that writers lock
+const lockFile = ".lock"
+
+// Lock is a lock on a store directory, shared between processes. Every save
+// holds it shared, so a migration holding it exclusively can replace the
+// store's files without losing documents other processes save meanwhile.
+// The exclusive lock belongs to the process: its own opens and saves of the
+// store proceed while it holds it.
+type Lock struct {
+	f       *os.File
+	release func()
+}
+
+var (
+	heldMu sync.Mutex
+	held   = make(map[string]int) // exclusive locks held by this process, by directory
+)
+
+// LockExclusive waits until no other process saves to the store in dir and
+// keeps them waiting until Unlock. Locking a directory the process already
+// holds succeeds at once.
+func LockExclusive(dir string) (*Lock, error) {
+	key, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+	}
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	release := func() {
+		heldMu.Lock()
+		held[key]--
+		heldMu.Unlock()
+	}
+	if held[key] > 0 {
+		held[key]++
+		return &Lock{release: release}, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := lockPath(filepath.Join(dir, lockFile), true)
+	if err != nil {
+		return nil, err
+	}
+	held[key]++
+	return &Lock{f: f, release: release}, nil
+}
+
+// lockShared locks the store in dir for a save or load. A directory that does
+// not exist yet has nothing to lock.
+func lockShared(dir string) (*Lock, error) {
+	key, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+	}
+	heldMu.Lock()
+	exclusive := held[key] > 0
+	heldMu.Unlock()
+	if exclusive {
+		return &Lock{}, nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return &Lock{}, nil
+	}
+	f, err := lockPath(filepath.Join(dir, lockFile), false)
+	if err != nil {
+		return nil, err
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	if l.release != nil {
+		l.release()
+		l.release = nil
+	}
+	if l.f == nil {
+		return nil
+	}
+	err := unlockFile(l.f)
+	if closeErr := l.f.Close(); err == nil {
+		err = closeErr
+	}
+	l.f = nil
+	return err
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"ollama_go/internal/vector"
+)
+
+// quantizedRescore is how many candidates per requested result a quantized
+// search re-ranks against the full-precision vectors
+const quantizedRescore = 4
+
+// searchQuantized scans the compressed vectors for topK·quantizedRescore
+// candidates and re-ranks them by their full-precision vectors read from
+// disk. The caller holds ds.mu for reading.
+func (ds *DocumentStore) searchQuantized(queryEmbedding []float32, topK int) []vector.Result {
+	if topK <= 0 || len(queryEmbedding) != ds.quantized.Dim() {
+		return nil
+	}
+	hits, err := ds.quantized.Search(queryEmbedding, topK)
+	if err != nil {
+		log.Printf("⚠️  Quantized search failed: %v", err)
+		return nil
+	}
+	return hits
+}
+
+// searchDisk scans the vectors in the backend's file. The caller holds ds.mu
+// for reading.
+func (ds *DocumentStore) searchDisk(queryEmbedding []float32, topK int) []vector.Result {
+	if topK <= 0 || len(queryEmbedding) != ds.meta.Dimension {
+		return nil
+	}
+	hits, err := ds.diskVectors.search(queryEmbedding, min(topK, len(ds.ids)), ds.meta.Metric, ds.positions)
+	if err != nil {
+		log.Printf("⚠️  Search failed: %v", err)
+		return nil
+	}
+	return hits
+}
+
+// vectorAt returns the embedding at position pos of the search index. From
+// the exact index it shares memory with the index; the caller holds ds.mu.
+func (ds *DocumentStore) vectorAt(pos int) ([]float32, error) {
+	switch {
+	case ds.quantized != nil:
+		return ds.quantized.Vector(pos)
+	case ds.diskVectors != nil:
+		vectors, err := ds.diskVectors.vectors([]string{ds.ids[pos]})
+		if err != nil {
+			return nil, err
+		}
+		return vectors[0], nil
+	}
+	return ds.index.Vector(pos), nil
+}
+
+// addVector appends an embedding to the search index and returns its
+// position. Vectors the backend serves are already saved.
+func (ds *DocumentStore) addVector(embedding []float32) (int, error) {
+	switch {
+	case ds.quantized != nil:
+		return ds.quantized.Add(embedding)
+	case ds.diskVectors != nil:
+		return len(ds.ids), nil
+	}
+	if ds.index == nil {
+		ds.index = vector.NewFlat(len(embedding), ds.meta.Metric)
+	}
+	return ds.index.Add(embedding)
+}
+
+// setVector replaces the embedding at position pos of the search index
+func (ds *DocumentStore) setVector(pos int, embedding []float32) error {
+	switch {
+	case ds.quantized != nil:
+		return ds.quantized.Set(pos, embedding)
+	case ds.diskVectors != nil:
+		return nil
+	}
+	return ds.index.Set(pos, embedding)
+}
+
+// removeVector removes the embedding at position pos of the search index,
+// moving the last one into its place. It returns the old position of the
+// moved embedding, or -1 if pos was the last one.
+func (ds *DocumentStore) removeVector(pos int) (int, error) {
+	switch {
+	case ds.quantized != nil:
+		return ds.quantized.Remove(pos)
+	case ds.diskVectors != nil:
+		if last := len(ds.ids) - 1; pos != last {
+			return last, nil
+		}
+		return -1, nil
+	}
+	return ds.index.Remove(pos), nil
+}
+
+// updateQuantized brings the search index in line with the quantization in
+// the metadata: it moves exact vectors into a quantized index or back, and
+// retrains a quantized index that outgrew the sample it was trained on, so
+// searches never have to. The caller holds ds.mu for writing.
+func (ds *DocumentStore) updateQuantized() error {
+	if ds.quantized != nil && ds.quantized.Kind() != ds.meta.Quantization {
+		if err := ds.unquantize(); err != nil {
+			return err
+		}
+	}
+	switch {
+	case ds.meta.Quantization == "":
+		return nil
+	case ds.quantized != nil && ds.quantized.Undertrained():
+		if err := ds.quantized.Retrain(); err != nil {
+			return fmt.Errorf("failed to retrain the %s index: %w", ds.meta.Quantization, err)
+		}
+	case ds.quantized == nil && len(ds.ids) > 0:
+		return ds.quantize()
+	}
+	return nil
+}
+
+// quantize moves the exact vectors, or those the backend serves, into a
+// quantized index. The full-precision vectors go to a file of this store
+// only, read back to re-rank candidates, so memory holds just the codes.
+func (ds *DocumentStore) quantize() error {
+	var vectors [][]float32
+	if ds.diskVectors != nil {
+		var err error
+		if vectors, err = ds.diskVectors.vectors(ds.ids); err != nil {
+			return err
+		}
+	} else {
+		vectors = make([][]float32, ds.index.Len())
+		for i := range vectors {
+			vectors[i] = ds.index.Vector(i)
+		}
+	}
+
+	if err := os.MkdirAll(ds.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.CreateTemp(ds.dir, ".vectors-*.raw")
+	if err != nil {
+		return fmt.Errorf("failed to create vector file: %w", err)
+	}
+	f.Close()
+	ix, err := vector.NewQuantizedIndex(vectors, f.Name(), vector.QuantizeOptions{Kind: ds.meta.Quantization, Rescore: quantizedRescore})
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to build the %s index: %w", ds.meta.Quantization, err)
+	}
+
+	// The open file outlives its name where the platform allows it, so a
+	// crash leaves nothing behind
+	ds.rawPath = ""
+	if err := os.Remove(f.Name()); err != nil {
+		ds.rawPath = f.Name()
+	}
+	ds.index, ds.quantized = nil, ix
+	return nil
+}
+
+// unquantize reads the full-precision vectors back into an exact index, or
+// leaves them to the backend that serves them
+func (ds *DocumentStore) unquantize() error {
+	if ds.diskVectors != nil {
+		ds.closeQuantized()
+		return nil
+	}
+	index := vector.NewFlat(ds.quantized.Dim(), ds.meta.Metric)
+	for i := 0; i < ds.quantized.Len(); i++ {
+		v, err := ds.quantized.Vector(i)
+		if err != nil {
+			return fmt.Errorf("failed to read the quantized vectors: %w", err)
+		}
+		if _, err := index.Add(v); err != nil {
+			return err
+		}
+	}
+	ds.closeQuantized()
+	ds.index = index
+	return nil
+}
+
+// closeQuantized releases the quantized index and its vector file
+func (ds *DocumentStore) closeQuantized() {
+	if ds.quantized == nil {
+		return
+	}
+	if err := ds.quantized.Close(); err != nil {
+		log.Printf("⚠️  Failed to close the quantized index: %v", err)
+	}
+	if ds.rawPath != "" {
+		os.Remove(ds.rawPath)
+	}
+	ds.quantized, ds.rawPath = nil, ""
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ShadowDir returns the directory of the shadow index of the store in dir: a
+// complete second index, built next to the live one by a migration such as
+// re-embedding and swapped in by SwapShadow when it is complete
+func ShadowDir(dir string) string {
+	return filepath.Join(dir, "shadow")
+}
+
+// OpenShadow opens the shadow index of s for documents embedded with model,
+// using the metric and backend of s. Without resume any earlier shadow index
+// is discarded; with resume it is reopened so its documents need not be
+// embedded again, and must have been started for the same model.
+func OpenShadow(s Store, model string, resume bool) (Store, error) {
+	meta := s.Metadata()
+	shadow := ShadowDir(s.Dir())
+
+	existing, ok, err := readMetadata(filepath.Join(shadow, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+	if resume && !ok {
+		return nil, fmt.Errorf("%w: no shadow index to resume in %s", ErrNotFound, shadow)
+	}
+	if resume && existing.Model != model {
+		return nil, fmt.Errorf("the shadow index in %s is being built for %s, not %s", shadow, existing.Model, model)
+	}
+	if !resume {
+		if err := os.RemoveAll(shadow); err != nil {
+			return nil, fmt.Errorf("failed to remove the old shadow index: %w", err)
+		}
+	}
+
+	ds, err := openBackend(shadow, meta.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the shadow index: %w", err)
+	}
+	if err := ds.SetMetric(meta.Metric); err != nil {
+		ds.Close()
+		return nil, err
+	}
+	if err := ds.SetQuantization(meta.Quantization); err != nil {
+		ds.Close()
+		return nil, err
+	}
+	if err := ds.SetModel(model); err != nil {
+		ds.Close()
+		return nil, err
+	}
+	if err := ds.Persist(); err != nil {
+		ds.Close()
+		return nil, err
+	}
+	return ds, nil
+}
+
+// SwapShadow replaces the store in dir with its shadow index, holding the
+// exclusive lock of dir. Neither store may be open. The shadow directory is
+// renamed in one step, so a crash leaves either the old store or a swap that
+// the next Open finishes.
+func SwapShadow(dir string) error {
+	shadow := ShadowDir(dir)
+	if _, err := os.Stat(filepath.Join(shadow, "index.json")); err != nil {
+		return fmt.Errorf("%w: no shadow index in %s", ErrNotFound, shadow)
+	}
+	return replaceStore(dir, shadow)
+}
+
+// swapDir holds the files of a store that is replacing the one in its parent
+// directory, and swapManifest lists them
+const (
+	swapDir      = ".swap"
+	swapManifest = "swap.json"
+)
+
+// replaceStore replaces the store files in dir with those of the complete
+// store in src, which is moved away. Renaming src to dir/.swap commits the
+// replacement; the files are then moved into place by finishSwap.
+func replaceStore(dir, src string) error {
+	lock, err := LockExclusive(dir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	var files []string
+	for file := range storeFiles {
+		if _, err := os.Stat(filepath.Join(src, file)); err == nil {
+			files = append(files, file)
+		}
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the swap manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, swapManifest), data, 0644); err != nil {
+		return fmt.Errorf("failed to write the swap manifest: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, swapDir)); err != nil {
+		return fmt.Errorf("failed to remove an old swap: %w", err)
+	}
+	if err := os.Rename(src, filepath.Join(dir, swapDir)); err != nil {
+		return fmt.Errorf("failed to swap in %s: %w", src, err)
+	}
+	return finishSwap(dir)
+}
+
+// swapPending reports whether a committed swap waits to be finished in dir
+func swapPending(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, swapDir, swapManifest))
+	return err == nil
+}
+
+// finishSwap moves the files of a committed swap into dir. It can run again
+// after a crash until it completes. The caller holds the exclusive lock.
+func finishSwap(dir string) error {
+	swap := filepath.Join(dir, swapDir)
+	data, err := os.ReadFile(filepath.Join(swap, swapManifest))
+	if os.IsNotExist(err) {
+		// Not committed yet: the manifest is written before the rename
+		return os.RemoveAll(swap)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the swap manifest: %w", err)
+	}
+	var files []string
+	if err := json.Unmarshal(data, &files); err != nil {
+		return fmt.Errorf("failed to unmarshal the swap manifest: %w", err)
+	}
+
+	// Files the new store lacks go first, e.g. the write-ahead log of the old
+	// SQLite database, which must not be applied to the new one
+	for file := range storeFiles {
+		if slices.Contains(files, file) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, file)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+	}
+	// index.json last, so the metadata only changes once the documents did
+	for _, file := range append(slices.DeleteFunc(files, func(f string) bool { return f == "index.json" }), "index.json") {
+		err := os.Rename(filepath.Join(swap, file), filepath.Join(dir, file))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to swap in %s: %w", file, err)
+		}
+	}
+	if err := os.RemoveAll(swap); err != nil {
+		return fmt.Errorf("failed to remove the swap directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+// buildShadow creates a SQLite collection with a complete shadow index for
+// another model and returns its directory
+func buildShadow(t *testing.T) string {
+	t.Helper()
+	c := NewCollections(t.TempDir())
+	if err := c.Create("docs", vector.Cosine, "", "hash/fnv-256", BackendSQLite); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := c.Dir("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.SaveDocument(testDoc("a", "https://go.dev/doc/a", 1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	shadow, err := OpenShadow(s, "hash/fnv-64", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shadow.Close()
+	if err := shadow.CopyDocuments([]*models.Document{testDoc("a", "https://go.dev/doc/a", 0, 0, 0, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestOpenFinishesInterruptedSwap(t *testing.T) {
+	dir := buildShadow(t)
+
+	// Crash after the commit, with only the database moved
+	shadow := ShadowDir(dir)
+	if err := os.WriteFile(filepath.Join(shadow, swapManifest), []byte(`["documents.db","index.json"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(shadow, filepath.Join(dir, swapDir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, swapDir, "documents.db"), filepath.Join(dir, "documents.db")); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if meta := s.Metadata(); meta.Model != "hash/fnv-64" || meta.Dimension != 4 {
+		t.Errorf("metadata after finishing the swap = %+v", meta)
+	}
+	if hits := s.SearchWithScores([]float32{0, 0, 0, 1}, 1); len(hits) != 1 || hits[0].Document.ID != "a" {
+		t.Errorf("search after finishing the swap = %+v", hits)
+	}
+	if _, err := os.Stat(filepath.Join(dir, swapDir)); !os.IsNotExist(err) {
+		t.Errorf("swap directory left behind: %v", err)
+	}
+}
+
+func TestOpenIgnoresUncommittedSwap(t *testing.T) {
+	dir := buildShadow(t)
+
+	// Crash while the shadow was renamed, before the manifest was written
+	if err := os.Rename(ShadowDir(dir), filepath.Join(dir, swapDir)); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if meta := s.Metadata(); meta.Model != "hash/fnv-256" {
+		t.Errorf("opened %+v, want the old store", meta)
+	}
+}
+
+func TestSaveToReplacedStoreFails(t *testing.T) {
+	dir := buildShadow(t)
+
+	// A process that opened the store before the swap
+	stale, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stale.Close()
+	if err := SwapShadow(dir); err != nil {
+		t.Fatal(err)
+	}
+	err = stale.SaveDocument(testDoc("b", "https://go.dev/doc/b", 0, 1, 0))
+	if !errors.Is(err, ErrReplaced) {
+		t.Fatalf("save after the swap: got %v, want ErrReplaced", err)
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if n := len(s.GetAllDocuments()); n != 1 {
+		t.Errorf("swapped store holds %d documents, want 1", n)
+	}
+}
+
+func TestExclusiveLockBlocksSaves(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := LockExclusive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process locks the file through its own descriptor
+	locked := make(chan error)
+	go func() {
+		f, err := lockPath(filepath.Join(dir, lockFile), false)
+		if err == nil {
+			err = f.Close()
+		}
+		locked <- err
+	}()
+	select {
+	case err := <-locked:
+		t.Fatalf("shared lock taken while the exclusive lock is held: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shared lock not taken after the exclusive lock was released")
+	}
+}
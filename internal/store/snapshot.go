@@ -0,0 +1,311 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SnapshotInfo describes a snapshot of a store, kept in dir/snapshots/<name>
+type SnapshotInfo struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"` // why it was taken, e.g. "manual" or "crawl"
+	Auto      bool      `json:"auto"`   // taken automatically and subject to retention
+	Documents int       `json:"documents"`
+	Backend   string    `json:"backend,omitempty"`
+}
+
+// Retention limits the automatic snapshots kept. Manual snapshots are only
+// deleted explicitly.
+type Retention struct {
+	Keep   int           // newest automatic snapshots to keep, 0 for no limit
+	MaxAge time.Duration // automatic snapshots older than this are deleted, 0 for no limit
+}
+
+// DefaultRetention applies after every automatic snapshot
+var DefaultRetention = Retention{Keep: 10, MaxAge: 30 * 24 * time.Hour}
+
+// snapshotInfoFile describes the snapshot in its directory
+const snapshotInfoFile = "snapshot.json"
+
+// storeFiles are the files a store may keep in its directory. Files that are
+// only ever replaced by rename can be hard-linked into snapshots; the others
+// are changed in place and must be copied.
+var storeFiles = map[string]bool{
+	"index.json":       true,
+	"documents.json":   true,
+	"documents.db":     false,
+	"documents.db-wal": false,
+	"documents.db-shm": false,
+	"documents.bolt":   false,
+}
+
+func snapshotsDir(dir string) string {
+	return filepath.Join(dir, "snapshots")
+}
+
+// Snapshot saves a consistent copy of the store as dir/snapshots/<name>.
+// An empty name is generated from the time and reason. The JSON backend
+// hard-links its files, since they are only ever replaced, never changed;
+// SQLite and bolt databases are copied from a read transaction.
+func (ds *DocumentStore) Snapshot(name, reason string, auto bool) (SnapshotInfo, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	now := time.Now().UTC()
+	if name == "" {
+		name = uniqueSnapshotName(ds.dir, now.Format("20060102-150405")+"-"+reason)
+	}
+	if !validName.MatchString(name) {
+		return SnapshotInfo{}, fmt.Errorf("invalid snapshot name %q: use lowercase letters, digits, - and _", name)
+	}
+	final := filepath.Join(snapshotsDir(ds.dir), name)
+	if _, err := os.Stat(final); err == nil {
+		return SnapshotInfo{}, fmt.Errorf("snapshot %s already exists", name)
+	}
+
+	// Build the snapshot next to its final place and rename it when complete
+	tmp := filepath.Join(snapshotsDir(ds.dir), ".tmp-"+name)
+	if err := os.RemoveAll(tmp); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := ds.backend.snapshot(tmp); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to snapshot documents: %w", err)
+	}
+	if err := linkOrCopy(ds.metaPath, filepath.Join(tmp, "index.json"), true); err != nil && !os.IsNotExist(err) {
+		return SnapshotInfo{}, fmt.Errorf("failed to snapshot index metadata: %w", err)
+	}
+
+	backend := ds.meta.Backend
+	if backend == "" {
+		backend = BackendJSON
+	}
+	info := SnapshotInfo{
+		Name:      name,
+		CreatedAt: now,
+		Reason:    reason,
+		Auto:      auto,
+		Documents: len(ds.documents),
+		Backend:   backend,
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to marshal snapshot info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, snapshotInfoFile), data, 0644); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to write snapshot info: %w", err)
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	return info, nil
+}
+
+// uniqueSnapshotName appends a counter to base if a snapshot has that name
+func uniqueSnapshotName(dir, base string) string {
+	name := base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(snapshotsDir(dir), name)); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// AutoSnapshot snapshots a non-empty store before a destructive operation
+// and prunes old automatic snapshots by r. It returns nil for an empty store.
+func AutoSnapshot(s Store, reason string, r Retention) (*SnapshotInfo, []string, error) {
+	if s.Len() == 0 {
+		return nil, nil, nil
+	}
+	info, err := s.Snapshot("", reason, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	pruned, err := PruneSnapshots(s.Dir(), r)
+	return &info, pruned, err
+}
+
+// ListSnapshots returns the snapshots of the store in dir, oldest first
+func ListSnapshots(dir string) ([]SnapshotInfo, error) {
+	entries, err := os.ReadDir(snapshotsDir(dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var infos []SnapshotInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := readSnapshotInfo(dir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b SnapshotInfo) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return infos, nil
+}
+
+// SnapshotAt returns the newest snapshot taken at or before t
+func SnapshotAt(dir string, t time.Time) (SnapshotInfo, error) {
+	infos, err := ListSnapshots(dir)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	for i := len(infos) - 1; i >= 0; i-- {
+		if !infos[i].CreatedAt.After(t) {
+			return infos[i], nil
+		}
+	}
+	return SnapshotInfo{}, fmt.Errorf("%w: no snapshot taken before %s", ErrNotFound, t.Format(time.RFC3339))
+}
+
+func readSnapshotInfo(dir, name string) (SnapshotInfo, error) {
+	data, err := os.ReadFile(filepath.Join(snapshotsDir(dir), name, snapshotInfoFile))
+	if os.IsNotExist(err) {
+		return SnapshotInfo{}, fmt.Errorf("%w: snapshot %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to read snapshot %s: %w", name, err)
+	}
+	var info SnapshotInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to unmarshal snapshot %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// RestoreSnapshot replaces the store in dir with the named snapshot. The
+// snapshot is copied next to the store first and swapped in like a shadow
+// index, so a failed copy leaves the store as it was. The store must not be
+// open.
+func RestoreSnapshot(dir, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("%w: snapshot %s", ErrNotFound, name)
+	}
+	if _, err := readSnapshotInfo(dir, name); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, ".restore")
+	if err := os.RemoveAll(tmp); err != nil {
+		return fmt.Errorf("failed to remove an old restore: %w", err)
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	src := filepath.Join(snapshotsDir(dir), name)
+	for file, linkable := range storeFiles {
+		err := linkOrCopy(filepath.Join(src, file), filepath.Join(tmp, file), linkable)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to restore %s: %w", file, err)
+		}
+	}
+	return replaceStore(dir, tmp)
+}
+
+// DeleteSnapshot deletes the named snapshot of the store in dir
+func DeleteSnapshot(dir, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("%w: snapshot %s", ErrNotFound, name)
+	}
+	if _, err := readSnapshotInfo(dir, name); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(snapshotsDir(dir), name)); err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+// PruneSnapshots deletes the automatic snapshots of the store in dir that r
+// does not keep, and returns their names
+func PruneSnapshots(dir string, r Retention) ([]string, error) {
+	infos, err := ListSnapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []string
+	kept := 0
+	for i := len(infos) - 1; i >= 0; i-- {
+		info := infos[i]
+		if !info.Auto {
+			continue
+		}
+		tooMany := r.Keep > 0 && kept >= r.Keep
+		tooOld := r.MaxAge > 0 && time.Since(info.CreatedAt) > r.MaxAge
+		if !tooMany && !tooOld {
+			kept++
+			continue
+		}
+		if err := DeleteSnapshot(dir, info.Name); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, info.Name)
+	}
+	return pruned, nil
+}
+
+// linkOrCopy hard-links src to dst if link is set and the filesystem allows
+// it, and copies it otherwise
+func linkOrCopy(src, dst string, link bool) error {
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	if link && os.Link(src, dst) == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeFileAtomic replaces path with data by renaming a temporary file, so
+// readers and hard-linked snapshots never see a partly written file. It
+// returns the file it wrote, which another writer may replace right away.
+func writeFileAtomic(path string, data []byte) (os.FileInfo, error) {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return nil, errors.Join(err, os.Remove(tmp))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, errors.Join(err, os.Remove(tmp))
+	}
+	return info, nil
+}
@@ -0,0 +1,226 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite, BackendBolt} {
+		t.Run(backend, func(t *testing.T) {
+			c := NewCollections(t.TempDir())
+			if err := c.Create("docs", vector.Cosine, "", "hash/fnv-256", backend); err != nil {
+				t.Fatal(err)
+			}
+			s, err := c.Open("docs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := s.Dir()
+
+			docs := []*models.Document{
+				testDoc("a", "https://go.dev/doc/a", 1, 0, 0),
+				testDoc("b", "https://go.dev/doc/b", 0, 1, 0),
+			}
+			if err := s.SaveDocuments(docs); err != nil {
+				t.Fatal(err)
+			}
+			info, err := s.Snapshot("before", "manual", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Documents != 2 || info.Backend != backend {
+				t.Errorf("snapshot info = %+v", info)
+			}
+			if _, err := s.Snapshot("before", "manual", false); err == nil {
+				t.Error("created two snapshots with the same name")
+			}
+
+			// Changes after the snapshot must not leak into it
+			if _, err := s.DeleteByURLPrefix("https://go.dev/doc/"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SaveDocument(testDoc("c", "https://go.dev/doc/c", 0, 0, 1)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := RestoreSnapshot(dir, "before"); err != nil {
+				t.Fatal(err)
+			}
+			restored, err := c.Open("docs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer restored.Close()
+
+			var ids []string
+			for _, doc := range restored.GetAllDocuments() {
+				ids = append(ids, doc.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, []string{"a", "b"}) {
+				t.Errorf("restored documents = %v, want [a b]", ids)
+			}
+			if _, ok := restored.(*DocumentStore); ok != (backend == BackendJSON) {
+				t.Errorf("restored a %T, want the %s backend", restored, backend)
+			}
+			if meta := restored.Metadata(); meta.Model != "hash/fnv-256" {
+				t.Errorf("restored metadata = %+v", meta)
+			}
+			results := restored.SearchWithScores([]float32{0, 1, 0}, 1)
+			if len(results) != 1 || results[0].Document.ID != "b" {
+				t.Errorf("search after restore = %+v, want b", results)
+			}
+
+			// The snapshot survives the restore and later writes
+			if err := restored.Clear(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readSnapshotInfo(dir, "before"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestFailedRestoreKeepsStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSQLite(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveDocument(testDoc("a", "https://go.dev/doc/a", 1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Snapshot("broken", "manual", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveDocument(testDoc("b", "https://go.dev/doc/b", 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// The database of the snapshot cannot be copied
+	db := filepath.Join(snapshotsDir(dir), "broken", "documents.db")
+	if err := os.Remove(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(db, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreSnapshot(dir, "broken"); err == nil {
+		t.Fatal("restored a snapshot that cannot be copied")
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if n := len(reopened.GetAllDocuments()); n != 2 {
+		t.Errorf("store holds %d documents after the failed restore, want 2", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".restore")); !os.IsNotExist(err) {
+		t.Errorf("restore directory left behind: %v", err)
+	}
+}
+
+func TestAutoSnapshotSkipsEmptyStore(t *testing.T) {
+	s := NewDocumentStoreAt(t.TempDir())
+	info, _, err := AutoSnapshot(s, "crawl", DefaultRetention)
+	if err != nil || info != nil {
+		t.Fatalf("AutoSnapshot of an empty store = %v, %v; want nothing", info, err)
+	}
+	if err := s.SaveDocument(testDoc("a", "https://go.dev/doc/a", 1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	info, _, err = AutoSnapshot(s, "crawl", DefaultRetention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil || !info.Auto || info.Reason != "crawl" {
+		t.Fatalf("AutoSnapshot = %+v", info)
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	s := NewDocumentStoreAt(dir)
+	if err := s.SaveDocument(testDoc("a", "https://go.dev/doc/a", 1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Snapshot("keep-me", "manual", false); err != nil {
+		t.Fatal(err)
+	}
+	for range 4 {
+		if _, err := s.Snapshot("", "crawl", true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Age the oldest automatic snapshot
+	infos, err := ListSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 5 {
+		t.Fatalf("got %d snapshots, want 5", len(infos))
+	}
+	old := infos[1]
+	old.CreatedAt = time.Now().Add(-48 * time.Hour)
+	writeSnapshotInfo(t, dir, old)
+
+	pruned, err := PruneSnapshots(dir, Retention{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pruned, []string{old.Name}) {
+		t.Errorf("pruned by age = %v, want [%s]", pruned, old.Name)
+	}
+
+	pruned, err = PruneSnapshots(dir, Retention{Keep: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 2 {
+		t.Errorf("pruned by count = %v, want 2 snapshots", pruned)
+	}
+	infos, err = ListSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name != "keep-me" || !infos[1].Auto {
+		t.Errorf("remaining snapshots = %+v, want the manual one and the newest automatic one", infos)
+	}
+
+	if err := DeleteSnapshot(dir, "keep-me"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteSnapshot(dir, "keep-me"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a missing snapshot = %v, want ErrNotFound", err)
+	}
+	if _, err := SnapshotAt(dir, time.Now().Add(-time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SnapshotAt before every snapshot = %v, want ErrNotFound", err)
+	}
+}
+
+func writeSnapshotInfo(t *testing.T, dir string, info SnapshotInfo) {
+	t.Helper()
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(snapshotsDir(dir), info.Name, snapshotInfoFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,239 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ollama_go/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema keeps documents and their metadata in tables, embeddings as
+// little-endian float32 blobs, and a full-text index for keyword search
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS documents (
+	id          TEXT PRIMARY KEY,
+	url         TEXT NOT NULL,
+	title       TEXT NOT NULL,
+	description TEXT NOT NULL,
+	content     TEXT NOT NULL,
+	created_at  TEXT NOT NULL,
+	version     INTEGER NOT NULL,
+	embedding   BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS documents_url ON documents(url);
+CREATE TABLE IF NOT EXISTS document_metadata (
+	document_id TEXT NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+	key         TEXT NOT NULL,
+	value       TEXT NOT NULL,
+	PRIMARY KEY (document_id, key)
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(id UNINDEXED, title, description, content);
+`
+
+// SQLiteStore is a DocumentStore saved in a SQLite database (documents.db)
+// instead of a JSON file. Every operation commits in one transaction, and
+// keyword search runs on SQLite's FTS5 index. The database can be
+// inspected with any SQLite client.
+type SQLiteStore struct {
+	*DocumentStore
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the SQLite store in dir and loads its
+// documents
+func OpenSQLite(dir string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	path := filepath.Join(dir, "documents.db")
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create the schema of %s: %w", path, err)
+	}
+
+	s := &SQLiteStore{DocumentStore: newDocumentStore(dir, &sqliteBackend{db: db}), db: db}
+	if err := s.LoadFromDisk(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.meta.Backend = BackendSQLite
+	return s, nil
+}
+
+// SearchByKeyword returns the topK documents best matching the words of
+// query, ranked by FTS5's BM25 over title, description and content
+func (s *SQLiteStore) SearchByKeyword(query string, topK int) ([]*models.Document, error) {
+	// Quote every term so punctuation cannot form FTS5 query syntax
+	terms := uniqueTerms(tokenize(query))
+	if len(terms) == 0 || topK <= 0 {
+		return []*models.Document{}, nil
+	}
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+
+	rows, err := s.db.Query(`SELECT id FROM documents_fts WHERE documents_fts MATCH ? ORDER BY bm25(documents_fts) LIMIT ?`,
+		strings.Join(terms, " OR "), topK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search keywords: %w", err)
+	}
+	defer rows.Close()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs := make([]*models.Document, 0, topK)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to search keywords: %w", err)
+		}
+		if doc, ok := s.documents[id]; ok {
+			docs = append(docs, copyDocument(doc))
+		}
+	}
+	return docs, rows.Err()
+}
+
+// sqliteBackend saves each operation of a DocumentStore in one transaction
+type sqliteBackend struct {
+	db *sql.DB
+}
+
+func (b *sqliteBackend) load() ([]*models.Document, error) {
+	// One read transaction, so documents and metadata come from the same commit
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, url, title, description, content, created_at, version, embedding FROM documents`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*models.Document
+	byID := make(map[string]*models.Document)
+	for rows.Next() {
+		doc := &models.Document{}
+		var createdAt string
+		var blob []byte
+		if err := rows.Scan(&doc.ID, &doc.URL, &doc.Title, &doc.Description, &doc.Content, &createdAt, &doc.Version, &blob); err != nil {
+			return nil, fmt.Errorf("failed to read documents: %w", err)
+		}
+		if err := doc.CreatedAt.UnmarshalText([]byte(createdAt)); err != nil {
+			return nil, fmt.Errorf("document %s has an invalid creation time: %w", doc.ID, err)
+		}
+		if doc.Embedding, err = decodeVector(blob); err != nil {
+			return nil, fmt.Errorf("document %s: %w", doc.ID, err)
+		}
+		docs = append(docs, doc)
+		byID[doc.ID] = doc
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+
+	meta, err := tx.Query(`SELECT document_id, key, value FROM document_metadata`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document metadata: %w", err)
+	}
+	defer meta.Close()
+	for meta.Next() {
+		var id, key, value string
+		if err := meta.Scan(&id, &key, &value); err != nil {
+			return nil, fmt.Errorf("failed to read document metadata: %w", err)
+		}
+		if doc, ok := byID[id]; ok {
+			if doc.Metadata == nil {
+				doc.Metadata = make(map[string]string)
+			}
+			doc.Metadata[key] = value
+		}
+	}
+	return docs, meta.Err()
+}
+
+func (b *sqliteBackend) save(_ func() ([]*models.Document, error), changed []*models.Document, removed []string) error {
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range removed {
+		if err := deleteRow(tx, id); err != nil {
+			return err
+		}
+	}
+	for _, doc := range changed {
+		if err := deleteRow(tx, doc.ID); err != nil {
+			return err
+		}
+		if err := insertRow(tx, doc); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit documents: %w", err)
+	}
+	return nil
+}
+
+// snapshot copies the database with VACUUM INTO, which reads it in one
+// transaction
+func (b *sqliteBackend) snapshot(dir string) error {
+	_, err := b.db.Exec(`VACUUM INTO ?`, filepath.Join(dir, "documents.db"))
+	return err
+}
+
+func (b *sqliteBackend) close() error {
+	return b.db.Close()
+}
+
+// deleteRow removes a document, its metadata and its full-text entry
+func deleteRow(tx *sql.Tx, id string) error {
+	if _, err := tx.Exec(`DELETE FROM documents WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete document %s: %w", id, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM documents_fts WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete document %s: %w", id, err)
+	}
+	return nil
+}
+
+func insertRow(tx *sql.Tx, doc *models.Document) error {
+	createdAt, err := doc.CreatedAt.MarshalText()
+	if err != nil {
+		return fmt.Errorf("document %s has an invalid creation time: %w", doc.ID, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO documents (id, url, title, description, content, created_at, version, embedding) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		doc.ID, doc.URL, doc.Title, doc.Description, doc.Content, string(createdAt), doc.Version, encodeVector(doc.Embedding)); err != nil {
+		return fmt.Errorf("failed to save document %s: %w", doc.ID, err)
+	}
+	for key, value := range doc.Metadata {
+		if _, err := tx.Exec(`INSERT INTO document_metadata (document_id, key, value) VALUES (?, ?, ?)`, doc.ID, key, value); err != nil {
+			return fmt.Errorf("failed to save metadata of document %s: %w", doc.ID, err)
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO documents_fts (id, title, description, content) VALUES (?, ?, ?, ?)`,
+		doc.ID, doc.Title, doc.Description, doc.Content); err != nil {
+		return fmt.Errorf("failed to index document %s: %w", doc.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSQLite(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetMetric(vector.L2); err != nil {
+		t.Fatal(err)
+	}
+
+	goroutines := testDoc("goroutines", "https://go.dev/doc/effective_go", 1, 0, 0)
+	goroutines.Content = "A goroutine is a lightweight thread; channels connect goroutines."
+	goroutines.Metadata = map[string]string{"chunk": "0", "section": "concurrency"}
+	maps := testDoc("maps", "https://go.dev/doc/effective_go", 0, 1, 0)
+	maps.Content = "Maps associate keys with values."
+	maps.Metadata = map[string]string{"chunk": "1"}
+	faq := testDoc("faq", "https://go.dev/doc/faq", 0, 0, 1)
+	if err := s.SaveDocuments([]*models.Document{goroutines, maps, faq}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Upsert(testDoc("faq", "https://go.dev/doc/faq", 0, 1, 1), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("maps"); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := s.SearchByKeyword("goroutine channels?", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].ID != "goroutines" {
+		t.Errorf("keyword search found %v, want the goroutines document", docs)
+	}
+	if docs, _ := s.SearchByKeyword("maps", 5); len(docs) != 0 {
+		t.Errorf("deleted document still found by keyword: %v", docs)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if _, ok := reopened.(*SQLiteStore); !ok {
+		t.Fatalf("Open returned a %T, want the SQLite backend recorded in the metadata", reopened)
+	}
+	if meta := reopened.Metadata(); meta.Metric != vector.L2 || meta.Dimension != 3 {
+		t.Errorf("reopened with metadata %+v", meta)
+	}
+
+	got, err := reopened.GetDocument("goroutines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Content != goroutines.Content || got.Metadata["section"] != "concurrency" || !slices.Equal(got.Embedding, goroutines.Embedding) {
+		t.Errorf("reloaded %+v, want %+v", got, goroutines)
+	}
+	if faq, _ := reopened.GetDocument("faq"); faq.Version != 2 {
+		t.Errorf("faq reloaded at version %d, want 2", faq.Version)
+	}
+	if _, err := reopened.GetDocument("maps"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted document reloaded: %v", err)
+	}
+	if hits := reopened.SearchBySimilarity([]float32{0, 1, 1}, 1); len(hits) != 1 || hits[0].ID != "faq" {
+		t.Errorf("vector search after reload found %v", hits)
+	}
+	if filtered := reopened.Filter(Filter{Metadata: map[string]string{"section": "concurrency"}}); len(filtered) != 1 || filtered[0].ID != "goroutines" {
+		t.Errorf("filter found %v", filtered)
+	}
+
+	if err := reopened.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	var rows int
+	if err := reopened.(*SQLiteStore).db.QueryRow(`SELECT count(*) FROM documents`).Scan(&rows); err != nil || rows != 0 {
+		t.Errorf("%d rows left after Clear (%v)", rows, err)
+	}
+	if meta := reopened.Metadata(); meta.Metric != vector.L2 || meta.Backend != BackendSQLite {
+		t.Errorf("Clear changed the metadata to %+v", meta)
+	}
+}
+
+func TestSQLiteStoreNormalizesCosine(t *testing.T) {
+	s, err := OpenSQLite(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if meta := s.Metadata(); meta.Metric != vector.Cosine || !meta.Normalized {
+		t.Errorf("new SQLite store has metadata %+v, want normalized cosine", meta)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	ds := NewDocumentStoreAt(t.TempDir())
+	var docs []*models.Document
+	for i, url := range []string{"https://go.dev/blog/b", "https://go.dev/blog/a", "https://go.dev/doc/x"} {
+		for chunk := range 2 {
+			doc := testDoc(url[len(url)-1:]+string(rune('0'+chunk)), url, float32(i), float32(chunk), 1)
+			doc.Metadata = map[string]string{"chunk": string(rune('0' + chunk))}
+			docs = append(docs, doc)
+		}
+	}
+	if err := ds.SaveDocuments(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, doc := range ds.Filter(Filter{URLPrefix: "https://go.dev/blog/"}) {
+		ids = append(ids, doc.ID)
+	}
+	if want := []string{"a0", "a1", "b0", "b1"}; !slices.Equal(ids, want) {
+		t.Errorf("filtered by prefix: %v, want %v", ids, want)
+	}
+	if got := ds.Filter(Filter{Metadata: map[string]string{"chunk": "1"}, Limit: 2}); len(got) != 2 || got[0].ID != "a1" || got[1].ID != "b1" {
+		t.Errorf("filtered by metadata with a limit: %v", got)
+	}
+}
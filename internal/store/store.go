@@ -0,0 +1,192 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"ollama_go/internal/models"
+	"ollama_go/internal/vector"
+)
+
+// Store is a document store with exact vector search, URL lookups and keyword
+// search. DocumentStore keeps the documents in a JSON file and SQLiteStore in
+// a SQLite database; both hold the vectors in memory for search. BoltStore
+// searches the vectors in its file instead.
+type Store interface {
+	// SaveDocuments saves a batch of documents, all or nothing
+	SaveDocuments(docs []*models.Document) error
+	// CopyDocuments saves documents from another index, keeping their versions
+	CopyDocuments(docs []*models.Document) error
+	// SaveDocument saves one document, replacing any with the same ID
+	SaveDocument(doc *models.Document) error
+	// ReplaceURL saves the chunks of a page and removes its other chunks
+	ReplaceURL(url string, docs []*models.Document) error
+	// Upsert saves doc if the stored document is at expectedVersion
+	Upsert(doc *models.Document, expectedVersion int64) error
+	// Delete removes the document with the given ID
+	Delete(id string) error
+	// DeleteByURL removes every chunk of a page
+	DeleteByURL(url string) (int, error)
+	// DeleteByURLPrefix removes every document whose URL has the prefix
+	DeleteByURLPrefix(prefix string) (int, error)
+	// Clear removes every document, keeping the metric and model
+	Clear() error
+
+	// GetDocument returns the document with the given ID
+	GetDocument(id string) (*models.Document, error)
+	// GetAllDocuments returns every document, without embeddings
+	GetAllDocuments() []*models.Document
+	// Len returns the number of documents
+	Len() int
+	// GetDocumentsByURL returns the chunks of a page in chunk order
+	GetDocumentsByURL(url string) []*models.Document
+	// URLs returns the distinct URLs with the prefix, sorted
+	URLs(prefix string) []string
+	// Filter returns the documents matching f
+	Filter(f Filter) []*models.Document
+
+	// SearchBySimilarity returns the topK documents closest to the query
+	SearchBySimilarity(queryEmbedding []float32, topK int) []*models.Document
+	// SearchWithScores is SearchBySimilarity returning the scores as well
+	SearchWithScores(queryEmbedding []float32, topK int) []ScoredDocument
+	// SearchByKeyword returns the topK documents best matching the words
+	SearchByKeyword(query string, topK int) ([]*models.Document, error)
+
+	// Metadata returns the index metadata
+	Metadata() IndexMetadata
+	// SetMetric selects the metric of an empty index
+	SetMetric(metric vector.Metric) error
+	// SetQuantization selects the compression used for search
+	SetQuantization(kind vector.Quantization) error
+	// SetModel records the embedding model of the index
+	SetModel(modelID string) error
+	// Dir returns the directory the store is kept in
+	Dir() string
+	// Persist writes the index metadata and any unsaved documents
+	Persist() error
+	// Snapshot saves a consistent copy of the store under a name
+	Snapshot(name, reason string, auto bool) (SnapshotInfo, error)
+	// Close releases the resources of the store
+	Close() error
+}
+
+// Storage backends, recorded in the index metadata
+const (
+	BackendJSON   = "json"
+	BackendSQLite = "sqlite"
+	BackendBolt   = "bolt"
+)
+
+// Open loads the store in dir with the backend recorded in its index
+// metadata
+func Open(dir string) (Store, error) {
+	// Loaded under the lock, so a swap cannot replace the files meanwhile
+	lock, err := lockShared(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { lock.Unlock() }()
+	if swapPending(dir) {
+		// Finish a swap that was interrupted
+		lock.Unlock()
+		if lock, err = LockExclusive(dir); err != nil {
+			return nil, err
+		}
+		if err := finishSwap(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	meta, _, err := readMetadata(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+	return openBackend(dir, meta.Backend)
+}
+
+// openBackend opens the store in dir with the given backend
+func openBackend(dir, backend string) (Store, error) {
+	switch backend {
+	case BackendJSON, "":
+		ds := NewDocumentStoreAt(dir)
+		if err := ds.LoadFromDisk(); err != nil {
+			return nil, fmt.Errorf("failed to load documents: %w", err)
+		}
+		return ds, nil
+	case BackendSQLite:
+		return OpenSQLite(dir)
+	case BackendBolt:
+		return OpenBolt(dir)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q: use %s, %s or %s", backend, BackendJSON, BackendSQLite, BackendBolt)
+	}
+}
+
+// Filter selects documents by URL prefix and metadata. The zero Filter
+// matches every document.
+type Filter struct {
+	URLPrefix string
+	Metadata  map[string]string // every key must have the given value
+	Limit     int               // 0 returns every match
+
+	// Embeddings copies the embeddings of the matches, as for an export;
+	// listings leave them out
+	Embeddings bool
+}
+
+// backend makes the changes of a DocumentStore durable. The store keeps
+// every document in memory; the backend only loads and saves them.
+type backend interface {
+	// load returns every stored document
+	load() ([]*models.Document, error)
+	// save persists one operation: changed documents are written and removed
+	// IDs deleted. It runs before the store applies the operation in memory;
+	// all returns every document as it will be afterwards.
+	save(all func() ([]*models.Document, error), changed []*models.Document, removed []string) error
+	// snapshot writes a consistent copy of the stored documents into dir
+	snapshot(dir string) error
+	close() error
+}
+
+// vectorBackend is a backend that serves the vectors from its file, so the
+// store keeps no exact index in memory
+type vectorBackend interface {
+	backend
+	// vectors returns the stored embeddings of the documents with the IDs
+	vectors(ids []string) ([][]float32, error)
+	// search returns the k stored vectors best matching query among the
+	// documents in positions, with their positions as indexes
+	search(query []float32, k int, metric vector.Metric, positions map[string]int) ([]vector.Result, error)
+}
+
+var (
+	_ Store = (*DocumentStore)(nil)
+	_ Store = (*SQLiteStore)(nil)
+	_ Store = (*BoltStore)(nil)
+	_ Store = (*Live)(nil)
+
+	_ vectorBackend = (*boltBackend)(nil)
+)
+
+// encodeVector encodes v as little-endian float32 values
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+// decodeVector decodes a vector written by encodeVector
+func decodeVector(buf []byte) ([]float32, error) {
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("embedding blob of %d bytes is not a float32 vector", len(buf))
+	}
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v, nil
+}
@@ -0,0 +1,279 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// minRowsPerWorker keeps small indexes on a single goroutine, where the cost
+// of starting workers would exceed the scan itself
+const minRowsPerWorker = 4096
+
+// Flat is an exact (brute-force) index. Vectors are stored row by row in one
+// contiguous slice, so a scan walks memory sequentially. Cosine indexes keep
+// unit-length rows and score with a dot product.
+type Flat struct {
+	dim    int
+	metric Metric
+	data   []float32 // Len() × dim
+	heaps  sync.Pool // *topK buffers reused across searches
+}
+
+// NewFlat creates an empty index of dim-dimensional vectors
+func NewFlat(dim int, metric Metric) *Flat {
+	return &Flat{dim: dim, metric: metric}
+}
+
+// Dim returns the vector dimension
+func (f *Flat) Dim() int {
+	return f.dim
+}
+
+// Len returns the number of vectors
+func (f *Flat) Len() int {
+	if f.dim == 0 {
+		return 0
+	}
+	return len(f.data) / f.dim
+}
+
+// Vector returns the stored vector at position i. It aliases the index
+// and must not be modified.
+func (f *Flat) Vector(i int) []float32 {
+	return f.data[i*f.dim : (i+1)*f.dim]
+}
+
+// Add appends v and returns its position
+func (f *Flat) Add(v []float32) (int, error) {
+	if len(v) != f.dim {
+		return 0, fmt.Errorf("vector has dimension %d, want %d", len(v), f.dim)
+	}
+	f.data = append(f.data, v...)
+	if f.metric == Cosine {
+		normalizeInPlace(f.data[len(f.data)-f.dim:])
+	}
+	return f.Len() - 1, nil
+}
+
+// Set replaces the vector at position i
+func (f *Flat) Set(i int, v []float32) error {
+	if len(v) != f.dim {
+		return fmt.Errorf("vector has dimension %d, want %d", len(v), f.dim)
+	}
+	row := f.data[i*f.dim : (i+1)*f.dim]
+	copy(row, v)
+	if f.metric == Cosine {
+		normalizeInPlace(row)
+	}
+	return nil
+}
+
+// Remove deletes the vector at position i by moving the last vector into
+// its place. It returns the old position of the moved vector (or -1 if i was
+// the last one) so callers can update their own position mapping.
+func (f *Flat) Remove(i int) int {
+	last := f.Len() - 1
+	if i != last {
+		copy(f.data[i*f.dim:(i+1)*f.dim], f.data[last*f.dim:])
+	}
+	f.data = f.data[:last*f.dim]
+	if i == last {
+		return -1
+	}
+	return last
+}
+
+// Search returns the k vectors best matching query under the index metric,
+// best first. Result scores are similarities for Cosine and Dot and
+// distances for L2. Rows are split across GOMAXPROCS workers, each keeping
+// its own bounded heap, and the per-worker results are merged at the end.
+func (f *Flat) Search(query []float32, k int) []Result {
+	n := f.Len()
+	if n == 0 || k <= 0 || len(query) != f.dim {
+		return nil
+	}
+	k = min(k, n)
+	if f.metric == Cosine {
+		query = Normalize(query)
+	}
+
+	workers := min(runtime.GOMAXPROCS(0), max(n/minRowsPerWorker, 1))
+	heaps := make([]*topK, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		heaps[w] = f.getHeap(k)
+		lo, hi := w*n/workers, (w+1)*n/workers
+		if w == workers-1 {
+			f.scan(query, lo, hi, heaps[w])
+			continue
+		}
+		wg.Add(1)
+		go func(top *topK) {
+			defer wg.Done()
+			f.scan(query, lo, hi, top)
+		}(heaps[w])
+	}
+	wg.Wait()
+
+	merged := heaps[0]
+	for _, h := range heaps[1:] {
+		for _, r := range h.heap {
+			merged.Push(r.Index, r.Score)
+		}
+		f.heaps.Put(h)
+	}
+	results := merged.Sorted()
+	f.heaps.Put(merged)
+
+	if f.metric.IsDistance() {
+		for i := range results {
+			results[i].Score = sqrt32(-results[i].Score)
+		}
+	}
+	return results
+}
+
+// Scanner is an exact search over vectors offered one at a time, e.g. read
+// from a file, so they never have to be held in memory together. Scores are
+// those of Flat.Search.
+type Scanner struct {
+	metric Metric
+	query  []float32
+	top    *topK
+}
+
+// NewScanner starts a search for the k vectors best matching query
+func NewScanner(query []float32, k int, metric Metric) *Scanner {
+	if metric == Cosine {
+		query = Normalize(query)
+	}
+	return &Scanner{metric: metric, query: query, top: newTopK(max(k, 0))}
+}
+
+// Offer scores v as the vector at position index. Vectors of another
+// dimension are skipped. For Cosine, v is scaled to unit length in place.
+func (s *Scanner) Offer(index int, v []float32) {
+	if len(v) != len(s.query) {
+		return
+	}
+	switch s.metric {
+	case L2:
+		s.top.Push(index, -squaredL2Unrolled(s.query, v))
+	case Cosine:
+		normalizeInPlace(v)
+		s.top.Push(index, dotUnrolled(s.query, v))
+	default:
+		s.top.Push(index, dotUnrolled(s.query, v))
+	}
+}
+
+// Results returns the best vectors offered, best first
+func (s *Scanner) Results() []Result {
+	results := s.top.Sorted()
+	if s.metric.IsDistance() {
+		for i := range results {
+			results[i].Score = sqrt32(-results[i].Score)
+		}
+	}
+	return results
+}
+
+// scan scores rows [lo, hi) into top. L2 pushes negated squared distances so
+// that higher is better throughout; Search converts them back.
+func (f *Flat) scan(query []float32, lo, hi int, top *topK) {
+	dim := f.dim
+	data := f.data[lo*dim : hi*dim]
+	if f.metric == L2 {
+		for i := 0; i < hi-lo; i++ {
+			top.Push(lo+i, -squaredL2Unrolled(query, data[i*dim:(i+1)*dim]))
+		}
+		return
+	}
+	for i := 0; i < hi-lo; i++ {
+		top.Push(lo+i, dotUnrolled(query, data[i*dim:(i+1)*dim]))
+	}
+}
+
+func (f *Flat) getHeap(k int) *topK {
+	if h, ok := f.heaps.Get().(*topK); ok {
+		h.k = k
+		h.heap = h.heap[:0]
+		return h
+	}
+	return newTopK(k)
+}
+
+// dotUnrolled is DotProduct in float32 with an 8-way unrolled loop; the
+// independent accumulators let the CPU pipeline the multiplications
+func dotUnrolled(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3, s4, s5, s6, s7 float32
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+		s4 += a[i+4] * b[i+4]
+		s5 += a[i+5] * b[i+5]
+		s6 += a[i+6] * b[i+6]
+		s7 += a[i+7] * b[i+7]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3) + (s4 + s5) + (s6 + s7)
+}
+
+// squaredL2Unrolled is the squared Euclidean distance with an 8-way unrolled loop
+func squaredL2Unrolled(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3, s4, s5, s6, s7 float32
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		d0 := a[i] - b[i]
+		d1 := a[i+1] - b[i+1]
+		d2 := a[i+2] - b[i+2]
+		d3 := a[i+3] - b[i+3]
+		d4 := a[i+4] - b[i+4]
+		d5 := a[i+5] - b[i+5]
+		d6 := a[i+6] - b[i+6]
+		d7 := a[i+7] - b[i+7]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+		s4 += d4 * d4
+		s5 += d5 * d5
+		s6 += d6 * d6
+		s7 += d7 * d7
+	}
+	for ; i < len(a); i++ {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return (s0 + s1) + (s2 + s3) + (s4 + s5) + (s6 + s7)
+}
+
+// normalizeInPlace scales v to unit length. Zero vectors, and vectors that
+// are already unit length, are left alone so that a stored normalized
+// vector reads back bit for bit.
+func normalizeInPlace(v []float32) {
+	var sum float32
+	for _, x := range v {
+		sum += x * x
+	}
+	if sum == 0 || math.Abs(float64(sum)-1) < 1e-6 {
+		return
+	}
+	inv := 1 / sqrt32(sum)
+	for i := range v {
+		v[i] *= inv
+	}
+}
+
+func sqrt32(x float32) float32 {
+	return float32(math.Sqrt(float64(x)))
+}
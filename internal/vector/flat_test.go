@@ -0,0 +1,126 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+// naiveSearch scores every vector with Metric.Score and sorts all of them
+func naiveSearch(vectors [][]float32, metric Metric, query []float32, k int) []Result {
+	results := make([]Result, len(vectors))
+	for i, v := range vectors {
+		results[i] = Result{Index: i, Score: metric.Score(query, v)}
+	}
+	sort.Slice(results, func(a, b int) bool {
+		return metric.Better(results[a].Score, results[b].Score)
+	})
+	return results[:min(k, len(results))]
+}
+
+func newFlatFrom(t testing.TB, vectors [][]float32, metric Metric) *Flat {
+	f := NewFlat(len(vectors[0]), metric)
+	for _, v := range vectors {
+		if _, err := f.Add(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func TestFlatSearchMatchesNaive(t *testing.T) {
+	// Force several workers even on a single CPU
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	vectors := clustered(3*minRowsPerWorker+17, 37, 1)
+	queries := clustered(5, 37, 2)
+
+	for _, metric := range []Metric{Cosine, Dot, L2} {
+		t.Run(string(metric), func(t *testing.T) {
+			f := newFlatFrom(t, vectors, metric)
+			for _, q := range queries {
+				want := naiveSearch(vectors, metric, q, 10)
+				got := f.Search(q, 10)
+				if len(got) != len(want) {
+					t.Fatalf("got %d results, want %d", len(got), len(want))
+				}
+				for i := range want {
+					if got[i].Index != want[i].Index || math.Abs(float64(got[i].Score-want[i].Score)) > 1e-3 {
+						t.Fatalf("result %d = %+v, want %+v", i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestScannerMatchesFlat(t *testing.T) {
+	vectors := clustered(500, 37, 1)
+	queries := clustered(5, 37, 2)
+
+	for _, metric := range []Metric{Cosine, Dot, L2} {
+		t.Run(string(metric), func(t *testing.T) {
+			f := newFlatFrom(t, vectors, metric)
+			for _, q := range queries {
+				s := NewScanner(q, 10, metric)
+				for i, v := range vectors {
+					s.Offer(i, append([]float32(nil), v...))
+				}
+				s.Offer(len(vectors), []float32{1, 2}) // another dimension
+				want, got := f.Search(q, 10), s.Results()
+				if len(got) != len(want) {
+					t.Fatalf("got %d results, want %d", len(got), len(want))
+				}
+				for i := range want {
+					if got[i].Index != want[i].Index || math.Abs(float64(got[i].Score-want[i].Score)) > 1e-5 {
+						t.Fatalf("result %d = %+v, want %+v", i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFlatRemove(t *testing.T) {
+	f := newFlatFrom(t, [][]float32{{1, 0}, {0, 1}, {-1, 0}}, Dot)
+
+	if moved := f.Remove(0); moved != 2 {
+		t.Fatalf("Remove(0) moved %d, want 2", moved)
+	}
+	if got := f.Search([]float32{-1, 0}, 1); got[0].Index != 0 || f.Len() != 2 {
+		t.Fatalf("after removal got %+v with %d vectors", got, f.Len())
+	}
+	if moved := f.Remove(1); moved != -1 {
+		t.Fatalf("removing the last vector moved %d", moved)
+	}
+}
+
+// Benchmarks search 128-dimensional vectors for the top 10 with the parallel
+// heap-based scan and, up to 100k vectors, the naive score-and-sort path.
+func BenchmarkFlatSearch(b *testing.B) {
+	const dim = 128
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		vectors := clustered(n, dim, 1)
+		queries := clustered(16, dim, 2)
+
+		b.Run(fmt.Sprintf("heap/%d", n), func(b *testing.B) {
+			f := newFlatFrom(b, vectors, Cosine)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				f.Search(queries[i%len(queries)], 10)
+			}
+		})
+
+		if n <= 100_000 {
+			b.Run(fmt.Sprintf("naive/%d", n), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					naiveSearch(vectors, Cosine, queries[i%len(queries)], 10)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Metric selects how vectors are compared
+type Metric string
+
+const (
+	// Cosine is the cosine similarity; higher is better
+	Cosine Metric = "cosine"
+	// Dot is the inner product; higher is better
+	Dot Metric = "dot"
+	// L2 is the Euclidean distance; lower is better
+	L2 Metric = "l2"
+)
+
+// ParseMetric parses a metric name; the empty string selects Cosine
+func ParseMetric(name string) (Metric, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "cosine", "cos":
+		return Cosine, nil
+	case "dot", "ip", "inner":
+		return Dot, nil
+	case "l2", "euclidean":
+		return L2, nil
+	default:
+		return "", fmt.Errorf("unknown distance metric %q (want cosine, dot or l2)", name)
+	}
+}
+
+// Score compares a and b: a similarity for Cosine and Dot, a distance for L2
+func (m Metric) Score(a, b []float32) float32 {
+	switch m {
+	case Dot:
+		return DotProduct(a, b)
+	case L2:
+		return EuclideanDistance(a, b)
+	default:
+		return CosineSimilarity(a, b)
+	}
+}
+
+// IsDistance reports whether lower scores are better
+func (m Metric) IsDistance() bool {
+	return m == L2
+}
+
+// Rank maps a score to a value where higher is always better, so results of
+// any metric can be ordered the same way
+func (m Metric) Rank(score float32) float32 {
+	if m.IsDistance() {
+		return -score
+	}
+	return score
+}
+
+// Better reports whether score x ranks above score y
+func (m Metric) Better(x, y float32) bool {
+	return m.Rank(x) > m.Rank(y)
+}
+
+// Normalize returns a unit-length copy of v (or a copy of v if it is zero).
+// Cosine similarity of normalized vectors equals their dot product.
+func Normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if sum == 0 {
+		copy(out, v)
+		return out
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i, x := range v {
+		out[i] = x * inv
+	}
+	return out
+}
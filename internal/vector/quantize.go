@@ -0,0 +1,246 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// ScalarQuantizer compresses each dimension to an int8 code, 4x smaller than
+// float32. Values are reconstructed as Offset[i] + Scale[i]*code, where
+// Offset and Scale are fitted to the per-dimension range of the training set.
+type ScalarQuantizer struct {
+	Offset []float32
+	Scale  []float32
+}
+
+// TrainScalar fits a scalar quantizer to vectors of equal length
+func TrainScalar(vectors [][]float32) (*ScalarQuantizer, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no training vectors")
+	}
+	dim := len(vectors[0])
+
+	lo := make([]float32, dim)
+	hi := make([]float32, dim)
+	copy(lo, vectors[0])
+	copy(hi, vectors[0])
+	for _, v := range vectors[1:] {
+		if len(v) != dim {
+			return nil, fmt.Errorf("dimension mismatch: %d != %d", len(v), dim)
+		}
+		for i, x := range v {
+			lo[i] = min(lo[i], x)
+			hi[i] = max(hi[i], x)
+		}
+	}
+
+	q := &ScalarQuantizer{Offset: make([]float32, dim), Scale: make([]float32, dim)}
+	for i := range lo {
+		q.Offset[i] = (lo[i] + hi[i]) / 2
+		q.Scale[i] = (hi[i] - lo[i]) / 254
+		if q.Scale[i] == 0 {
+			q.Scale[i] = 1
+		}
+	}
+	return q, nil
+}
+
+// CodeSize returns the number of bytes per encoded vector
+func (q *ScalarQuantizer) CodeSize() int {
+	return len(q.Offset)
+}
+
+// Encode appends the int8 codes of v to dst
+func (q *ScalarQuantizer) Encode(dst []int8, v []float32) []int8 {
+	for i, x := range v {
+		c := math.Round(float64((x - q.Offset[i]) / q.Scale[i]))
+		dst = append(dst, int8(max(-127, min(127, c))))
+	}
+	return dst
+}
+
+// Decode appends the reconstructed vector of code to dst
+func (q *ScalarQuantizer) Decode(dst []float32, code []int8) []float32 {
+	for i, c := range code {
+		dst = append(dst, q.Offset[i]+q.Scale[i]*float32(c))
+	}
+	return dst
+}
+
+// DotTable precomputes the query side of an asymmetric dot product:
+// dot(query, decode(code)) == bias + Σ weights[i]*code[i]
+func (q *ScalarQuantizer) DotTable(query []float32) (weights []float32, bias float32) {
+	weights = make([]float32, len(query))
+	for i, x := range query {
+		weights[i] = x * q.Scale[i]
+		bias += x * q.Offset[i]
+	}
+	return weights, bias
+}
+
+// Dot returns the asymmetric dot product for a table from DotTable
+func (q *ScalarQuantizer) Dot(weights []float32, bias float32, code []int8) float32 {
+	code = code[:len(weights)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(weights); i += 4 {
+		s0 += weights[i] * float32(code[i])
+		s1 += weights[i+1] * float32(code[i+1])
+		s2 += weights[i+2] * float32(code[i+2])
+		s3 += weights[i+3] * float32(code[i+3])
+	}
+	for ; i < len(weights); i++ {
+		s0 += weights[i] * float32(code[i])
+	}
+	return bias + s0 + s1 + s2 + s3
+}
+
+// ProductQuantizer splits vectors into M sub-vectors and replaces each with
+// the index of its nearest of K (at most 256) centroids learned by k-means, so
+// a vector takes M bytes. Distances are computed asymmetrically: the query
+// stays in full precision and is compared against centroids via a lookup table.
+type ProductQuantizer struct {
+	Dim       int
+	M         int       // number of sub-vectors
+	K         int       // centroids per sub-vector
+	Centroids []float32 // M × K × Dim/M, contiguous
+}
+
+// TrainProduct learns an M-subspace product quantizer from vectors with
+// the given number of k-means iterations. Dim must be divisible by m.
+func TrainProduct(vectors [][]float32, m, iterations int, seed int64) (*ProductQuantizer, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no training vectors")
+	}
+	dim := len(vectors[0])
+	if m <= 0 || dim%m != 0 {
+		return nil, fmt.Errorf("dimension %d is not divisible into %d sub-vectors", dim, m)
+	}
+	for _, v := range vectors {
+		if len(v) != dim {
+			return nil, fmt.Errorf("dimension mismatch: %d != %d", len(v), dim)
+		}
+	}
+
+	pq := &ProductQuantizer{Dim: dim, M: m, K: min(256, len(vectors))}
+	sub := dim / m
+	pq.Centroids = make([]float32, m*pq.K*sub)
+
+	rng := rand.New(rand.NewSource(seed))
+	points := make([][]float32, len(vectors))
+	for s := 0; s < m; s++ {
+		for i, v := range vectors {
+			points[i] = v[s*sub : (s+1)*sub]
+		}
+		centroids := pq.Centroids[s*pq.K*sub : (s+1)*pq.K*sub]
+		kmeans(points, centroids, pq.K, sub, iterations, rng)
+	}
+	return pq, nil
+}
+
+// kmeans runs Lloyd's algorithm, writing k centroids of size dim into centroids
+func kmeans(points [][]float32, centroids []float32, k, dim, iterations int, rng *rand.Rand) {
+	for c, p := range rng.Perm(len(points))[:k] {
+		copy(centroids[c*dim:], points[p])
+	}
+
+	assign := make([]int, len(points))
+	sums := make([]float32, k*dim)
+	counts := make([]int, k)
+	for iter := 0; iter < iterations; iter++ {
+		for i, p := range points {
+			assign[i] = nearest(centroids, k, dim, p)
+		}
+
+		clear(sums)
+		clear(counts)
+		for i, p := range points {
+			c := assign[i]
+			counts[c]++
+			row := sums[c*dim : (c+1)*dim]
+			for j, x := range p {
+				row[j] += x
+			}
+		}
+		for c := 0; c < k; c++ {
+			if counts[c] == 0 {
+				// Re-seed empty clusters with a random point
+				copy(centroids[c*dim:(c+1)*dim], points[rng.Intn(len(points))])
+				continue
+			}
+			for j := 0; j < dim; j++ {
+				centroids[c*dim+j] = sums[c*dim+j] / float32(counts[c])
+			}
+		}
+	}
+}
+
+// nearest returns the centroid closest to p in squared L2 distance
+func nearest(centroids []float32, k, dim int, p []float32) int {
+	best, bestDist := 0, float32(math.MaxFloat32)
+	for c := 0; c < k; c++ {
+		if d := squaredL2(centroids[c*dim:(c+1)*dim], p); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func squaredL2(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+// CodeSize returns the number of bytes per encoded vector
+func (pq *ProductQuantizer) CodeSize() int {
+	return pq.M
+}
+
+// Encode appends the centroid indexes of v to dst
+func (pq *ProductQuantizer) Encode(dst []uint8, v []float32) []uint8 {
+	sub := pq.Dim / pq.M
+	for s := 0; s < pq.M; s++ {
+		centroids := pq.Centroids[s*pq.K*sub : (s+1)*pq.K*sub]
+		dst = append(dst, uint8(nearest(centroids, pq.K, sub, v[s*sub:(s+1)*sub])))
+	}
+	return dst
+}
+
+// Decode appends the reconstructed vector of code to dst
+func (pq *ProductQuantizer) Decode(dst []float32, code []uint8) []float32 {
+	sub := pq.Dim / pq.M
+	for s, c := range code {
+		offset := (s*pq.K + int(c)) * sub
+		dst = append(dst, pq.Centroids[offset:offset+sub]...)
+	}
+	return dst
+}
+
+// DotTable precomputes the dot product of every query sub-vector with every
+// centroid of its subspace (M × K entries)
+func (pq *ProductQuantizer) DotTable(query []float32) []float32 {
+	sub := pq.Dim / pq.M
+	table := make([]float32, pq.M*pq.K)
+	for s := 0; s < pq.M; s++ {
+		q := query[s*sub : (s+1)*sub]
+		for c := 0; c < pq.K; c++ {
+			offset := (s*pq.K + c) * sub
+			table[s*pq.K+c] = DotProduct(q, pq.Centroids[offset:offset+sub])
+		}
+	}
+	return table
+}
+
+// Dot returns the asymmetric dot product for a table from DotTable
+func (pq *ProductQuantizer) Dot(table []float32, code []uint8) float32 {
+	var sum float32
+	for s, c := range code {
+		sum += table[s*pq.K+int(c)]
+	}
+	return sum
+}
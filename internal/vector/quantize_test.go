@@ -0,0 +1,257 @@
+package vector
+
+import (
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// clustered returns n vectors of dimension dim scattered around a few
+// centers, which resembles real embeddings more than uniform noise does
+func clustered(n, dim int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	centers := make([][]float32, 64)
+	for c := range centers {
+		centers[c] = make([]float32, dim)
+		for i := range centers[c] {
+			centers[c][i] = float32(rng.NormFloat64())
+		}
+	}
+
+	vectors := make([][]float32, n)
+	for v := range vectors {
+		center := centers[rng.Intn(len(centers))]
+		vectors[v] = make([]float32, dim)
+		for i := range vectors[v] {
+			vectors[v][i] = center[i] + 0.6*float32(rng.NormFloat64())
+		}
+	}
+	return vectors
+}
+
+// exactSearch is the full-precision reference: cosine similarity against every vector
+func exactSearch(vectors [][]float32, query []float32, k int) []Result {
+	top := newTopK(k)
+	for i, v := range vectors {
+		top.Push(i, CosineSimilarity(query, v))
+	}
+	return top.Sorted()
+}
+
+// recall is the fraction of the exact top-k found by got
+func recall(exact, got []Result) float64 {
+	want := make(map[int]bool, len(exact))
+	for _, r := range exact {
+		want[r.Index] = true
+	}
+	hits := 0
+	for _, r := range got {
+		if want[r.Index] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(exact))
+}
+
+func meanRecall(t testing.TB, ix *QuantizedIndex, vectors, queries [][]float32, k int) float64 {
+	var sum float64
+	for _, q := range queries {
+		got, err := ix.Search(q, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += recall(exactSearch(vectors, q, k), got)
+	}
+	return sum / float64(len(queries))
+}
+
+func TestQuantizedIndexRecall(t *testing.T) {
+	vectors := clustered(2000, 64, 1)
+	queries := clustered(20, 64, 2)
+
+	tests := []struct {
+		name      string
+		opts      QuantizeOptions
+		rescore   bool
+		minRecall float64
+	}{
+		{"int8", QuantizeOptions{Kind: QuantizeInt8}, false, 0.85},
+		{"int8+rescore", QuantizeOptions{Kind: QuantizeInt8}, true, 0.98},
+		{"pq", QuantizeOptions{Kind: QuantizePQ, SubVectors: 16}, false, 0.4},
+		{"pq+rescore", QuantizeOptions{Kind: QuantizePQ, SubVectors: 16, Rescore: 10}, true, 0.9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawPath := ""
+			if tt.rescore {
+				rawPath = filepath.Join(t.TempDir(), "vectors.f32")
+			}
+			ix, err := NewQuantizedIndex(vectors, rawPath, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ix.Close()
+
+			if r := meanRecall(t, ix, vectors, queries, 10); r < tt.minRecall {
+				t.Errorf("recall@10 = %.2f, want >= %.2f", r, tt.minRecall)
+			}
+			if full := 4 * 64 * len(vectors); ix.MemoryBytes() >= full {
+				t.Errorf("index uses %d bytes, full precision uses %d", ix.MemoryBytes(), full)
+			}
+		})
+	}
+}
+
+func TestQuantizedIndexUpdates(t *testing.T) {
+	vectors := clustered(400, 32, 4)
+	queries := clustered(10, 32, 5)
+	for _, kind := range []Quantization{QuantizeInt8, QuantizePQ} {
+		t.Run(string(kind), func(t *testing.T) {
+			// Train on a few vectors and add the rest one by one
+			ix, err := NewQuantizedIndex(vectors[:10], filepath.Join(t.TempDir(), "vectors.f32"), QuantizeOptions{Kind: kind})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ix.Close()
+			live := slices.Clone(vectors[:10])
+			for _, v := range vectors[10:] {
+				if _, err := ix.Add(v); err != nil {
+					t.Fatal(err)
+				}
+				live = append(live, v)
+			}
+			if !ix.Undertrained() {
+				t.Error("an index trained on 10 of 400 vectors is not undertrained")
+			}
+			if err := ix.Retrain(); err != nil {
+				t.Fatal(err)
+			}
+			if ix.Undertrained() {
+				t.Error("still undertrained after Retrain")
+			}
+
+			// Replace one vector and remove others the way Flat does
+			if err := ix.Set(3, vectors[0]); err != nil {
+				t.Fatal(err)
+			}
+			live[3] = vectors[0]
+			for _, i := range []int{5, 100, -1} {
+				if i < 0 {
+					i = len(live) - 1
+				}
+				moved, err := ix.Remove(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if last := len(live) - 1; i == last {
+					if moved != -1 {
+						t.Errorf("removing the last vector moved %d", moved)
+					}
+				} else {
+					live[i] = live[last]
+				}
+				live = live[:len(live)-1]
+			}
+
+			if ix.Len() != len(live) {
+				t.Fatalf("index holds %d vectors, want %d", ix.Len(), len(live))
+			}
+			for _, i := range []int{3, 5, 100} {
+				got, err := ix.Vector(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if sim := CosineSimilarity(got, live[i]); sim < 0.9999 {
+					t.Errorf("vector %d has similarity %f to the one stored there", i, sim)
+				}
+			}
+			if r := meanRecall(t, ix, live, queries, 10); r < 0.9 {
+				t.Errorf("recall@10 = %.2f after updates, want >= 0.9", r)
+			}
+		})
+	}
+}
+
+func TestScalarQuantizerRoundTrip(t *testing.T) {
+	vectors := clustered(100, 32, 3)
+	q, err := TrainScalar(vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range vectors {
+		decoded := q.Decode(nil, q.Encode(nil, v))
+		for i := range v {
+			if diff := decoded[i] - v[i]; diff > q.Scale[i] || diff < -q.Scale[i] {
+				t.Fatalf("dimension %d: decoded %f, want %f ± %f", i, decoded[i], v[i], q.Scale[i])
+			}
+		}
+	}
+}
+
+// Benchmarks compare memory, speed and recall@10 of the full-precision scan
+// with the quantized indexes (20k vectors, 256 dimensions).
+const (
+	benchVectors = 20000
+	benchDim     = 256
+)
+
+func BenchmarkSearchFloat32(b *testing.B) {
+	vectors := clustered(benchVectors, benchDim, 1)
+	queries := clustered(64, benchDim, 2)
+	index := NewFlat(benchDim, Cosine)
+	for _, v := range vectors {
+		if _, err := index.Add(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		index.Search(queries[i%len(queries)], 10)
+	}
+	b.ReportMetric(float64(4*benchDim), "bytes/vector")
+}
+
+func benchmarkQuantized(b *testing.B, opts QuantizeOptions, rescore bool) {
+	vectors := clustered(benchVectors, benchDim, 1)
+	queries := clustered(64, benchDim, 2)
+
+	rawPath := ""
+	if rescore {
+		rawPath = filepath.Join(b.TempDir(), "vectors.f32")
+	}
+	ix, err := NewQuantizedIndex(vectors, rawPath, opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer ix.Close()
+
+	recall := meanRecall(b, ix, vectors, queries[:16], 10)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ix.Search(queries[i%len(queries)], 10); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(ix.MemoryBytes())/float64(ix.Len()), "bytes/vector")
+	b.ReportMetric(recall, "recall@10")
+}
+
+func BenchmarkSearchInt8(b *testing.B) {
+	benchmarkQuantized(b, QuantizeOptions{Kind: QuantizeInt8}, false)
+}
+
+func BenchmarkSearchInt8Rescore(b *testing.B) {
+	benchmarkQuantized(b, QuantizeOptions{Kind: QuantizeInt8}, true)
+}
+
+func BenchmarkSearchPQ(b *testing.B) {
+	benchmarkQuantized(b, QuantizeOptions{Kind: QuantizePQ, SubVectors: 64, TrainSize: 5000, Iterations: 5}, false)
+}
+
+func BenchmarkSearchPQRescore(b *testing.B) {
+	benchmarkQuantized(b, QuantizeOptions{Kind: QuantizePQ, SubVectors: 64, TrainSize: 5000, Iterations: 5, Rescore: 10}, true)
+}
@@ -0,0 +1,403 @@
+package vector
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// Quantization selects how a QuantizedIndex compresses vectors
+type Quantization string
+
+const (
+	// QuantizeInt8 stores one int8 per dimension (4x smaller)
+	QuantizeInt8 Quantization = "int8"
+	// QuantizePQ stores one byte per sub-vector (Dim/SubVectors·4x smaller)
+	QuantizePQ Quantization = "pq"
+)
+
+// ParseQuantization parses a quantization name; "" or "none" means none
+func ParseQuantization(name string) (Quantization, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "none":
+		return "", nil
+	case "int8":
+		return QuantizeInt8, nil
+	case "pq":
+		return QuantizePQ, nil
+	default:
+		return "", fmt.Errorf("unknown quantization %q (want none, int8 or pq)", name)
+	}
+}
+
+// QuantizeOptions configures a QuantizedIndex
+type QuantizeOptions struct {
+	Kind       Quantization
+	SubVectors int   // PQ sub-vectors; defaults to Dim/8
+	Iterations int   // PQ k-means iterations; defaults to 10
+	TrainSize  int   // vectors sampled for training; defaults to 20000
+	Rescore    int   // candidates re-scored per requested result; defaults to 4
+	Seed       int64 // seed for sampling and k-means
+}
+
+func (o *QuantizeOptions) setDefaults(dim int) {
+	if o.SubVectors <= 0 {
+		// PQ needs the dimension to split evenly
+		o.SubVectors = max(dim/8, 1)
+		for dim%o.SubVectors != 0 {
+			o.SubVectors--
+		}
+	}
+	if o.Iterations <= 0 {
+		o.Iterations = 10
+	}
+	if o.TrainSize <= 0 {
+		o.TrainSize = 20000
+	}
+	if o.Rescore <= 0 {
+		o.Rescore = 4
+	}
+}
+
+// QuantizedIndex is an exact-scan index over compressed vectors ranked by
+// cosine similarity. The query stays in full precision (asymmetric distance).
+// When the full-precision vectors are kept in a RawFile, the best
+// k·Rescore candidates are re-scored against them before returning k.
+//
+// Like Flat, vectors are addressed by position and Remove moves the last one
+// into the freed position. New vectors are encoded with the quantizer as
+// trained; Retrain fits it again once the index has outgrown its sample.
+type QuantizedIndex struct {
+	opts     QuantizeOptions
+	dim      int
+	n        int
+	trained  int // vectors the quantizer was trained on
+	sq       *ScalarQuantizer
+	pq       *ProductQuantizer
+	int8s    []int8  // n × dim scalar codes, contiguous
+	pqCodes  []uint8 // n × M product codes, contiguous
+	codeSize int
+
+	rawMu sync.Mutex
+	raw   *RawFile
+}
+
+// NewQuantizedIndex trains a quantizer on vectors and encodes them. If
+// rawPath is not empty the normalized full-precision vectors are written
+// there and used for re-scoring; vectors need not be kept in memory after.
+func NewQuantizedIndex(vectors [][]float32, rawPath string, opts QuantizeOptions) (*QuantizedIndex, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no vectors to index")
+	}
+	dim := len(vectors[0])
+	opts.setDefaults(dim)
+
+	normalized := make([][]float32, len(vectors))
+	for i, v := range vectors {
+		if len(v) != dim {
+			return nil, fmt.Errorf("vector %d has dimension %d, want %d", i, len(v), dim)
+		}
+		normalized[i] = Normalize(v)
+	}
+
+	ix := &QuantizedIndex{opts: opts, dim: dim, n: len(vectors)}
+	if err := ix.train(sample(normalized, opts.TrainSize, opts.Seed)); err != nil {
+		return nil, err
+	}
+	for _, v := range normalized {
+		ix.encode(v)
+	}
+
+	if rawPath != "" {
+		if err := WriteRawFile(rawPath, normalized); err != nil {
+			return nil, err
+		}
+		var err error
+		if ix.raw, err = OpenRawFile(rawPath, dim); err != nil {
+			return nil, err
+		}
+	}
+
+	return ix, nil
+}
+
+// train fits a new quantizer to training and drops the codes of the old one
+func (ix *QuantizedIndex) train(training [][]float32) error {
+	switch ix.opts.Kind {
+	case QuantizeInt8:
+		sq, err := TrainScalar(training)
+		if err != nil {
+			return err
+		}
+		ix.sq, ix.codeSize = sq, sq.CodeSize()
+		ix.int8s = make([]int8, 0, ix.n*ix.codeSize)
+	case QuantizePQ:
+		pq, err := TrainProduct(training, ix.opts.SubVectors, ix.opts.Iterations, ix.opts.Seed)
+		if err != nil {
+			return err
+		}
+		ix.pq, ix.codeSize = pq, pq.CodeSize()
+		ix.pqCodes = make([]uint8, 0, ix.n*ix.codeSize)
+	default:
+		return fmt.Errorf("unknown quantization: %q", ix.opts.Kind)
+	}
+	ix.trained = len(training)
+	return nil
+}
+
+// encode appends the codes of the normalized vector v
+func (ix *QuantizedIndex) encode(v []float32) {
+	if ix.sq != nil {
+		ix.int8s = ix.sq.Encode(ix.int8s, v)
+	} else {
+		ix.pqCodes = ix.pq.Encode(ix.pqCodes, v)
+	}
+}
+
+// Len returns the number of indexed vectors
+func (ix *QuantizedIndex) Len() int {
+	return ix.n
+}
+
+// Kind returns the quantization of the index
+func (ix *QuantizedIndex) Kind() Quantization {
+	return ix.opts.Kind
+}
+
+// Dim returns the vector dimension
+func (ix *QuantizedIndex) Dim() int {
+	return ix.dim
+}
+
+// MemoryBytes returns the in-memory size of the codes and codebooks
+func (ix *QuantizedIndex) MemoryBytes() int {
+	switch {
+	case ix.sq != nil:
+		return len(ix.int8s) + 8*ix.dim
+	case ix.pq != nil:
+		return len(ix.pqCodes) + 4*len(ix.pq.Centroids)
+	}
+	return 0
+}
+
+// Add encodes v at the next position and returns that position
+func (ix *QuantizedIndex) Add(v []float32) (int, error) {
+	if len(v) != ix.dim {
+		return 0, fmt.Errorf("vector has dimension %d, want %d", len(v), ix.dim)
+	}
+	v = Normalize(v)
+	if err := ix.writeRaw(ix.n, v); err != nil {
+		return 0, err
+	}
+	ix.encode(v)
+	ix.n++
+	return ix.n - 1, nil
+}
+
+// Set replaces the vector at position i
+func (ix *QuantizedIndex) Set(i int, v []float32) error {
+	if len(v) != ix.dim {
+		return fmt.Errorf("vector has dimension %d, want %d", len(v), ix.dim)
+	}
+	v = Normalize(v)
+	if err := ix.writeRaw(i, v); err != nil {
+		return err
+	}
+	if ix.sq != nil {
+		copy(ix.int8s[i*ix.codeSize:], ix.sq.Encode(make([]int8, 0, ix.codeSize), v))
+	} else {
+		copy(ix.pqCodes[i*ix.codeSize:], ix.pq.Encode(make([]uint8, 0, ix.codeSize), v))
+	}
+	return nil
+}
+
+// Remove deletes the vector at position i by moving the last vector into
+// its place. It returns the old position of the moved vector, or -1 if i
+// was the last one.
+func (ix *QuantizedIndex) Remove(i int) (int, error) {
+	last := ix.n - 1
+	if ix.raw != nil {
+		ix.rawMu.Lock()
+		defer ix.rawMu.Unlock()
+		if i != last {
+			v := make([]float32, ix.dim)
+			if err := ix.raw.Read(last, v); err != nil {
+				return 0, err
+			}
+			if err := ix.raw.Write(i, v); err != nil {
+				return 0, err
+			}
+		}
+		if err := ix.raw.Truncate(last); err != nil {
+			return 0, err
+		}
+	}
+
+	cs := ix.codeSize
+	if ix.sq != nil {
+		copy(ix.int8s[i*cs:(i+1)*cs], ix.int8s[last*cs:])
+		ix.int8s = ix.int8s[:last*cs]
+	} else {
+		copy(ix.pqCodes[i*cs:(i+1)*cs], ix.pqCodes[last*cs:])
+		ix.pqCodes = ix.pqCodes[:last*cs]
+	}
+	ix.n = last
+	if i == last {
+		return -1, nil
+	}
+	return last, nil
+}
+
+// writeRaw stores the normalized v at position i of the raw file, if any
+func (ix *QuantizedIndex) writeRaw(i int, v []float32) error {
+	if ix.raw == nil {
+		return nil
+	}
+	ix.rawMu.Lock()
+	defer ix.rawMu.Unlock()
+	return ix.raw.Write(i, v)
+}
+
+// Vector reads the normalized full-precision vector at position i from the
+// raw file
+func (ix *QuantizedIndex) Vector(i int) ([]float32, error) {
+	if ix.raw == nil {
+		return nil, fmt.Errorf("the full-precision vectors are not kept")
+	}
+	ix.rawMu.Lock()
+	defer ix.rawMu.Unlock()
+
+	v := make([]float32, ix.dim)
+	if err := ix.raw.Read(i, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Undertrained reports whether the index has grown to twice the vectors its
+// quantizer was trained on while those were fewer than TrainSize, so that
+// Retrain would fit the data better
+func (ix *QuantizedIndex) Undertrained() bool {
+	return ix.trained < ix.opts.TrainSize && ix.n >= 2*ix.trained
+}
+
+// Retrain fits a new quantizer to a sample of the raw file and re-encodes
+// every vector with it. The index is unchanged if it fails.
+func (ix *QuantizedIndex) Retrain() error {
+	if ix.raw == nil {
+		return fmt.Errorf("retraining needs the full-precision vectors")
+	}
+	ix.rawMu.Lock()
+	defer ix.rawMu.Unlock()
+
+	picked := sampleIndexes(ix.n, ix.opts.TrainSize, ix.opts.Seed)
+	training := make([][]float32, len(picked))
+	for j, i := range picked {
+		training[j] = make([]float32, ix.dim)
+		if err := ix.raw.Read(i, training[j]); err != nil {
+			return err
+		}
+	}
+	next := &QuantizedIndex{opts: ix.opts, dim: ix.dim, n: ix.n}
+	if err := next.train(training); err != nil {
+		return err
+	}
+	v := make([]float32, ix.dim)
+	for i := 0; i < ix.n; i++ {
+		if err := ix.raw.Read(i, v); err != nil {
+			return err
+		}
+		next.encode(v)
+	}
+
+	ix.trained, ix.sq, ix.pq, ix.codeSize = next.trained, next.sq, next.pq, next.codeSize
+	ix.int8s, ix.pqCodes = next.int8s, next.pqCodes
+	return nil
+}
+
+// Search returns the k vectors most similar to query, best first
+func (ix *QuantizedIndex) Search(query []float32, k int) ([]Result, error) {
+	if len(query) != ix.dim {
+		return nil, fmt.Errorf("query has dimension %d, want %d", len(query), ix.dim)
+	}
+	if ix.n == 0 || k <= 0 {
+		return nil, nil
+	}
+	query = Normalize(query)
+
+	candidates := k
+	if ix.raw != nil {
+		candidates = k * ix.opts.Rescore
+	}
+	top := newTopK(min(candidates, ix.n))
+
+	switch {
+	case ix.sq != nil:
+		weights, bias := ix.sq.DotTable(query)
+		for i := 0; i < ix.n; i++ {
+			top.Push(i, ix.sq.Dot(weights, bias, ix.int8s[i*ix.codeSize:(i+1)*ix.codeSize]))
+		}
+	case ix.pq != nil:
+		table := ix.pq.DotTable(query)
+		for i := 0; i < ix.n; i++ {
+			top.Push(i, ix.pq.Dot(table, ix.pqCodes[i*ix.codeSize:(i+1)*ix.codeSize]))
+		}
+	}
+
+	results := top.Sorted()
+	if ix.raw == nil {
+		return results, nil
+	}
+	return ix.rescore(query, results, k)
+}
+
+// rescore ranks candidates by their exact similarity read from the raw file
+func (ix *QuantizedIndex) rescore(query []float32, candidates []Result, k int) ([]Result, error) {
+	ix.rawMu.Lock()
+	defer ix.rawMu.Unlock()
+
+	top := newTopK(min(k, len(candidates)))
+	v := make([]float32, ix.dim)
+	for _, c := range candidates {
+		if err := ix.raw.Read(c.Index, v); err != nil {
+			return nil, err
+		}
+		top.Push(c.Index, DotProduct(query, v))
+	}
+	return top.Sorted(), nil
+}
+
+// Close releases the raw vector file
+func (ix *QuantizedIndex) Close() error {
+	if ix.raw == nil {
+		return nil
+	}
+	return ix.raw.Close()
+}
+
+// sample returns at most n vectors chosen uniformly at random
+func sample(vectors [][]float32, n int, seed int64) [][]float32 {
+	if len(vectors) <= n {
+		return vectors
+	}
+	picked := make([][]float32, n)
+	for i, p := range sampleIndexes(len(vectors), n, seed) {
+		picked[i] = vectors[p]
+	}
+	return picked
+}
+
+// sampleIndexes returns at most n of the positions 0..total-1 chosen
+// uniformly at random
+func sampleIndexes(total, n int, seed int64) []int {
+	if total <= n {
+		picked := make([]int, total)
+		for i := range picked {
+			picked[i] = i
+		}
+		return picked
+	}
+	rng := rand.New(rand.NewSource(seed))
+	return rng.Perm(total)[:n]
+}
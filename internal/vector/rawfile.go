@@ -0,0 +1,123 @@
+package vector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// RawFile gives random access to full-precision vectors stored on disk as
+// consecutive little-endian float32 rows, so they need not stay in memory
+type RawFile struct {
+	f   *os.File
+	dim int
+	n   int
+	buf []byte
+}
+
+// WriteRawFile writes vectors of equal length to path
+func WriteRawFile(path string, vectors [][]float32) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create vector file: %w", err)
+	}
+
+	var row []byte
+	for _, v := range vectors {
+		row = row[:0]
+		for _, x := range v {
+			row = binary.LittleEndian.AppendUint32(row, math.Float32bits(x))
+		}
+		if _, err := f.Write(row); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write vector file: %w", err)
+		}
+	}
+	return f.Close()
+}
+
+// OpenRawFile opens a vector file written by WriteRawFile for reading and
+// writing
+func OpenRawFile(path string, dim int) (*RawFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vector file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat vector file: %w", err)
+	}
+
+	rowSize := int64(dim) * 4
+	if dim <= 0 || info.Size()%rowSize != 0 {
+		f.Close()
+		return nil, fmt.Errorf("vector file size %d is not a multiple of %d-dimensional rows", info.Size(), dim)
+	}
+
+	return &RawFile{f: f, dim: dim, n: int(info.Size() / rowSize), buf: make([]byte, rowSize)}, nil
+}
+
+// Len returns the number of vectors in the file
+func (r *RawFile) Len() int {
+	return r.n
+}
+
+// Read loads vector i into dst, which must have room for the dimension.
+// It is not safe for concurrent use.
+func (r *RawFile) Read(i int, dst []float32) error {
+	if i < 0 || i >= r.n {
+		return fmt.Errorf("vector %d out of range", i)
+	}
+	if _, err := r.f.ReadAt(r.buf, int64(i)*int64(len(r.buf))); err != nil {
+		return fmt.Errorf("failed to read vector %d: %w", i, err)
+	}
+	for j := range dst[:r.dim] {
+		dst[j] = math.Float32frombits(binary.LittleEndian.Uint32(r.buf[j*4:]))
+	}
+	return nil
+}
+
+// Write stores v as vector i; i == Len() appends it. Like Read, it is not
+// safe for concurrent use.
+func (r *RawFile) Write(i int, v []float32) error {
+	if i < 0 || i > r.n {
+		return fmt.Errorf("vector %d out of range", i)
+	}
+	if len(v) != r.dim {
+		return fmt.Errorf("vector has dimension %d, want %d", len(v), r.dim)
+	}
+	for j, x := range v {
+		binary.LittleEndian.PutUint32(r.buf[j*4:], math.Float32bits(x))
+	}
+	if _, err := r.f.WriteAt(r.buf, int64(i)*int64(len(r.buf))); err != nil {
+		return fmt.Errorf("failed to write vector %d: %w", i, err)
+	}
+	if i == r.n {
+		r.n++
+	}
+	return nil
+}
+
+// Truncate keeps the first n vectors
+func (r *RawFile) Truncate(n int) error {
+	if n < 0 || n > r.n {
+		return fmt.Errorf("cannot truncate %d vectors to %d", r.n, n)
+	}
+	if err := r.f.Truncate(int64(n) * int64(len(r.buf))); err != nil {
+		return fmt.Errorf("failed to truncate vector file: %w", err)
+	}
+	r.n = n
+	return nil
+}
+
+// Close closes the file
+func (r *RawFile) Close() error {
+	return r.f.Close()
+}
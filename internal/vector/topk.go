@@ -0,0 +1,78 @@
+package vector
+
+// Result is a search hit: the position of a vector and its score. Higher
+// scores are better.
+type Result struct {
+	Index int
+	Score float32
+}
+
+// topK keeps the k best results seen so far in a min-heap, so the worst of
+// them is at the root and a new result only costs O(log k) when it qualifies
+type topK struct {
+	k    int
+	heap []Result
+}
+
+func newTopK(k int) *topK {
+	return &topK{k: k, heap: make([]Result, 0, k)}
+}
+
+// Push offers a result
+func (t *topK) Push(index int, score float32) {
+	if t.k <= 0 {
+		return
+	}
+	if len(t.heap) < t.k {
+		t.heap = append(t.heap, Result{Index: index, Score: score})
+		t.up(len(t.heap) - 1)
+		return
+	}
+	if score <= t.heap[0].Score {
+		return
+	}
+	t.heap[0] = Result{Index: index, Score: score}
+	t.down(0)
+}
+
+// Sorted returns the kept results, best first. The heap is consumed.
+func (t *topK) Sorted() []Result {
+	results := make([]Result, len(t.heap))
+	for i := len(t.heap) - 1; i >= 0; i-- {
+		results[i] = t.heap[0]
+		last := len(t.heap) - 1
+		t.heap[0] = t.heap[last]
+		t.heap = t.heap[:last]
+		t.down(0)
+	}
+	return results
+}
+
+func (t *topK) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if t.heap[parent].Score <= t.heap[i].Score {
+			return
+		}
+		t.heap[parent], t.heap[i] = t.heap[i], t.heap[parent]
+		i = parent
+	}
+}
+
+func (t *topK) down(i int) {
+	n := len(t.heap)
+	for {
+		smallest := i
+		if l := 2*i + 1; l < n && t.heap[l].Score < t.heap[smallest].Score {
+			smallest = l
+		}
+		if r := 2*i + 2; r < n && t.heap[r].Score < t.heap[smallest].Score {
+			smallest = r
+		}
+		if smallest == i {
+			return
+		}
+		t.heap[i], t.heap[smallest] = t.heap[smallest], t.heap[i]
+		i = smallest
+	}
+}
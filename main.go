@@ -3,13 +3,17 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"time"
 
+	commands "ollama_go/cmd"
 	"ollama_go/internal"
+	"ollama_go/internal/embedding"
+	"ollama_go/internal/resilience"
 	"ollama_go/internal/store"
 )
 
@@ -29,39 +33,137 @@ func cleanInput(str string) string {
 }
 
 func main() {
-	fmt.Println(logo)
+	fmt.Print(logo + "\n")
+
+	// Check if we should run a subcommand
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "crawl":
+			fmt.Println("Starting web crawler...")
+			crawling(os.Args[2:])
+			fmt.Println("\nCrawling completed!")
+			return
+		case "add":
+			if err := commands.Add(os.Args[2:]); err != nil {
+				log.Fatal("Error adding files:", err)
+			}
+			return
+		case "godoc":
+			if err := commands.GoDoc(os.Args[2:]); err != nil {
+				log.Fatal("Error indexing Go documentation:", err)
+			}
+			return
+		case "cache":
+			if err := commands.Cache(os.Args[2:]); err != nil {
+				log.Fatal("Error managing embedding cache:", err)
+			}
+			return
+		case "collection":
+			if err := commands.Collection(os.Args[2:]); err != nil {
+				log.Fatal("Error managing collections:", err)
+			}
+			return
+		case "docs":
+			if err := commands.Docs(os.Args[2:]); err != nil {
+				log.Fatal("Error managing documents:", err)
+			}
+			return
+		case "snapshot":
+			if err := commands.Snapshot(os.Args[2:]); err != nil {
+				log.Fatal("Error managing snapshots:", err)
+			}
+			return
+		case "reembed":
+			if err := commands.Reembed(os.Args[2:]); err != nil {
+				log.Fatal("Error re-embedding documents:", err)
+			}
+			return
+		case "export":
+			if err := commands.Export(os.Args[2:]); err != nil {
+				log.Fatal("Error exporting documents:", err)
+			}
+			return
+		case "import":
+			if err := commands.Import(os.Args[2:]); err != nil {
+				log.Fatal("Error importing documents:", err)
+			}
+			return
+		case "eval":
+			if err := commands.Eval(os.Args[2:]); err != nil {
+				log.Fatal("Error evaluating retrieval:", err)
+			}
+			return
+		}
+	}
 
-	// Check if we should crawl
-	if len(os.Args) > 1 && os.Args[1] == "crawl" {
-		fmt.Println("Starting web crawler...")
-		crawling()
-		fmt.Println("\nCrawling completed!")
-		return
+	chatFlags := flag.NewFlagSet("chat", flag.ExitOnError)
+	collectionNames := chatFlags.String("collections", store.DefaultCollection, "comma-separated collections to answer from")
+	watch := chatFlags.Duration("watch", 2*time.Second, "how often to check the collections for changes saved by other processes (0 to disable)")
+	chatFlags.Parse(os.Args[1:])
+
+	// Embedders for every model the collections were indexed with, sharing
+	// one cache; the default is the backend selected by the environment
+	embedders, err := embedding.NewSet(embedding.ConfigFromEnv("llama3:latest"))
+	if err != nil {
+		log.Fatal("Error initializing embedding service:", err)
 	}
 
-	// Initialize document store and load existing documents
-	docStore := store.NewDocumentStore()
-	if err := docStore.LoadFromDisk(); err != nil {
-		log.Printf("Warning: Could not load documents: %v", err)
+	// Load the collections to answer from. They are reloaded when a crawl or
+	// another command saves them, and queried with the embedder of the model
+	// they record at the time.
+	collections := store.NewCollections(store.DefaultDir)
+	var sources []internal.Source
+	var live []*store.Live
+	total := 0
+	for _, name := range strings.Split(*collectionNames, ",") {
+		name = strings.TrimSpace(name)
+		opened, err := collections.Open(name)
+		if err != nil {
+			log.Fatal("Error loading collection:", err)
+		}
+		docStore, err := store.NewLive(opened)
+		if err != nil {
+			log.Fatal("Error loading collection:", err)
+		}
+		live = append(live, docStore)
+		if _, err := embedders.For(docStore.Metadata().Model); err != nil {
+			log.Fatal("Error initializing embedding service:", err)
+		}
+		sources = append(sources, internal.Source{Name: name, Store: docStore, Embedders: embedders})
+
+		count := docStore.Len()
+		total += count
+		fmt.Printf("✅ Loaded %d documents from %s (metric: %s)\n", count, name, docStore.Metadata().Metric)
 	}
 
-	docs := docStore.GetAllDocuments()
-	if len(docs) == 0 {
+	if total == 0 {
 		fmt.Println("⚠️  No documents found! Please run 'go run . crawl' first to index documents.")
 		return
 	}
-
-	fmt.Printf("✅ Loaded %d documents from index\n\n", len(docs))
+	fmt.Println()
 
 	// Initialize RAG service
-	ragService, err := internal.NewRAGService("llama3:latest", docStore, 3)
+	ragService, err := internal.NewMultiRAGService("llama3:latest", sources, 3)
 	if err != nil {
 		log.Fatal("Error initializing RAG service:", err)
 	}
 
+	if *watch > 0 {
+		for i, docStore := range live {
+			name := sources[i].Name
+			go docStore.Watch(context.Background(), *watch, func(err error) {
+				if err != nil {
+					log.Printf("⚠️  Could not reload %s: %v", name, err)
+					return
+				}
+				fmt.Printf("\n🔄 Reloaded %s: %d documents\n", name, docStore.Len())
+			})
+		}
+	}
+
 	scanner := bufio.NewScanner(os.Stdin)
 	fmt.Println("🤖 RAG-powered Q&A ready! Ask questions about the indexed Go documentation.")
-	fmt.Println("Type 'exit' to quit.\n")
+	fmt.Print("Type 'reload' to reload the collections, 'exit' to quit.\n\n")
 
 	for {
 		fmt.Printf("Prompt : ")
@@ -75,6 +177,9 @@ func main() {
 
 		// Exit condition
 		if strings.ToLower(text) == "exit" {
+			if err := embedders.Close(); err != nil {
+				log.Printf("Warning: Could not save embedding cache: %v", err)
+			}
 			fmt.Println("Exiting CLI. Goodbye!")
 			break
 		}
@@ -83,6 +188,19 @@ func main() {
 			continue
 		}
 
+		if strings.ToLower(text) == "reload" {
+			for i, docStore := range live {
+				if err := docStore.Reload(); err != nil {
+					log.Println("❌ Error reloading collection:", err)
+					continue
+				}
+				meta := docStore.Metadata()
+				fmt.Printf("🔄 Reloaded %s: %d documents (generation %d)\n", sources[i].Name, docStore.Len(), meta.Generation)
+			}
+			fmt.Println()
+			continue
+		}
+
 		start := time.Now()
 		ctx := context.Background()
 
@@ -94,6 +212,9 @@ func main() {
 		})
 		if err != nil {
 			log.Println("\n❌ Error generating response:", err)
+			if hint := resilience.Hint(err); hint != "" {
+				fmt.Printf("💡 %s\n\n", hint)
+			}
 			continue
 		}
 
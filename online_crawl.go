@@ -2,57 +2,303 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	commands "ollama_go/cmd"
+	"ollama_go/internal/crawl"
 	"ollama_go/internal/embedding"
+	"ollama_go/internal/ingest"
 	"ollama_go/internal/models"
 	"ollama_go/internal/store"
+	"ollama_go/internal/vector"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly"
-	"github.com/google/uuid"
 )
 
-func crawling() {
-	// Initialize embedding service
-	embService, err := embedding.NewService("llama3:latest")
-	if err != nil {
-		log.Fatal("Failed to initialize embedding service:", err)
+// crawlConfig holds the politeness settings for a crawl
+type crawlConfig struct {
+	respectRobots bool
+	userAgent     string
+	parallelism   int
+	delay         time.Duration
+	maxRetries    int
+	maxPages      int
+	resume        bool
+	statePath     string
+	seeds         []string
+	scope         string
+	metric        vector.Metric
+	collection    string
+	noSnapshot    bool
+	pipeline      ingest.PipelineConfig
+	set           map[string]bool // flags given on the command line
+}
+
+// defaultSeeds are crawled when no -seed is given
+var defaultSeeds = []string{
+	"https://go.dev/doc/tutorial/getting-started",
+	"https://go.dev/doc/effective_go",
+	"https://go.dev/doc/code",
+	"https://go.dev/doc/install",
+}
+
+// parseCrawlFlags parses the arguments of the crawl command
+func parseCrawlFlags(args []string) crawlConfig {
+	cfg := crawlConfig{}
+	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
+	fs.BoolVar(&cfg.respectRobots, "respect-robots", true, "obey robots.txt rules and Crawl-delay")
+	fs.StringVar(&cfg.userAgent, "user-agent", "GoRAGBot/1.0 (+https://github.com/Anjila-26/RAG_in_Golang)", "User-Agent sent with every request")
+	fs.IntVar(&cfg.parallelism, "parallelism", 1, "maximum concurrent requests per host")
+	fs.DurationVar(&cfg.delay, "delay", 2*time.Second, "minimum delay between requests to the same host")
+	fs.IntVar(&cfg.maxRetries, "max-retries", 3, "retries for 429/503 responses before giving up")
+	fs.IntVar(&cfg.maxPages, "max-pages", 5, "maximum number of pages to index")
+	fs.BoolVar(&cfg.resume, "resume", false, "continue an interrupted crawl from its saved state")
+	fs.StringVar(&cfg.statePath, "state", "", "path of the crawl state file (default crawl_state.json in the collection directory)")
+	fs.StringVar(&cfg.collection, "collection", store.DefaultCollection, "collection to index into")
+	fs.BoolVar(&cfg.noSnapshot, "no-snapshot", false, "do not snapshot the collection before replacing its documents")
+	fs.Func("seed", "start URL (repeatable; defaults to the go.dev documentation)", func(s string) error {
+		cfg.seeds = append(cfg.seeds, s)
+		return nil
+	})
+	fs.Func("metric", "distance metric of the index: cosine (default), dot or l2", func(s string) error {
+		var err error
+		cfg.metric, err = vector.ParseMetric(s)
+		return err
+	})
+	fs.StringVar(&cfg.scope, "scope", "", "only follow links containing this string (default go.dev/doc, or anything on the seed hosts with -seed)")
+
+	cfg.pipeline = ingest.DefaultPipelineConfig()
+	fs.IntVar(&cfg.pipeline.ExtractWorkers, "extract-workers", cfg.pipeline.ExtractWorkers, "content extraction workers")
+	fs.IntVar(&cfg.pipeline.ChunkWorkers, "chunk-workers", cfg.pipeline.ChunkWorkers, "chunking workers")
+	fs.IntVar(&cfg.pipeline.EmbedWorkers, "embed-workers", cfg.pipeline.EmbedWorkers, "parallel embedding workers")
+	fs.IntVar(&cfg.pipeline.StoreWorkers, "store-workers", cfg.pipeline.StoreWorkers, "document store writers")
+	fs.IntVar(&cfg.pipeline.Buffer, "buffer", cfg.pipeline.Buffer, "capacity of the channels between pipeline stages")
+	fs.IntVar(&cfg.pipeline.MaxBatch, "max-batch", cfg.pipeline.MaxBatch, "largest number of chunks embedded in one request")
+	fs.DurationVar(&cfg.pipeline.TargetLatency, "batch-latency", cfg.pipeline.TargetLatency, "embedding batches slower than this shrink the batch size")
+	fs.Parse(args)
+	cfg.set = make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { cfg.set[f.Name] = true })
+
+	if len(cfg.seeds) == 0 {
+		cfg.seeds = defaultSeeds
+		if cfg.scope == "" {
+			cfg.scope = "go.dev/doc"
+		}
+	}
+	return cfg
+}
+
+// restore continues with the seeds, scope and collection of the crawl being
+// resumed, refusing flags that contradict them
+func (cfg *crawlConfig) restore(saved crawl.Target) error {
+	if len(saved.Seeds) == 0 {
+		fmt.Println("⚠️  The crawl state does not record its seeds and scope; using the flags")
+		return nil
 	}
+	if cfg.set["seed"] && !slices.Equal(cfg.seeds, saved.Seeds) {
+		return fmt.Errorf("the crawl being resumed started from %s, not %s; resume without -seed or start a new crawl",
+			strings.Join(saved.Seeds, ", "), strings.Join(cfg.seeds, ", "))
+	}
+	if cfg.set["scope"] && cfg.scope != saved.Scope {
+		return fmt.Errorf("the crawl being resumed has the scope %q, not %q; resume without -scope or start a new crawl", saved.Scope, cfg.scope)
+	}
+	// Without -state the state file is in the collection it belongs to
+	if saved.Collection != cfg.collection && (cfg.set["collection"] || !cfg.set["state"]) {
+		return fmt.Errorf("the crawl being resumed indexes into the collection %q, not %q", saved.Collection, cfg.collection)
+	}
+	cfg.seeds, cfg.scope, cfg.collection = saved.Seeds, saved.Scope, saved.Collection
+	return nil
+}
 
-	// Initialize store
-	docStore := store.NewDocumentStore()
+func crawling(args []string) {
+	cfg := parseCrawlFlags(args)
+	collections := store.NewCollections(store.DefaultDir)
+	if cfg.statePath == "" {
+		dir, err := collections.Dir(cfg.collection)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.statePath = filepath.Join(dir, "crawl_state.json")
+	}
 
-	ctx := context.Background()
+	// Frontier, visited set and fetched-but-not-embedded pages are persisted
+	// with the seeds, scope and collection, which a resumed crawl keeps
+	state := crawl.NewState(cfg.statePath)
+	if cfg.resume {
+		var err error
+		if state, err = crawl.LoadState(cfg.statePath); err != nil {
+			log.Fatal("Failed to load crawl state:", err)
+		}
+		if err := cfg.restore(state.Target()); err != nil {
+			log.Fatal(err)
+		}
+	}
+	state.SetTarget(crawl.Target{Seeds: cfg.seeds, Scope: cfg.scope, Collection: cfg.collection})
 
-	// Thread-safe storage for documents
-	var documentsMux sync.Mutex
-	documents := make([]*models.PageContent, 0)
+	// Initialize store: a fresh crawl replaces the documents of the collection
+	// but keeps its metric and embedding model unless -metric is given
+	docStore, err := collections.Open(cfg.collection)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer docStore.Close()
+	if !cfg.resume {
+		if !cfg.noSnapshot {
+			if err := commands.SnapshotBefore(docStore, "crawl"); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := docStore.Clear(); err != nil {
+			log.Fatal("Failed to clear documents:", err)
+		}
+		if cfg.metric != "" {
+			if err := docStore.SetMetric(cfg.metric); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	// Initialize embedding backend: the collection's model, or the one
+	// selected by RAG_EMBEDDER (ollama, openai or hash) for a new index
+	embedCfg := embedding.ConfigFromEnv("llama3:latest")
+	if model := docStore.Metadata().Model; model != "" {
+		if embedCfg, err = embedCfg.WithModelID(model); err != nil {
+			log.Fatal(err)
+		}
+	}
+	embedder, err := embedding.New(embedCfg)
+	if err != nil {
+		log.Fatal("Failed to initialize embedding service:", err)
+	}
+	defer func() {
+		if err := embedding.Close(embedder); err != nil {
+			log.Printf("⚠️  %v\n", err)
+		}
+	}()
+	if err := docStore.SetModel(embedder.ModelID()); err != nil {
+		log.Fatal(err)
+	}
+
+	// Cancel on Ctrl-C so the crawl state can be flushed before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stop() // a second Ctrl-C quits immediately
+			fmt.Println("\n🛑 Interrupted: finishing in-flight requests and saving crawl state (Ctrl-C again to force quit)")
+		case <-finished:
+		}
+	}()
+
+	seeds := cfg.seeds
+	queue := seeds
+	if cfg.resume {
+		queue = state.Frontier()
+		fmt.Printf("♻️  Resuming crawl: %d queued URLs, %d pages awaiting embeddings\n",
+			len(queue), len(state.Pending()))
+	}
 
 	// Thread-safe page counter
 	var pageCountMux sync.Mutex
-	pageCount := 0
-	maxPages := 5 // Reduced to 5 pages to avoid rate limits
+	pageCount := state.PageCount()
+	maxPages := cfg.maxPages
 
-	// Create collector with async enabled for concurrent crawling
+	// Politeness: robots.txt, throttling retries and a report of skipped URLs
+	robots := crawl.NewRobotsPolicy(cfg.userAgent, cfg.respectRobots)
+	backoff := crawl.NewBackoff(cfg.maxRetries)
+	report := crawl.NewReport()
+
+	var attemptsMux sync.Mutex
+	attempts := make(map[string]int)
+
+	// Fetched pages stream through extract → chunk → embed → store while the
+	// crawl continues; submitted maps page URLs to their page number
+	pipeline := ingest.NewPipeline(embedder, docStore, cfg.pipeline)
+	var submitted sync.Map
+
+	// Create collector with async enabled for concurrent crawling.
+	// robots.txt is checked by our own policy so skipped URLs can be reported.
 	c := colly.NewCollector(
-		colly.AllowedDomains("go.dev"),
+		colly.AllowedDomains(seedHosts(seeds)...),
 		colly.MaxDepth(2),
 		colly.Async(true),
-		colly.UserAgent("Mozilla/5.0 (compatible; GoRAGBot/1.0)"),
+		colly.UserAgent(cfg.userAgent),
+		colly.IgnoreRobotsTxt(),
 	)
 
-	// Limit parallelism - control how many requests run simultaneously
-	c.Limit(&colly.LimitRule{
-		DomainGlob:  "go.dev",
-		Parallelism: 1,               // 1 request at a time to avoid rate limits
-		Delay:       2 * time.Second, // 2 second delay between requests
-		RandomDelay: 1 * time.Second, // Additional random delay
-	})
+	// Limit parallelism per host, honouring a larger Crawl-delay from robots.txt
+	limited := make(map[string]bool)
+	for _, seed := range seeds {
+		u, err := url.Parse(seed)
+		if err != nil || limited[u.Host] {
+			continue
+		}
+		limited[u.Host] = true
+
+		delay := max(cfg.delay, robots.CrawlDelay(seed))
+		fmt.Printf("🤖 %s: robots.txt respected: %v, delay: %s, parallelism: %d\n",
+			u.Host, cfg.respectRobots, delay, cfg.parallelism)
+
+		c.Limit(&colly.LimitRule{
+			DomainGlob:  u.Host,
+			Parallelism: cfg.parallelism,
+			Delay:       delay,
+			RandomDelay: delay / 2, // Additional random delay
+		})
+	}
+
+	// visit checks robots.txt before handing the URL to colly, keeps the
+	// frontier up to date and records why a URL was not crawled
+	visit := func(rawURL string, visitFn func(string) error) {
+		if state.Visited(rawURL) {
+			return
+		}
+		// Once interrupted, new links are only queued for the next run
+		if ctx.Err() != nil {
+			state.Enqueue(rawURL)
+			return
+		}
+
+		allowed, err := robots.Allowed(rawURL)
+		if err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+			report.Skip(rawURL, crawl.ReasonRobotsErr)
+			return
+		}
+		if !allowed {
+			report.Skip(rawURL, crawl.ReasonRobots)
+			return
+		}
+
+		state.Enqueue(rawURL)
+		switch err := visitFn(rawURL); {
+		case err == nil, errors.Is(err, colly.ErrAlreadyVisited):
+			// Queued or in flight; finished in OnScraped/OnError
+		case errors.Is(err, colly.ErrMaxDepth):
+			report.Skip(rawURL, crawl.ReasonMaxDepth)
+			state.Finish(rawURL)
+		case errors.Is(err, colly.ErrForbiddenDomain):
+			report.Skip(rawURL, crawl.ReasonDomain)
+			state.Finish(rawURL)
+		default:
+			state.Finish(rawURL)
+		}
+	}
 
 	// Extract and display page content
 	c.OnHTML("html", func(e *colly.HTMLElement) {
@@ -60,76 +306,60 @@ func crawling() {
 		pageCountMux.Lock()
 		if pageCount >= maxPages {
 			pageCountMux.Unlock()
+			report.Skip(e.Request.URL.String(), crawl.ReasonPageLimit)
 			return
 		}
 		pageCount++
 		currentPage := pageCount
 		pageCountMux.Unlock()
 
+		// Hand the page to the pipeline; blocks while extraction is backed up
+		pageURL := e.Request.URL.String()
+		if err := pipeline.SubmitHTML(ctx, pageURL, e.DOM); err != nil {
+			return
+		}
+		submitted.Store(pageURL, currentPage)
+	})
+
+	// Display extracted content and remember it until it is embedded
+	pipeline.OnPageExtracted(func(pageContent *models.PageContent) {
+		currentPage, _ := submitted.Load(pageContent.URL)
+
 		fmt.Println("\n" + strings.Repeat("=", 80))
 		fmt.Printf("PAGE #%d\n", currentPage)
 		fmt.Println(strings.Repeat("=", 80))
 
-		pageContent := &models.PageContent{
-			URL:         e.Request.URL.String(),
-			MainContent: make([]string, 0),
+		fmt.Printf("📄 Title: %s\n", pageContent.Title)
+		fmt.Printf("🔗 URL: %s\n", pageContent.URL)
+		if pageContent.Description != "" {
+			fmt.Printf("📝 Description: %s\n", pageContent.Description)
 		}
 
-		// Title
-		title := e.ChildText("title")
-		pageContent.Title = title
-		fmt.Printf("📄 Title: %s\n", title)
-		fmt.Printf("🔗 URL: %s\n", e.Request.URL.String())
-
-		// Meta description
-		e.DOM.Find("meta[name='description']").Each(func(_ int, s *goquery.Selection) {
-			if desc, exists := s.Attr("content"); exists {
-				pageContent.Description = desc
-				fmt.Printf("📝 Description: %s\n", desc)
-			}
-		})
-
 		// Extract MAIN content only (skip navigation and footer)
 		fmt.Println("\n📖 Main Content:")
 		fmt.Println(strings.Repeat("-", 80))
 
-		var contentPrinted bool
-
-		// Try to get main content area
-		e.ForEach("main, article, .Documentation-content, .SearchResults", func(_ int, mainEl *colly.HTMLElement) {
-			var textCount int
-			mainEl.ForEach("h1, h2, h3, h4, p, pre, code, li", func(_ int, el *colly.HTMLElement) {
-				text := strings.TrimSpace(el.Text)
-				// Skip generic footer text
-				if text != "" && textCount < 20 &&
-					!strings.Contains(text, "Common problems companies solve") &&
-					!strings.Contains(text, "Learn and network with Go") &&
-					!strings.Contains(text, "Meet other local Go developers") &&
-					len(text) > 20 { // Skip very short text
-					fmt.Printf("\n• %s\n", text)
-					pageContent.MainContent = append(pageContent.MainContent, text)
-					textCount++
-					contentPrinted = true
-				}
-			})
-		})
-
-		if !contentPrinted {
+		for _, text := range pageContent.MainContent {
+			fmt.Printf("\n• %s\n", text)
+		}
+		if len(pageContent.MainContent) == 0 {
 			fmt.Println("(No main content extracted)")
 		}
 
-		// Count links
-		linkCount := 0
-		e.ForEach("a[href]", func(_ int, el *colly.HTMLElement) {
-			linkCount++
-		})
-		pageContent.LinkCount = linkCount
-		fmt.Printf("\n🔗 Links found: %d\n", linkCount)
+		fmt.Printf("\n🔗 Links found: %d\n", pageContent.LinkCount)
+
+		// Keep the page content until its embeddings are stored (thread-safe)
+		state.AddPending(pageContent)
+		if err := state.Save(); err != nil {
+			log.Printf("⚠️  Error saving crawl state: %v\n", err)
+		}
+	})
 
-		// Store the page content for later embedding generation (thread-safe)
-		documentsMux.Lock()
-		documents = append(documents, pageContent)
-		documentsMux.Unlock()
+	pipeline.OnPageIndexed(func(page *models.PageContent) {
+		state.Embedded(page.URL)
+		if err := state.Save(); err != nil {
+			log.Printf("⚠️  Error saving crawl state: %v\n", err)
+		}
 	})
 
 	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
@@ -138,125 +368,152 @@ func crawling() {
 		shouldSkip := pageCount >= maxPages
 		pageCountMux.Unlock()
 
-		if shouldSkip {
+		link := e.Attr("href")
+		absURL := e.Request.AbsoluteURL(link)
+		if absURL == "" {
 			return
 		}
 
-		link := e.Attr("href")
-		absURL := e.Request.AbsoluteURL(link)
+		if shouldSkip {
+			report.Skip(absURL, crawl.ReasonPageLimit)
+			return
+		}
 
-		// Only follow links within go.dev/doc
-		if !strings.Contains(absURL, "go.dev/doc") {
+		// Only follow links within the crawl scope (go.dev/doc by default)
+		if !strings.Contains(absURL, cfg.scope) {
+			report.Skip(absURL, crawl.ReasonOutOfScope)
 			return
 		}
 
-		c.Visit(absURL)
+		visit(absURL, e.Request.Visit)
 	})
 
 	c.OnRequest(func(r *colly.Request) {
+		// Leave queued requests in the frontier once interrupted
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
 		fmt.Printf("\n🔍 Crawling: %s\n", r.URL.String())
 	})
 
+	c.OnScraped(func(r *colly.Response) {
+		// Pages handed to the pipeline leave the frontier once extracted
+		if _, ok := submitted.Load(r.Request.URL.String()); ok {
+			return
+		}
+		state.Finish(r.Request.URL.String())
+		if err := state.Save(); err != nil {
+			log.Printf("⚠️  Error saving crawl state: %v\n", err)
+		}
+	})
+
+	c.OnResponse(func(r *colly.Response) {
+		report.Fetched()
+	})
+
 	c.OnError(func(r *colly.Response, err error) {
+		pageURL := r.Request.URL.String()
+
+		// Back off and retry when the server asks us to slow down
+		if crawl.Retryable(r.StatusCode) {
+			attemptsMux.Lock()
+			attempt := attempts[pageURL]
+			attempts[pageURL] = attempt + 1
+			attemptsMux.Unlock()
+
+			if attempt < backoff.MaxRetries {
+				var retryAfter string
+				if r.Headers != nil {
+					retryAfter = r.Headers.Get("Retry-After")
+				}
+				wait := backoff.Wait(attempt, retryAfter)
+				fmt.Printf("⏳ %d from %s, retrying in %s (attempt %d/%d)\n",
+					r.StatusCode, pageURL, wait.Round(time.Millisecond), attempt+1, backoff.MaxRetries)
+				report.Retried()
+
+				// Sleep in the callback so c.Wait() keeps waiting for the retry
+				time.Sleep(wait)
+				if err := r.Request.Retry(); err == nil {
+					return
+				}
+			}
+
+			report.Skip(pageURL, crawl.ReasonRetries)
+			state.Finish(pageURL)
+			return
+		}
+
 		fmt.Printf("❌ Error: %v\n", err)
+		report.Skip(pageURL, crawl.ReasonHTTPError)
+		state.Finish(pageURL)
 	})
 
-	// Start crawling from Go documentation
-	c.Visit("https://go.dev/doc/tutorial/getting-started")
-	c.Visit("https://go.dev/doc/effective_go")
-	c.Visit("https://go.dev/doc/code")
-	c.Visit("https://go.dev/doc/install")
+	pipeline.Start(ctx)
+
+	// Pages fetched before an interruption go straight to the chunk stage
+	var resubmit sync.WaitGroup
+	resubmit.Add(1)
+	go func() {
+		defer resubmit.Done()
+		for _, page := range state.Pending() {
+			if err := pipeline.SubmitPage(ctx, page); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Start crawling from Go documentation (or the saved frontier)
+	for _, u := range queue {
+		visit(u, c.Visit)
+	}
 
-	// Wait for all async requests to complete
+	// Wait for all async requests to complete, then let the pipeline drain
 	c.Wait()
+	resubmit.Wait()
+	pipeline.Close()
+	pipeline.Wait()
+
+	report.Print()
+	pipeline.PrintProgress()
+	pipeline.PrintThroughput()
+
+	if err := state.Save(); err != nil {
+		log.Printf("⚠️  Error saving crawl state: %v\n", err)
+	}
+	if ctx.Err() != nil {
+		fmt.Printf("\n💾 Crawl state saved to %s. Run 'go run . crawl -resume' to continue.\n", cfg.statePath)
+		return
+	}
 
 	fmt.Println("\n" + strings.Repeat("=", 80))
 	fmt.Printf("✅ Crawling completed! Total pages: %d\n", pageCount)
+	fmt.Printf("✅ Generated and saved %d embeddings\n", pipeline.Saved())
 	fmt.Println(strings.Repeat("=", 80))
 
-	// Generate embeddings for all collected documents (parallelized)
-	if len(documents) > 0 {
-		fmt.Println("\n🔄 Generating embeddings for crawled content...")
-
-		// Use worker pool for parallel embedding generation
-		numWorkers := 3 // Number of parallel embedding workers
-		docChan := make(chan struct {
-			index   int
-			content *models.PageContent
-		}, len(documents))
-
-		var wg sync.WaitGroup
-
-		// Start workers
-		for w := 0; w < numWorkers; w++ {
-			wg.Add(1)
-			go func(workerID int) {
-				defer wg.Done()
-
-				for job := range docChan {
-					i := job.index
-					pageContent := job.content
-
-					// Combine title, description, and content for embedding
-					combinedText := fmt.Sprintf("%s. %s. %s",
-						pageContent.Title,
-						pageContent.Description,
-						strings.Join(pageContent.MainContent, " "))
-
-					// Truncate if too long (optional - adjust as needed)
-					if len(combinedText) > 2000 {
-						combinedText = combinedText[:2000]
-					}
-
-					fmt.Printf("\n📊 [Worker %d] Generating embedding for page %d/%d: %s\n",
-						workerID, i+1, len(documents), pageContent.Title)
-
-					// Generate embedding
-					embedding, err := embService.GenerateEmbedding(ctx, combinedText)
-					if err != nil {
-						log.Printf("⚠️  Error generating embedding for %s: %v\n", pageContent.URL, err)
-						continue
-					}
-
-					// Create document with embedding
-					doc := &models.Document{
-						ID:          uuid.New().String(),
-						URL:         pageContent.URL,
-						Title:       pageContent.Title,
-						Description: pageContent.Description,
-						Content:     strings.Join(pageContent.MainContent, "\n"),
-						Embedding:   embedding,
-						CreatedAt:   time.Now(),
-					}
-
-					// Save to store
-					if err := docStore.SaveDocument(doc); err != nil {
-						log.Printf("⚠️  Error saving document: %v\n", err)
-						continue
-					}
-
-					fmt.Printf("✅ [Worker %d] Embedding generated (dim: %d) and document saved\n",
-						workerID, len(embedding))
-				}
-			}(w)
-		}
-
-		// Send jobs to workers
-		for i, pageContent := range documents {
-			docChan <- struct {
-				index   int
-				content *models.PageContent
-			}{i, pageContent}
-		}
-		close(docChan)
+	if pageCount == 0 {
+		fmt.Println("\n⚠️  No documents were crawled!")
+	}
 
-		// Wait for all workers to complete
-		wg.Wait()
+	// Keep the state file while anything is left to do
+	if remaining := len(state.Pending()); remaining > 0 {
+		fmt.Printf("\n💾 %d pages still need embeddings. Run 'go run . crawl -resume' to retry.\n", remaining)
+		return
+	}
+	if err := state.Remove(); err != nil {
+		log.Printf("⚠️  %v\n", err)
+	}
+}
 
-		fmt.Println("\n" + strings.Repeat("=", 80))
-		fmt.Printf("✅ Generated and saved %d embeddings\n", len(documents))
-		fmt.Println(strings.Repeat("=", 80))
-	} else {
-		fmt.Println("\n⚠️  No documents were crawled!")
+// seedHosts returns the distinct hosts (with port) of the seed URLs
+func seedHosts(seeds []string) []string {
+	hosts := make([]string, 0, len(seeds))
+	for _, seed := range seeds {
+		u, err := url.Parse(seed)
+		if err != nil || u.Host == "" || slices.Contains(hosts, u.Host) {
+			continue
+		}
+		hosts = append(hosts, u.Host)
 	}
+	return hosts
 }